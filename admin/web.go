@@ -58,6 +58,9 @@ func (p *Plugin) InitWeb() {
 
 	mux.Handle(pat.Post("/reconnect_all"), http.HandlerFunc(p.handleReconnectAll))
 
+	// User data requests
+	mux.Handle(pat.Get("/userdata"), http.HandlerFunc(p.handleDownloadUserData))
+
 	getConfigHandler := web.ControllerHandler(p.handleGetConfig, "bot_admin_config")
 	mux.Handle(pat.Get("/config"), getConfigHandler)
 	mux.Handle(pat.Post("/config/edit/:key"), web.ControllerPostHandler(p.handleEditConfig, getConfigHandler, nil))
@@ -372,3 +375,25 @@ func (p *Plugin) handleReconnectAll(w http.ResponseWriter, r *http.Request) {
 		time.Sleep(time.Second * 5)
 	}
 }
+
+// handleDownloadUserData sends the data stored about the user as a zip, for data requests too big to send in DM's
+func (p *Plugin) handleDownloadUserData(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(r.URL.Query().Get("user"), 10, 64)
+	if err != nil || userID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid user id"))
+		return
+	}
+
+	archive, err := common.ExportUserDataArchive(r.Context(), userID)
+	if err != nil {
+		logger.WithError(err).WithField("user", userID).Error("failed exporting user data")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Error exporting user data: " + err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"userdata-%d.zip\"", userID))
+	w.Write(archive)
+}
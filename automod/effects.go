@@ -437,6 +437,70 @@ func (mute *MuteUserEffect) MergeDuplicates(data []interface{}) interface{} {
 
 ///////////////////////////////////////////////////////
 
+type TimeoutUserEffect struct{}
+
+type TimeoutUserEffectData struct {
+	Duration     int
+	CustomReason string `valid:",0,150,trimspace"`
+}
+
+func (timeout *TimeoutUserEffect) Kind() RulePartType {
+	return RulePartEffect
+}
+
+func (timeout *TimeoutUserEffect) DataType() interface{} {
+	return &TimeoutUserEffectData{}
+}
+
+func (timeout *TimeoutUserEffect) UserSettings() []*SettingDef {
+	return []*SettingDef{
+		&SettingDef{
+			Name:    "Duration (minutes, max 40320)",
+			Key:     "Duration",
+			Min:     1,
+			Max:     40320,
+			Kind:    SettingTypeInt,
+			Default: 10,
+		},
+		&SettingDef{
+			Name: "Custom message (empty for default)",
+			Key:  "CustomReason",
+			Min:  0,
+			Max:  150,
+			Kind: SettingTypeString,
+		},
+	}
+}
+
+func (timeout *TimeoutUserEffect) Name() (name string) {
+	return "Timeout user"
+}
+
+func (timeout *TimeoutUserEffect) Description() (description string) {
+	return "Puts the user in a native discord timeout"
+}
+
+func (timeout *TimeoutUserEffect) Apply(ctxData *TriggeredRuleData, settings interface{}) error {
+	settingsCast := settings.(*TimeoutUserEffectData)
+
+	reason := "Automoderator:\n"
+	if settingsCast.CustomReason != "" {
+		reason += settingsCast.CustomReason
+	} else {
+		reason += ctxData.ConstructReason(true)
+	}
+
+	duration := time.Duration(settingsCast.Duration) * time.Minute
+	err := moderation.TimeoutUser(nil, ctxData.GS.ID, ctxData.CS, ctxData.Message, common.BotUser, reason, ctxData.MS, duration)
+	return err
+}
+
+func (timeout *TimeoutUserEffect) MergeDuplicates(data []interface{}) interface{} {
+	return data[0]
+}
+
+///////////////////////////////////////////////////////
+
 type WarnUserEffect struct{}
 
 type WarnUserEffectData struct {
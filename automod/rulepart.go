@@ -75,6 +75,7 @@ var RulePartMap = map[int]RulePart{
 	309: &GiveRoleEffect{},
 	311: &EnableChannelSlowmodeEffect{},
 	312: &RemoveRoleEffect{},
+	313: &TimeoutUserEffect{},
 }
 
 var InverseRulePartMap = make(map[RulePart]int)
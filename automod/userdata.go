@@ -0,0 +1,26 @@
+package automod
+
+import (
+	"context"
+
+	"emperror.dev/errors"
+	"github.com/jonas747/yagpdb/automod/models"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+var _ common.PluginWithUserData = (*Plugin)(nil)
+
+func (p *Plugin) ExportUserData(ctx context.Context, userID int64) (interface{}, error) {
+	violations, err := models.AutomodViolations(models.AutomodViolationWhere.UserID.EQ(userID), qm.OrderBy("id asc")).AllG(ctx)
+	if err != nil {
+		return nil, errors.WrapIf(err, "automod_violations")
+	}
+
+	return map[string]interface{}{"violations": violations}, nil
+}
+
+func (p *Plugin) EraseUserData(ctx context.Context, userID int64) error {
+	_, err := models.AutomodViolations(models.AutomodViolationWhere.UserID.EQ(userID)).DeleteAll(ctx, common.PQ)
+	return errors.WrapIf(err, "automod_violations")
+}
@@ -92,7 +92,7 @@ func GetCommandPrefixRedis(guild int64) (string, error) {
 	var prefix string
 	err := common.RedisPool.Do(radix.Cmd(&prefix, "GET", "command_prefix:"+discordgo.StrID(guild)))
 	if err == nil && prefix == "" {
-		prefix = defaultCommandPrefix()
+		prefix = DefaultCommandPrefix()
 	}
 	return prefix, err
 }
@@ -112,7 +112,7 @@ func (p *Plugin) UpdateFeatureFlags(guildID int64) ([]string, error) {
 	}
 
 	var flags []string
-	if defaultCommandPrefix() != prefix {
+	if DefaultCommandPrefix() != prefix {
 		flags = append(flags, featureFlagHasCustomPrefix)
 	}
 
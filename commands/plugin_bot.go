@@ -269,7 +269,7 @@ func handleMsgCreate(evt *eventsystem.EventData) {
 		return
 	}
 
-	prefix := defaultCommandPrefix()
+	prefix := DefaultCommandPrefix()
 	if evt.GS != nil && evt.HasFeatureFlag(featureFlagHasCustomPrefix) {
 		var err error
 		prefix, err = GetCommandPrefixRedis(evt.GS.ID)
@@ -283,7 +283,7 @@ func handleMsgCreate(evt *eventsystem.EventData) {
 }
 
 func GetCommandPrefixBotEvt(evt *eventsystem.EventData) (string, error) {
-	prefix := defaultCommandPrefix()
+	prefix := DefaultCommandPrefix()
 	if evt.GS != nil && evt.HasFeatureFlag(featureFlagHasCustomPrefix) {
 		var err error
 		prefix, err = GetCommandPrefixRedis(evt.GS.ID)
@@ -330,7 +330,7 @@ func ensureEmbedLimits(embed *discordgo.MessageEmbed) {
 	embed.Description = firstField.Value
 }
 
-func defaultCommandPrefix() string {
+func DefaultCommandPrefix() string {
 	defaultPrefix := "-"
 	if common.Testing {
 		defaultPrefix = "("
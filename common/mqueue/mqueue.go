@@ -53,6 +53,12 @@ type PluginWithWebhookAvatar interface {
 	WebhookAvatar() string
 }
 
+// PluginWithMessageSent can be implemented by sources that need the message after it was sent,
+// only called for messages not sent through webhooks
+type PluginWithMessageSent interface {
+	MessageSent(elem *QueuedElement, msg *discordgo.Message)
+}
+
 var (
 	_ bot.LateBotInitHandler = (*Plugin)(nil)
 	_ bot.BotStopperHandler  = (*Plugin)(nil)
@@ -120,11 +126,11 @@ func incrIDCounter() (next int64) {
 	return next
 }
 
-// QueueMessage queues a message in the message queue
-func QueueMessage(elem *QueuedElement) {
+// QueueMessage queues a message in the message queue, the error can be ignored by sources that don't mind losing the message
+func QueueMessage(elem *QueuedElement) error {
 	nextID := incrIDCounter()
 	if nextID == -1 {
-		return
+		return errors.New("failed increasing mqueue id counter")
 	}
 
 	elem.ID = nextID
@@ -132,13 +138,11 @@ func QueueMessage(elem *QueuedElement) {
 	serialized, err := json.Marshal(elem)
 	if err != nil {
 		logger.WithError(err).Error("Failed marshaling mqueue element")
-		return
+		return errors.WithStackIf(err)
 	}
 
 	err = common.RedisPool.Do(radix.Cmd(nil, "ZADD", "mqueue", "-1", string(serialized)))
-	if err != nil {
-		return
-	}
+	return errors.WithStackIf(err)
 }
 
 var _ bot.BotInitHandler = (*Plugin)(nil)
@@ -417,12 +421,16 @@ func process(elem *QueuedElement, raw []byte) {
 
 	for {
 		var err error
+		var msg *discordgo.Message
 		if elem.UseWebhook {
 			err = trySendWebhook(queueLogger, elem)
 		} else {
-			err = trySendNormal(queueLogger, elem)
+			msg, err = trySendNormal(queueLogger, elem)
 		}
 		if err == nil {
+			if source, ok := sources[elem.Source].(PluginWithMessageSent); ok && msg != nil {
+				go source.MessageSent(elem, msg)
+			}
 			break
 		}
 
@@ -483,14 +491,14 @@ func maybeDisableFeed(source PluginWithSourceDisabler, elem *QueuedElement, err
 	source.DisableFeed(elem, err)
 }
 
-func trySendNormal(l *logrus.Entry, elem *QueuedElement) (err error) {
+func trySendNormal(l *logrus.Entry, elem *QueuedElement) (msg *discordgo.Message, err error) {
 	if elem.MessageStr != "" {
-		_, err = common.BotSession.ChannelMessageSendComplex(elem.Channel, &discordgo.MessageSend{
+		msg, err = common.BotSession.ChannelMessageSendComplex(elem.Channel, &discordgo.MessageSend{
 			Content:         elem.MessageStr,
 			AllowedMentions: elem.AllowedMentions,
 		})
 	} else if elem.MessageEmbed != nil {
-		_, err = common.BotSession.ChannelMessageSendEmbed(elem.Channel, elem.MessageEmbed)
+		msg, err = common.BotSession.ChannelMessageSendEmbed(elem.Channel, elem.MessageEmbed)
 	} else {
 		l.Error("Both MessageEmbed and MessageStr empty")
 	}
@@ -515,6 +515,9 @@ func baseContextFuncs(c *Context) {
 	c.ContextFuncs["getMessage"] = c.tmplGetMessage
 	c.ContextFuncs["getMember"] = c.tmplGetMember
 	c.ContextFuncs["getChannel"] = c.tmplGetChannel
+	c.ContextFuncs["getMembersWithRole"] = c.tmplGetMembersWithRole
+	c.ContextFuncs["searchMembers"] = c.tmplSearchMembers
+	c.ContextFuncs["countMembers"] = c.tmplCountMembers
 	c.ContextFuncs["addReactions"] = c.tmplAddReactions
 	c.ContextFuncs["addResponseReactions"] = c.tmplAddResponseReactions
 	c.ContextFuncs["addMessageReactions"] = c.tmplAddMessageReactions
@@ -530,6 +533,11 @@ func baseContextFuncs(c *Context) {
 
 	c.ContextFuncs["editChannelTopic"] = c.tmplEditChannelTopic
 	c.ContextFuncs["editChannelName"] = c.tmplEditChannelName
+	c.ContextFuncs["getChannelOverwrites"] = c.tmplGetChannelOverwrites
+	c.ContextFuncs["getTargetPermissionsIn"] = c.tmplGetTargetPermissionsIn
+	c.ContextFuncs["targetHasPermissionsIn"] = c.tmplTargetHasPermissionsIn
+	c.ContextFuncs["editChannelOverwrite"] = c.tmplEditChannelOverwrite
+	c.ContextFuncs["deleteChannelOverwrite"] = c.tmplDeleteChannelOverwrite
 	c.ContextFuncs["onlineCount"] = c.tmplOnlineCount
 	c.ContextFuncs["onlineCountBots"] = c.tmplOnlineCountBots
 	c.ContextFuncs["editNickname"] = c.tmplEditNickname
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -996,6 +997,253 @@ func (c *Context) tmplGetMember(target interface{}) (*discordgo.Member, error) {
 	return member.DGoCopy(), nil
 }
 
+const (
+	// MaxMemberResults is the max number of members returned by the member listing and search functions
+	MaxMemberResults = 100
+)
+
+func memberResultLimit(optionalArgs []interface{}) int {
+	limit := MaxMemberResults
+	if len(optionalArgs) > 0 {
+		limit = tmplToInt(optionalArgs[0])
+	}
+
+	if limit < 1 || limit > MaxMemberResults {
+		limit = MaxMemberResults
+	}
+
+	return limit
+}
+
+// sortMembers sorts the provided members by ID, capped to limit
+func sortMembers(members []*discordgo.Member, limit int) []*discordgo.Member {
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].User.ID < members[j].User.ID
+	})
+
+	if len(members) > limit {
+		members = members[:limit]
+	}
+
+	return members
+}
+
+const (
+	// maxFetchAllMembers is the max member count of servers whose members are requested from the gateway
+	// when state doesn't hold all of them
+	maxFetchAllMembers = 25000
+)
+
+// errMembersNotCached is returned by the functions going through all the members when state doesn't hold all of them
+// and they couldn't be fetched, the results would otherwise be silently incomplete on large servers
+var errMembersNotCached = errors.New("Not all members of this server are cached, try again later")
+
+// allMembersCached returns true if state holds all the members of the guild, GS has to be read locked
+func (c *Context) allMembersCached() bool {
+	cached := 0
+	for _, v := range c.GS.Members {
+		if v.MemberSet {
+			cached++
+		}
+	}
+
+	return cached >= c.GS.Guild.MemberCount
+}
+
+// forEachMember calls f with every member of the server, if state doesn't hold all of them they're requested from the gateway instead.
+// When going through state f is called with GS read locked
+func (c *Context) forEachMember(f func(ms *dstate.MemberState)) error {
+	c.GS.RLock()
+	if c.allMembersCached() {
+		for _, v := range c.GS.Members {
+			if v.MemberSet {
+				f(v)
+			}
+		}
+		c.GS.RUnlock()
+		return nil
+	}
+
+	memberCount := c.GS.Guild.MemberCount
+	c.GS.RUnlock()
+
+	if memberCount > maxFetchAllMembers {
+		return errMembersNotCached
+	}
+
+	members, err := bot.BatchMemberJobManager.FetchAllMembers(c.GS.ID, time.Second*10)
+	if err != nil {
+		if err == bot.ErrGuildNotOnProcess || err == bot.ErrTimeoutWaitingForMember {
+			return errMembersNotCached
+		}
+
+		return err
+	}
+
+	for _, v := range members {
+		if v == nil || v.User == nil {
+			continue
+		}
+
+		f(dstate.MSFromDGoMember(c.GS, v))
+	}
+
+	return nil
+}
+
+// tmplGetMembersWithRole returns up to limit (max 100) members that has the specified role,
+// members are requested from the gateway if not all of them are in state
+func (c *Context) tmplGetMembersWithRole(roleID interface{}, optionalArgs ...interface{}) ([]*discordgo.Member, error) {
+	if c.IncreaseCheckCallCounter("member_list", 2) {
+		return nil, ErrTooManyCalls
+	}
+
+	role := ToInt64(roleID)
+	if role == 0 {
+		return nil, errors.New("No role id specified")
+	}
+
+	limit := memberResultLimit(optionalArgs)
+
+	if c.GS.Role(true, role) == nil {
+		return nil, errors.New("Unknown role")
+	}
+
+	matches := make([]*discordgo.Member, 0)
+	err := c.forEachMember(func(ms *dstate.MemberState) {
+		if common.ContainsInt64Slice(ms.Roles, role) {
+			matches = append(matches, ms.DGoCopy())
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sortMembers(matches, limit), nil
+}
+
+// tmplSearchMembers returns up to limit (max 100) members whose username or nickname starts with query (case insensitive),
+// members not in state are requested from the gateway.
+func (c *Context) tmplSearchMembers(query string, optionalArgs ...interface{}) ([]*discordgo.Member, error) {
+	if c.IncreaseCheckCallCounter("member_search", 2) {
+		return nil, ErrTooManyCalls
+	}
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errors.New("No search query specified")
+	}
+
+	limit := memberResultLimit(optionalArgs)
+	lowerQuery := strings.ToLower(query)
+
+	members, err := bot.BatchMemberJobManager.SearchByUsername(c.GS.ID, query)
+	if err == nil {
+		result := make([]*discordgo.Member, 0, limit)
+		for _, v := range members {
+			if v == nil || v.User == nil {
+				continue
+			}
+
+			if !strings.HasPrefix(strings.ToLower(v.User.Username), lowerQuery) && !strings.HasPrefix(strings.ToLower(v.Nick), lowerQuery) {
+				continue
+			}
+
+			v.GuildID = c.GS.ID
+			result = append(result, v)
+			if len(result) >= limit {
+				break
+			}
+		}
+
+		return result, nil
+	}
+
+	if err != bot.ErrGuildNotOnProcess && err != bot.ErrTimeoutWaitingForMember {
+		return nil, err
+	}
+
+	// fall back to searching the members we have in state
+	c.GS.RLock()
+	matches := make([]*discordgo.Member, 0)
+	for _, v := range c.GS.Members {
+		if !v.MemberSet {
+			continue
+		}
+
+		if strings.HasPrefix(strings.ToLower(v.Username), lowerQuery) || strings.HasPrefix(strings.ToLower(v.Nick), lowerQuery) {
+			matches = append(matches, v.DGoCopy())
+		}
+	}
+	c.GS.RUnlock()
+
+	return sortMembers(matches, limit), nil
+}
+
+// tmplCountMembers counts the members matching the provided criteria, supported keys are:
+// "role" (role id), "joinedBefore" (time) and "joinedAfter" (time), members are requested from the gateway if not all of them are in state
+func (c *Context) tmplCountMembers(values ...interface{}) (int, error) {
+	if c.IncreaseCheckCallCounter("member_count", 2) {
+		return 0, ErrTooManyCalls
+	}
+
+	var role int64
+	var joinedBefore, joinedAfter time.Time
+
+	if len(values) > 0 {
+		criteria, err := StringKeyDictionary(values...)
+		if err != nil {
+			return 0, err
+		}
+
+		for k, v := range criteria {
+			switch strings.ToLower(k) {
+			case "role":
+				role = ToInt64(v)
+				if role == 0 {
+					return 0, errors.New("Invalid role id")
+				}
+			case "joinedbefore":
+				t, ok := v.(time.Time)
+				if !ok {
+					return 0, errors.New("joinedBefore has to be a time")
+				}
+				joinedBefore = t
+			case "joinedafter":
+				t, ok := v.(time.Time)
+				if !ok {
+					return 0, errors.New("joinedAfter has to be a time")
+				}
+				joinedAfter = t
+			default:
+				return 0, errors.New("Unknown criteria: " + k)
+			}
+		}
+	}
+
+	count := 0
+	err := c.forEachMember(func(ms *dstate.MemberState) {
+		if role != 0 && !common.ContainsInt64Slice(ms.Roles, role) {
+			return
+		}
+
+		if !joinedBefore.IsZero() && (ms.JoinedAt.IsZero() || !ms.JoinedAt.Before(joinedBefore)) {
+			return
+		}
+
+		if !joinedAfter.IsZero() && (ms.JoinedAt.IsZero() || !ms.JoinedAt.After(joinedAfter)) {
+			return
+		}
+
+		count++
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
 func (c *Context) tmplGetChannel(channel interface{}) (*CtxChannel, error) {
 
 	if c.IncreaseCheckGenericAPICall() {
@@ -1224,6 +1472,191 @@ func (c *Context) tmplEditChannelTopic(channel interface{}, newTopic string) (st
 	return "", err
 }
 
+func (c *Context) tmplGetChannelOverwrites(channel interface{}) ([]*discordgo.PermissionOverwrite, error) {
+	if c.IncreaseCheckStateLock() {
+		return nil, ErrTooManyCalls
+	}
+
+	cID := c.ChannelArgNoDM(channel)
+	if cID == 0 {
+		return nil, errors.New("Unknown channel")
+	}
+
+	c.GS.RLock()
+	defer c.GS.RUnlock()
+
+	cs := c.GS.Channel(false, cID)
+	if cs == nil {
+		return nil, errors.New("Channel not in state")
+	}
+
+	overwrites := make([]*discordgo.PermissionOverwrite, 0, len(cs.PermissionOverwrites))
+	for _, v := range cs.PermissionOverwrites {
+		cop := *v
+		overwrites = append(overwrites, &cop)
+	}
+
+	return overwrites, nil
+}
+
+func (c *Context) tmplGetTargetPermissionsIn(target interface{}, channel interface{}) (int, error) {
+	if c.IncreaseCheckGenericAPICall() {
+		return 0, ErrTooManyAPICalls
+	}
+
+	targetID := targetUserID(target)
+	if targetID == 0 {
+		return 0, errors.New("Unknown member")
+	}
+
+	cID := c.ChannelArgNoDM(channel)
+	if cID == 0 {
+		return 0, errors.New("Unknown channel")
+	}
+
+	ms, err := bot.GetMember(c.GS.ID, targetID)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.GS.MemberPermissionsMS(true, cID, ms)
+}
+
+func (c *Context) tmplTargetHasPermissionsIn(target interface{}, channel interface{}, permissions interface{}) (bool, error) {
+	perms, err := c.tmplGetTargetPermissionsIn(target, channel)
+	if err != nil {
+		return false, err
+	}
+
+	if perms&discordgo.PermissionAdministrator != 0 {
+		return true, nil
+	}
+
+	needed := tmplToInt(permissions)
+	return perms&needed == needed, nil
+}
+
+// checkCanEditOverwrite makes sure the bot has manage roles in the channel, and that its highest role is above
+// the role or member the overwrite is for, the same way discord does it for humans.
+func (c *Context) checkCanEditOverwrite(channelID, targetID int64, targetType string) error {
+	if !bot.BotProbablyHasPermissionGS(c.GS, channelID, discordgo.PermissionManageRoles) {
+		return errors.New("Bot does not have the manage permissions permission in that channel")
+	}
+
+	botMember, err := bot.GetMember(c.GS.ID, common.BotUser.ID)
+	if err != nil {
+		return err
+	}
+
+	var targetMember *dstate.MemberState
+	if targetType == "member" {
+		targetMember, err = bot.GetMember(c.GS.ID, targetID)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.GS.RLock()
+	defer c.GS.RUnlock()
+
+	switch targetType {
+	case "role":
+		role := c.GS.Role(false, targetID)
+		if role == nil {
+			return errors.New("Unknown role")
+		}
+
+		if role.ID != c.GS.ID && !bot.IsMemberAboveRole(c.GS, botMember, role) {
+			return errors.New("Bot's highest role is not above the target role")
+		}
+	case "member":
+		if targetMember.ID != botMember.ID && !bot.IsMemberAbove(c.GS, botMember, targetMember) {
+			return errors.New("Bot's highest role is not above the target member's highest role")
+		}
+	}
+
+	return nil
+}
+
+func overwriteTargetType(targetType string) (string, error) {
+	switch strings.ToLower(targetType) {
+	case "role":
+		return "role", nil
+	case "member", "user":
+		return "member", nil
+	}
+
+	return "", errors.New("Invalid overwrite type, has to be either role or member")
+}
+
+func (c *Context) tmplEditChannelOverwrite(channel interface{}, target interface{}, targetType string, allow interface{}, deny interface{}) (string, error) {
+	if c.IncreaseCheckCallCounter("edit_overwrite", 10) {
+		return "", ErrTooManyCalls
+	}
+
+	cID := c.ChannelArgNoDM(channel)
+	if cID == 0 {
+		return "", errors.New("Unknown channel")
+	}
+
+	tType, err := overwriteTargetType(targetType)
+	if err != nil {
+		return "", err
+	}
+
+	targetID := targetUserID(target)
+	if targetID == 0 {
+		return "", errors.New("Unknown overwrite target")
+	}
+
+	if err := c.checkCanEditOverwrite(cID, targetID, tType); err != nil {
+		return "", err
+	}
+
+	err = common.BotSession.ChannelPermissionSet(cID, targetID, tType, tmplToInt(allow), tmplToInt(deny))
+	return "", err
+}
+
+func (c *Context) tmplDeleteChannelOverwrite(channel interface{}, target interface{}) (string, error) {
+	if c.IncreaseCheckCallCounter("edit_overwrite", 10) {
+		return "", ErrTooManyCalls
+	}
+
+	cID := c.ChannelArgNoDM(channel)
+	if cID == 0 {
+		return "", errors.New("Unknown channel")
+	}
+
+	targetID := targetUserID(target)
+	if targetID == 0 {
+		return "", errors.New("Unknown overwrite target")
+	}
+
+	tType := ""
+	c.GS.RLock()
+	if cs := c.GS.Channel(false, cID); cs != nil {
+		for _, v := range cs.PermissionOverwrites {
+			if v.ID == targetID {
+				tType = v.Type
+				break
+			}
+		}
+	}
+	c.GS.RUnlock()
+
+	if tType == "" {
+		// no overwrite to delete
+		return "", nil
+	}
+
+	if err := c.checkCanEditOverwrite(cID, targetID, tType); err != nil {
+		return "", err
+	}
+
+	err := common.BotSession.ChannelPermissionDelete(cID, targetID)
+	return "", err
+}
+
 func (c *Context) tmplOnlineCount() (int, error) {
 	if c.IncreaseCheckCallCounter("online_users", 1) {
 		return 0, ErrTooManyCalls
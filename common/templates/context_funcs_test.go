@@ -0,0 +1,182 @@
+package templates
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/dstate/v2"
+)
+
+var memberTestJoinedAt = time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// newMemberTestContext returns a context with a guild holding 4 members in state,
+// memberCount can be set higher than that to simulate a guild that isn't fully cached
+func newMemberTestContext(memberCount int) *Context {
+	gs := &dstate.GuildState{
+		ID: 1,
+		Guild: &discordgo.Guild{
+			ID:          1,
+			MemberCount: memberCount,
+			Roles: []*discordgo.Role{
+				&discordgo.Role{ID: 10, Position: 2},
+				&discordgo.Role{ID: 20, Position: 1},
+				&discordgo.Role{ID: 30, Position: 3},
+			},
+		},
+		Members: make(map[int64]*dstate.MemberState),
+	}
+
+	members := []*dstate.MemberState{
+		&dstate.MemberState{ID: 104, Username: "dave", Nick: "alpha", Roles: []int64{10}, JoinedAt: memberTestJoinedAt.Add(time.Hour * 24 * 30)},
+		&dstate.MemberState{ID: 101, Username: "alice", Roles: []int64{10, 20}, JoinedAt: memberTestJoinedAt},
+		&dstate.MemberState{ID: 103, Username: "Carol", Roles: []int64{20}, JoinedAt: memberTestJoinedAt.Add(time.Hour * 24)},
+		&dstate.MemberState{ID: 102, Username: "bob", Roles: []int64{10}},
+	}
+
+	for _, v := range members {
+		v.Guild = gs
+		v.MemberSet = true
+		gs.Members[v.ID] = v
+	}
+
+	return &Context{
+		GS:       gs,
+		Counters: make(map[string]int),
+	}
+}
+
+func memberIDs(members []*discordgo.Member) []int64 {
+	result := make([]int64, 0, len(members))
+	for _, v := range members {
+		result = append(result, v.User.ID)
+	}
+
+	return result
+}
+
+func TestGetMembersWithRole(t *testing.T) {
+	cases := []struct {
+		memberCount int
+		role        interface{}
+		args        []interface{}
+		expectedIDs []int64
+		shouldError bool
+	}{
+		{4, 10, nil, []int64{101, 102, 104}, false},
+		{4, "20", nil, []int64{101, 103}, false},
+		{4, int64(10), []interface{}{2}, []int64{101, 102}, false},
+		{4, 10, []interface{}{1000}, []int64{101, 102, 104}, false},
+		{4, 30, nil, []int64{}, false},
+		{4, 40, nil, nil, true},
+		{4, 0, nil, nil, true},
+
+		// not all members are in state, and they can't be requested as the guild isn't on this process
+		{5, 10, nil, nil, true},
+	}
+
+	for i, c := range cases {
+		t.Run("case #"+strconv.Itoa(i), func(t *testing.T) {
+			ctx := newMemberTestContext(c.memberCount)
+			members, err := ctx.tmplGetMembersWithRole(c.role, c.args...)
+			if err != nil {
+				if !c.shouldError {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+
+			if c.shouldError {
+				t.Fatalf("Expected an error, GOT %v", memberIDs(members))
+			}
+
+			if ids := memberIDs(members); !reflect.DeepEqual(ids, c.expectedIDs) {
+				t.Errorf("GOT %v EXPECTED %v", ids, c.expectedIDs)
+			}
+		})
+	}
+}
+
+func TestSearchMembers(t *testing.T) {
+	cases := []struct {
+		query       string
+		args        []interface{}
+		expectedIDs []int64
+		shouldError bool
+	}{
+		{"a", nil, []int64{101, 104}, false},
+		{"A", []interface{}{1}, []int64{101}, false},
+		{"carol", nil, []int64{103}, false},
+		{" bo ", nil, []int64{102}, false},
+		{"zed", nil, []int64{}, false},
+		{"  ", nil, nil, true},
+	}
+
+	for i, c := range cases {
+		t.Run("case #"+strconv.Itoa(i), func(t *testing.T) {
+			// the guild isn't on this process so this searches the members in state
+			ctx := newMemberTestContext(4)
+			members, err := ctx.tmplSearchMembers(c.query, c.args...)
+			if err != nil {
+				if !c.shouldError {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+
+			if c.shouldError {
+				t.Fatalf("Expected an error, GOT %v", memberIDs(members))
+			}
+
+			if ids := memberIDs(members); !reflect.DeepEqual(ids, c.expectedIDs) {
+				t.Errorf("GOT %v EXPECTED %v", ids, c.expectedIDs)
+			}
+		})
+	}
+}
+
+func TestCountMembers(t *testing.T) {
+	cases := []struct {
+		memberCount   int
+		criteria      []interface{}
+		expectedCount int
+		shouldError   bool
+	}{
+		{4, nil, 4, false},
+		{4, []interface{}{"role", 10}, 3, false},
+		{4, []interface{}{"role", 30}, 0, false},
+		{4, []interface{}{"joinedAfter", memberTestJoinedAt}, 2, false},
+		{4, []interface{}{"joinedBefore", memberTestJoinedAt.Add(time.Hour)}, 1, false},
+		{4, []interface{}{"role", 10, "joinedAfter", memberTestJoinedAt}, 1, false},
+		{4, []interface{}{"role", 0}, 0, true},
+		{4, []interface{}{"joinedAfter", "yesterday"}, 0, true},
+		{4, []interface{}{"nickname", "alpha"}, 0, true},
+		{4, []interface{}{"role"}, 0, true},
+
+		// not all members are in state, and they can't be requested as the guild isn't on this process
+		{5, nil, 0, true},
+	}
+
+	for i, c := range cases {
+		t.Run("case #"+strconv.Itoa(i), func(t *testing.T) {
+			ctx := newMemberTestContext(c.memberCount)
+			count, err := ctx.tmplCountMembers(c.criteria...)
+			if err != nil {
+				if !c.shouldError {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+
+			if c.shouldError {
+				t.Fatalf("Expected an error, GOT %d", count)
+			}
+
+			if count != c.expectedCount {
+				t.Errorf("GOT %d EXPECTED %d", count, c.expectedCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package common
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"emperror.dev/errors"
+)
+
+// PluginWithUserData is for plugins that store data about users, it's used to handle data export and erasure requests
+type PluginWithUserData interface {
+	Plugin
+
+	// ExportUserData returns all the data the plugin stores about the user, it's encoded as json in the export
+	ExportUserData(ctx context.Context, userID int64) (interface{}, error)
+
+	// EraseUserData deletes or anonymizes all the data the plugin stores about the user
+	EraseUserData(ctx context.Context, userID int64) error
+}
+
+// ExportUserData collects the data stored about the user from all plugins implementing PluginWithUserData, keyed by the plugins SysName
+func ExportUserData(ctx context.Context, userID int64) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+
+	for _, v := range Plugins {
+		cast, ok := v.(PluginWithUserData)
+		if !ok {
+			continue
+		}
+
+		data, err := cast.ExportUserData(ctx, userID)
+		if err != nil {
+			return nil, errors.WithMessage(err, v.PluginInfo().SysName)
+		}
+
+		result[v.PluginInfo().SysName] = data
+	}
+
+	return result, nil
+}
+
+// ExportUserDataArchive exports the data stored about the user and returns it as a zip with a json file for every plugin
+func ExportUserDataArchive(ctx context.Context, userID int64) ([]byte, error) {
+	exported, err := ExportUserData(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+
+	for name, v := range exported {
+		f, err := w.Create(name + ".json")
+		if err != nil {
+			return nil, err
+		}
+
+		encoded, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = f.Write(encoded)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = w.Close()
+	return buf.Bytes(), err
+}
+
+// EraseUserData erases the data stored about the user in all plugins implementing PluginWithUserData,
+// it keeps going if one fails and returns the names of the plugins that succeeded along with the combined errors
+func EraseUserData(ctx context.Context, userID int64) (erased []string, err error) {
+	for _, v := range Plugins {
+		cast, ok := v.(PluginWithUserData)
+		if !ok {
+			continue
+		}
+
+		pluginErr := cast.EraseUserData(ctx, userID)
+		if pluginErr != nil {
+			err = errors.Append(err, errors.WithMessage(pluginErr, v.PluginInfo().SysName))
+			continue
+		}
+
+		erased = append(erased, v.PluginInfo().Name)
+	}
+
+	return erased, err
+}
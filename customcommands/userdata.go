@@ -0,0 +1,28 @@
+package customcommands
+
+import (
+	"context"
+
+	"emperror.dev/errors"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/customcommands/models"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+var _ common.PluginWithUserData = (*Plugin)(nil)
+
+// ExportUserData returns the database entries custom commands have stored for the user,
+// the values are the raw msgpack encoded data
+func (p *Plugin) ExportUserData(ctx context.Context, userID int64) (interface{}, error) {
+	entries, err := models.TemplatesUserDatabases(models.TemplatesUserDatabaseWhere.UserID.EQ(userID), qm.OrderBy("id asc")).AllG(ctx)
+	if err != nil {
+		return nil, errors.WrapIf(err, "templates_user_database")
+	}
+
+	return map[string]interface{}{"database_entries": entries}, nil
+}
+
+func (p *Plugin) EraseUserData(ctx context.Context, userID int64) error {
+	_, err := models.TemplatesUserDatabases(models.TemplatesUserDatabaseWhere.UserID.EQ(userID)).DeleteAll(ctx, common.PQ)
+	return errors.WrapIf(err, "templates_user_database")
+}
@@ -0,0 +1,237 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/jonas747/dstate/v2"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/common/config"
+	"github.com/jonas747/yagpdb/web"
+	"github.com/lib/pq"
+)
+
+// MaxArchivedAttachmentSize is the max size of a single attachment that will be archived, bigger ones are only linked
+const MaxArchivedAttachmentSize = 8 * 1024 * 1024
+
+var (
+	confAttachmentsDir = config.RegisterOption("yagpdb.logs.attachments_dir", "Directory to archive message log attachments to, archival is disabled if no storage is set", "")
+
+	// AttachmentStorage is where archived attachments are kept, if nil archival is disabled.
+	// It defaults to a FSAttachmentStore if yagpdb.logs.attachments_dir is set,
+	// set it to something else before the plugin is registered to use another storage backend.
+	AttachmentStorage AttachmentStore
+
+	attachmentHTTPClient = &http.Client{
+		Timeout: time.Second * 30,
+	}
+)
+
+// AttachmentStore stores archived attachments by key
+type AttachmentStore interface {
+	Put(ctx context.Context, key string, data io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// FSAttachmentStore stores attachments on the local filesystem
+type FSAttachmentStore struct {
+	Dir string
+}
+
+var _ AttachmentStore = (*FSAttachmentStore)(nil)
+
+func (s *FSAttachmentStore) path(key string) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(key))
+}
+
+func (s *FSAttachmentStore) Put(ctx context.Context, key string, data io.Reader) error {
+	p := s.path(key)
+	err := os.MkdirAll(filepath.Dir(p), 0755)
+	if err != nil {
+		return err
+	}
+
+	// write to a temp file first so a failed download never leaves a partial file in place
+	tmp, err := ioutil.TempFile(filepath.Dir(p), ".tmp-")
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tmp, data)
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), p)
+}
+
+func (s *FSAttachmentStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(s.path(key))
+}
+
+func (s *FSAttachmentStore) Delete(ctx context.Context, key string) error {
+	err := os.Remove(s.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// ArchivedAttachment is an attachment of a logged message that has been copied to AttachmentStorage
+type ArchivedAttachment struct {
+	GuildID      int64
+	MessageID    int64
+	AttachmentID int64
+	Filename     string
+	Size         int
+	StorageKey   string
+}
+
+func attachmentStorageKey(guildID, messageID, attachmentID int64) string {
+	return fmt.Sprintf("%d/%d/%d", guildID, messageID, attachmentID)
+}
+
+// Link returns the url the archived attachment is served at
+func (a *ArchivedAttachment) Link() string {
+	return fmt.Sprintf("%s/public/%d/logs/attachments/%d/%d", web.BaseURL(), a.GuildID, a.MessageID, a.AttachmentID)
+}
+
+// ContentType guesses the content type from the filename
+func (a *ArchivedAttachment) ContentType() string {
+	ct := mime.TypeByExtension(strings.ToLower(filepath.Ext(a.Filename)))
+	if ct == "" {
+		return "application/octet-stream"
+	}
+	return ct
+}
+
+// IsImage returns true if it's a image type browsers can safely display inline
+func (a *ArchivedAttachment) IsImage() bool {
+	switch strings.SplitN(a.ContentType(), ";", 2)[0] {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+// archiveAttachments copies the attachments of the messages to AttachmentStorage, already archived ones are skipped
+func archiveAttachments(ctx context.Context, guildID int64, msgs []*dstate.MessageState) {
+	if AttachmentStorage == nil {
+		return
+	}
+
+	for _, m := range msgs {
+		for _, a := range m.Attachments {
+			if a.Size > MaxArchivedAttachmentSize {
+				continue
+			}
+
+			err := archiveAttachment(ctx, guildID, m.ID, a.ID, a.Filename, a.URL)
+			if err != nil {
+				logger.WithError(err).WithField("guild", guildID).WithField("message", m.ID).Error("failed archiving attachment")
+			}
+		}
+	}
+}
+
+func archiveAttachment(ctx context.Context, guildID, messageID, attachmentID int64, filename, url string) error {
+	var exists bool
+	err := common.PQ.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM message_log_attachments WHERE message_id = $1 AND attachment_id = $2)", messageID, attachmentID).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := attachmentHTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.WithStackIf(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status code downloading attachment: %d", resp.StatusCode)
+	}
+
+	key := attachmentStorageKey(guildID, messageID, attachmentID)
+	counter := &countingReader{r: io.LimitReader(resp.Body, MaxArchivedAttachmentSize)}
+	err = AttachmentStorage.Put(ctx, key, counter)
+	if err != nil {
+		return errors.WrapIf(err, "storage.put")
+	}
+
+	_, err = common.PQ.ExecContext(ctx, `INSERT INTO message_log_attachments (guild_id, message_id, attachment_id, created_at, filename, size, storage_key)
+VALUES ($1, $2, $3, now(), $4, $5, $6) ON CONFLICT DO NOTHING`, guildID, messageID, attachmentID, filename, counter.n, key)
+	return errors.WrapIf(err, "insert")
+}
+
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+// GetArchivedAttachments returns the archived attachments of the messages, by message id
+func GetArchivedAttachments(ctx context.Context, guildID int64, messageIDs []int64) (map[int64][]*ArchivedAttachment, error) {
+	result := make(map[int64][]*ArchivedAttachment)
+	if len(messageIDs) < 1 {
+		return result, nil
+	}
+
+	rows, err := common.PQ.QueryContext(ctx, `SELECT message_id, attachment_id, filename, size, storage_key FROM message_log_attachments
+WHERE guild_id = $1 AND message_id = ANY($2) ORDER BY attachment_id`, guildID, pq.Int64Array(messageIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		a := &ArchivedAttachment{GuildID: guildID}
+		err = rows.Scan(&a.MessageID, &a.AttachmentID, &a.Filename, &a.Size, &a.StorageKey)
+		if err != nil {
+			return nil, err
+		}
+
+		result[a.MessageID] = append(result[a.MessageID], a)
+	}
+
+	return result, rows.Err()
+}
+
+// GetArchivedAttachment returns a single archived attachment, or sql.ErrNoRows if it's not archived
+func GetArchivedAttachment(ctx context.Context, guildID, messageID, attachmentID int64) (*ArchivedAttachment, error) {
+	a := &ArchivedAttachment{GuildID: guildID, MessageID: messageID, AttachmentID: attachmentID}
+	err := common.PQ.QueryRowContext(ctx, `SELECT filename, size, storage_key FROM message_log_attachments
+WHERE guild_id = $1 AND message_id = $2 AND attachment_id = $3`, guildID, messageID, attachmentID).Scan(&a.Filename, &a.Size, &a.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
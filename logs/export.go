@@ -0,0 +1,256 @@
+package logs
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/logs/models"
+	"github.com/jonas747/yagpdb/web"
+	"goji.io/pat"
+)
+
+// MaxExportInlineSize is the max total size of archived images embedded into a html export, the rest are linked
+const MaxExportInlineSize = 16 * 1024 * 1024
+
+// LogExport is the json export of a message log
+type LogExport struct {
+	ID             int       `json:"id"`
+	GuildID        int64     `json:"guild_id,string"`
+	ChannelID      int64     `json:"channel_id,string"`
+	ChannelName    string    `json:"channel_name"`
+	CreatedAt      time.Time `json:"created_at"`
+	AuthorID       int64     `json:"author_id,string"`
+	AuthorUsername string    `json:"author_username"`
+
+	Messages []*ExportedMessage `json:"messages"`
+}
+
+type ExportedMessage struct {
+	ID             int64     `json:"id,string"`
+	AuthorID       int64     `json:"author_id,string"`
+	AuthorUsername string    `json:"author_username"`
+	CreatedAt      time.Time `json:"created_at"`
+	Deleted        bool      `json:"deleted"`
+	Content        string    `json:"content"`
+
+	Attachments []*ExportedAttachment `json:"archived_attachments"`
+}
+
+type ExportedAttachment struct {
+	ID       int64  `json:"id,string"`
+	Filename string `json:"filename"`
+	Size     int    `json:"size"`
+	URL      string `json:"url"`
+}
+
+func setExportFilename(w http.ResponseWriter, logs *models.MessageLogs2, ext string) {
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"log-%d-%d.%s\"", logs.GuildID, logs.ID, ext))
+}
+
+// HandleLogsExportHTML renders the log into a single html file that doesn't need anything else to be viewed
+func HandleLogsExportHTML(w http.ResponseWriter, r *http.Request) interface{} {
+	g, tmpl := web.GetBaseCPContextData(r.Context())
+
+	logs := r.Context().Value(ctxKeyLogs).(*models.MessageLogs2)
+	messages := r.Context().Value(ctxKeyMessages).([]*models.Messages2)
+	config := r.Context().Value(ctxKeyConfig).(*models.GuildLoggingConfig)
+
+	canViewDeleted := CanViewDeleted(r, config)
+	views := CreateMessageViews(r.Context(), g.ID, messages)
+
+	// embed archived images so the export still works once they're gone from discord and the bot
+	inlineImages := make(map[int64]template.URL)
+	inlinedSize := 0
+	if AttachmentStorage != nil {
+		for _, v := range views {
+			if v.Model.Deleted && !canViewDeleted {
+				continue
+			}
+
+			for _, a := range v.Attachments {
+				if !a.IsImage() || inlinedSize+a.Size > MaxExportInlineSize {
+					continue
+				}
+
+				data, err := readArchivedAttachment(r, a)
+				if err != nil {
+					web.CtxLogger(r.Context()).WithError(err).Error("failed reading archived attachment")
+					continue
+				}
+
+				inlinedSize += len(data)
+				inlineImages[a.AttachmentID] = template.URL("data:" + a.ContentType() + ";base64," + base64.StdEncoding.EncodeToString(data))
+			}
+		}
+	}
+
+	setExportFilename(w, logs, "html")
+
+	tmpl["CanViewDeleted"] = canViewDeleted
+	tmpl["Logs"] = logs
+	tmpl["Messages"] = views
+	tmpl["InlineImages"] = inlineImages
+	tmpl["ExportedAt"] = time.Now().UTC().Format(time.RFC822)
+
+	return tmpl
+}
+
+func readArchivedAttachment(r *http.Request, a *ArchivedAttachment) ([]byte, error) {
+	f, err := AttachmentStorage.Open(r.Context(), a.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ioutil.ReadAll(io.LimitReader(f, MaxArchivedAttachmentSize))
+}
+
+// HandleLogsExportJSON returns the log as json
+func HandleLogsExportJSON(w http.ResponseWriter, r *http.Request) interface{} {
+	g, _ := web.GetBaseCPContextData(r.Context())
+
+	logs := r.Context().Value(ctxKeyLogs).(*models.MessageLogs2)
+	messages := r.Context().Value(ctxKeyMessages).([]*models.Messages2)
+	config := r.Context().Value(ctxKeyConfig).(*models.GuildLoggingConfig)
+
+	canViewDeleted := CanViewDeleted(r, config)
+	views := CreateMessageViews(r.Context(), g.ID, messages)
+
+	export := &LogExport{
+		ID:             logs.ID,
+		GuildID:        logs.GuildID,
+		ChannelID:      logs.ChannelID,
+		ChannelName:    logs.ChannelName,
+		CreatedAt:      logs.CreatedAt,
+		AuthorID:       logs.AuthorID,
+		AuthorUsername: logs.AuthorUsername,
+		Messages:       make([]*ExportedMessage, 0, len(views)),
+	}
+
+	for _, v := range views {
+		m := &ExportedMessage{
+			ID:             v.Model.ID,
+			AuthorID:       v.Model.AuthorID,
+			AuthorUsername: v.Model.AuthorUsername,
+			CreatedAt:      v.Model.CreatedAt,
+			Deleted:        v.Model.Deleted,
+			Attachments:    []*ExportedAttachment{},
+		}
+
+		if !v.Model.Deleted || canViewDeleted {
+			m.Content = v.Model.Content
+			for _, a := range v.Attachments {
+				m.Attachments = append(m.Attachments, &ExportedAttachment{
+					ID:       a.AttachmentID,
+					Filename: a.Filename,
+					Size:     a.Size,
+					URL:      a.Link(),
+				})
+			}
+		}
+
+		export.Messages = append(export.Messages, m)
+	}
+
+	setExportFilename(w, logs, "json")
+	return export
+}
+
+// logFetchErrToJSON turns the alerts LogFetchMW responds with on errors into a json error
+func logFetchErrToJSON(inner web.CustomHandlerFunc) web.CustomHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) interface{} {
+		out := inner(w, r)
+
+		tmpl, ok := out.(web.TemplateData)
+		if !ok {
+			return out
+		}
+
+		msg := "Failed retrieving message logs"
+		if alerts, ok := tmpl["Alerts"].([]*web.Alert); ok && len(alerts) > 0 {
+			msg = alerts[len(alerts)-1].Message
+		}
+
+		return web.NewPublicError(msg)
+	}
+}
+
+// HandleArchivedAttachment serves an archived attachment, with the same access rules as the logs it's in
+func HandleArchivedAttachment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	g, _ := web.GetBaseCPContextData(ctx)
+
+	if AttachmentStorage == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	messageID, _ := strconv.ParseInt(pat.Param(r, "message_id"), 10, 64)
+	attachmentID, _ := strconv.ParseInt(pat.Param(r, "attachment_id"), 10, 64)
+
+	config, err := GetConfig(common.PQ, ctx, g.ID)
+	if err != nil {
+		web.CtxLogger(ctx).WithError(err).Error("failed retrieving logging config")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !CheckCanAccessLogs(w, r, config) {
+		http.Error(w, "You don't have access to the logs on this server", http.StatusForbidden)
+		return
+	}
+
+	attachment, err := GetArchivedAttachment(ctx, g.ID, messageID, attachmentID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+
+		web.CtxLogger(ctx).WithError(err).Error("failed retrieving archived attachment")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	msg, err := models.Messages2s(models.Messages2Where.ID.EQ(messageID), models.Messages2Where.GuildID.EQ(g.ID)).OneG(ctx)
+	if err != nil && err != sql.ErrNoRows {
+		// we can't tell if the message was deleted, so don't risk serving it
+		web.CtxLogger(ctx).WithError(err).Error("failed retrieving archived attachment message")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if msg != nil && msg.Deleted && !CanViewDeleted(r, config) {
+		http.Error(w, "This attachment belongs to a deleted message", http.StatusForbidden)
+		return
+	}
+
+	f, err := AttachmentStorage.Open(ctx, attachment.StorageKey)
+	if err != nil {
+		web.CtxLogger(ctx).WithError(err).Error("failed opening archived attachment")
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	// anything that isn't a plain image is downloaded, so uploaded html and such can't run on our domain
+	disposition := "attachment"
+	if attachment.IsImage() {
+		disposition = "inline"
+	}
+
+	w.Header().Set("Content-Type", attachment.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, attachment.Filename))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Length", strconv.Itoa(attachment.Size))
+
+	io.Copy(w, f)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 
 	"emperror.dev/errors"
 	"github.com/jonas747/discordgo"
@@ -26,7 +27,9 @@ var (
 	logger = common.GetPluginLogger(&Plugin{})
 )
 
-type Plugin struct{}
+type Plugin struct {
+	stopWorker chan *sync.WaitGroup
+}
 
 func (p *Plugin) PluginInfo() *common.PluginInfo {
 	return &common.PluginInfo{
@@ -39,7 +42,13 @@ func (p *Plugin) PluginInfo() *common.PluginInfo {
 func RegisterPlugin() {
 	common.InitSchemas("logs", DBSchemas...)
 
-	p := &Plugin{}
+	if dir := confAttachmentsDir.GetString(); dir != "" && AttachmentStorage == nil {
+		AttachmentStorage = &FSAttachmentStore{Dir: dir}
+	}
+
+	p := &Plugin{
+		stopWorker: make(chan *sync.WaitGroup),
+	}
 	common.RegisterPlugin(p)
 }
 
@@ -168,6 +177,10 @@ func CreateChannelLog(ctx context.Context, config *models.GuildLoggingConfig, gu
 		return nil, errors.WrapIf(err, "commit")
 	}
 
+	if config.ArchiveAttachments.Bool && AttachmentStorage != nil {
+		go archiveAttachments(context.Background(), guildID, msgs)
+	}
+
 	return log, nil
 }
 
@@ -0,0 +1,256 @@
+package logs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/dstate/v2"
+	"github.com/jonas747/yagpdb/bot/eventsystem"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/logs/models"
+)
+
+// The message log continuously posts deleted and edited messages to a channel.
+// The content comes from the state message cache, so only messages sent while the bot was running
+// and within the state's max message age can be logged.
+
+const (
+	messageLogColorDeleted = 0xe74c3c
+	messageLogColorEdited  = 0xf39c12
+)
+
+// cachedLogMessage is a copy of a message and the author's roles, taken from state before it's updated
+type cachedLogMessage struct {
+	Message     *dstate.MessageState
+	AuthorRoles []int64
+}
+
+// MessageLogEnabled returns true if the message log is set up to log anything
+func MessageLogEnabled(config *models.GuildLoggingConfig) bool {
+	return config.MessageLogChannel.Int64 != 0 && (config.MessageLogDeleted.Bool || config.MessageLogEdited.Bool)
+}
+
+// copyStateMessages grabs copies of the messages from state, this has to be done before state is updated
+// as edits overwrite the content and the messages may be evicted at any time after
+func copyStateMessages(gs *dstate.GuildState, channelID int64, messageIDs ...int64) (channelName string, parentID int64, messages []*cachedLogMessage) {
+	gs.RLock()
+	defer gs.RUnlock()
+
+	cs := gs.Channel(false, channelID)
+	if cs == nil {
+		return "", 0, nil
+	}
+
+	for _, id := range messageIDs {
+		m := cs.Message(false, id)
+		if m == nil || m.Author == nil || m.Author.Bot {
+			continue
+		}
+
+		cached := &cachedLogMessage{
+			Message: m.Copy(),
+		}
+
+		if ms := gs.Member(false, m.Author.ID); ms != nil {
+			cached.AuthorRoles = make([]int64, len(ms.Roles))
+			copy(cached.AuthorRoles, ms.Roles)
+		}
+
+		messages = append(messages, cached)
+	}
+
+	return cs.Name, cs.ParentID, messages
+}
+
+// handleMessageLogEvent runs before state is updated so it can see the old content of edited messages
+func handleMessageLogEvent(evt *eventsystem.EventData) {
+	if evt.GS == nil {
+		return
+	}
+
+	var channelID int64
+	var messageIDs []int64
+	var edit *discordgo.MessageUpdate
+
+	switch evt.Type {
+	case eventsystem.EventMessageDelete:
+		channelID = evt.MessageDelete().ChannelID
+		messageIDs = []int64{evt.MessageDelete().ID}
+	case eventsystem.EventMessageDeleteBulk:
+		channelID = evt.MessageDeleteBulk().ChannelID
+		messageIDs = evt.MessageDeleteBulk().Messages
+	case eventsystem.EventMessageUpdate:
+		edit = evt.MessageUpdate()
+		// embed unfurls and the like are sent as updates without content
+		if edit.Author == nil || edit.Author.Bot || edit.EditedTimestamp == "" {
+			return
+		}
+		channelID = edit.ChannelID
+		messageIDs = []int64{edit.ID}
+	default:
+		return
+	}
+
+	channelName, parentID, messages := copyStateMessages(evt.GS, channelID, messageIDs...)
+	if len(messages) < 1 {
+		return
+	}
+
+	guildID := evt.GS.ID
+	evtType := evt.Type
+
+	go func() {
+		config, err := GetConfigCached(common.PQ, guildID)
+		if err != nil {
+			logger.WithError(err).WithField("guild", guildID).Error("failed retrieving config for message log")
+			return
+		}
+
+		if !MessageLogEnabled(config) || config.MessageLogChannel.Int64 == channelID {
+			return
+		}
+
+		if common.ContainsInt64SliceOneOf(config.MessageLogIgnoredChannels, []int64{channelID, parentID}) {
+			return
+		}
+
+		filtered := messages[:0]
+		for _, v := range messages {
+			if !common.ContainsInt64SliceOneOf(v.AuthorRoles, config.MessageLogIgnoredRoles) {
+				filtered = append(filtered, v)
+			}
+		}
+
+		if len(filtered) < 1 {
+			return
+		}
+
+		var embed *discordgo.MessageEmbed
+		switch {
+		case edit != nil:
+			if !config.MessageLogEdited.Bool || edit.Content == filtered[0].Message.Content {
+				return
+			}
+			embed = messageLogEditedEmbed(guildID, channelName, filtered[0].Message, edit.Message)
+		case evtType == eventsystem.EventMessageDeleteBulk:
+			if !config.MessageLogDeleted.Bool {
+				return
+			}
+			embed = messageLogBulkDeletedEmbed(channelName, len(messageIDs), filtered)
+		default:
+			if !config.MessageLogDeleted.Bool {
+				return
+			}
+			embed = messageLogDeletedEmbed(channelName, filtered[0].Message)
+		}
+
+		_, err = common.BotSession.ChannelMessageSendEmbed(config.MessageLogChannel.Int64, embed)
+		if err != nil && !common.IsDiscordErr(err, discordgo.ErrCodeMissingPermissions, discordgo.ErrCodeMissingAccess, discordgo.ErrCodeUnknownChannel) {
+			logger.WithError(err).WithField("guild", guildID).Error("failed sending message log entry")
+		}
+	}()
+}
+
+func messageLogAuthor(m *dstate.MessageState) *discordgo.MessageEmbedAuthor {
+	return &discordgo.MessageEmbedAuthor{
+		Name:    fmt.Sprintf("%s#%s", m.Author.Username, m.Author.Discriminator),
+		IconURL: discordgo.EndpointUserAvatar(m.Author.ID, m.Author.Avatar),
+	}
+}
+
+func messageLogFooter(m *dstate.MessageState) *discordgo.MessageEmbedFooter {
+	return &discordgo.MessageEmbedFooter{
+		Text: fmt.Sprintf("User ID: %d | Message ID: %d", m.Author.ID, m.ID),
+	}
+}
+
+func messageLogAttachments(m *dstate.MessageState) string {
+	if len(m.Attachments) < 1 {
+		return ""
+	}
+
+	var builder strings.Builder
+	for _, v := range m.Attachments {
+		builder.WriteString(fmt.Sprintf("[%s](%s)\n", v.Filename, v.URL))
+	}
+
+	return common.CutStringShort(builder.String(), 1024)
+}
+
+func messageLogContent(content string, maxLen int) string {
+	if content == "" {
+		return "*No text content*"
+	}
+
+	return common.CutStringShort(content, maxLen)
+}
+
+func messageLogDeletedEmbed(channelName string, m *dstate.MessageState) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("Message deleted in #%s", channelName),
+		Color:       messageLogColorDeleted,
+		Author:      messageLogAuthor(m),
+		Description: messageLogContent(m.Content, 2000),
+		Footer:      messageLogFooter(m),
+		Timestamp:   time.Now().Format(time.RFC3339),
+	}
+
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+		Name:  "Sent",
+		Value: m.ParsedCreated.UTC().Format(time.RFC822),
+	})
+
+	if attachments := messageLogAttachments(m); attachments != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "Attachments",
+			Value: attachments,
+		})
+	}
+
+	return embed
+}
+
+func messageLogEditedEmbed(guildID int64, channelName string, before *dstate.MessageState, after *discordgo.Message) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("Message edited in #%s", channelName),
+		URL:         fmt.Sprintf("https://discord.com/channels/%d/%d/%d", guildID, before.ChannelID, before.ID),
+		Color:       messageLogColorEdited,
+		Author:      messageLogAuthor(before),
+		Footer:      messageLogFooter(before),
+		Timestamp:   time.Now().Format(time.RFC3339),
+		Description: "**Before:**\n" + messageLogContent(before.Content, 900) + "\n\n**After:**\n" + messageLogContent(after.Content, 900),
+	}
+
+	if attachments := messageLogAttachments(before); attachments != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "Attachments",
+			Value: attachments,
+		})
+	}
+
+	return embed
+}
+
+func messageLogBulkDeletedEmbed(channelName string, total int, messages []*cachedLogMessage) *discordgo.MessageEmbed {
+	var builder strings.Builder
+	for _, v := range messages {
+		line := fmt.Sprintf("**%s#%s:** %s\n", v.Message.Author.Username, v.Message.Author.Discriminator, messageLogContent(v.Message.Content, 200))
+		if builder.Len()+len(line) > 2000 {
+			builder.WriteString("...")
+			break
+		}
+		builder.WriteString(line)
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("%d messages bulk deleted in #%s", total, channelName),
+		Color:       messageLogColorDeleted,
+		Description: builder.String(),
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("%d of them were cached and are shown", len(messages)),
+		},
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+}
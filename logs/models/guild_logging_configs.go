@@ -34,6 +34,14 @@ type GuildLoggingConfig struct {
 	ManageMessagesCanViewDeleted null.Bool        `boil:"manage_messages_can_view_deleted" json:"manage_messages_can_view_deleted,omitempty" toml:"manage_messages_can_view_deleted" yaml:"manage_messages_can_view_deleted,omitempty"`
 	EveryoneCanViewDeleted       null.Bool        `boil:"everyone_can_view_deleted" json:"everyone_can_view_deleted,omitempty" toml:"everyone_can_view_deleted" yaml:"everyone_can_view_deleted,omitempty"`
 	MessageLogsAllowedRoles      types.Int64Array `boil:"message_logs_allowed_roles" json:"message_logs_allowed_roles,omitempty" toml:"message_logs_allowed_roles" yaml:"message_logs_allowed_roles,omitempty"`
+	MessageLogChannel            null.Int64       `boil:"message_log_channel" json:"message_log_channel,omitempty" toml:"message_log_channel" yaml:"message_log_channel,omitempty"`
+	MessageLogDeleted            null.Bool        `boil:"message_log_deleted" json:"message_log_deleted,omitempty" toml:"message_log_deleted" yaml:"message_log_deleted,omitempty"`
+	MessageLogEdited             null.Bool        `boil:"message_log_edited" json:"message_log_edited,omitempty" toml:"message_log_edited" yaml:"message_log_edited,omitempty"`
+	MessageLogIgnoredChannels    types.Int64Array `boil:"message_log_ignored_channels" json:"message_log_ignored_channels,omitempty" toml:"message_log_ignored_channels" yaml:"message_log_ignored_channels,omitempty"`
+	MessageLogIgnoredRoles       types.Int64Array `boil:"message_log_ignored_roles" json:"message_log_ignored_roles,omitempty" toml:"message_log_ignored_roles" yaml:"message_log_ignored_roles,omitempty"`
+	ArchiveAttachments           null.Bool        `boil:"archive_attachments" json:"archive_attachments,omitempty" toml:"archive_attachments" yaml:"archive_attachments,omitempty"`
+	RetentionDays                null.Int         `boil:"retention_days" json:"retention_days,omitempty" toml:"retention_days" yaml:"retention_days,omitempty"`
+	RetentionMaxLogs             null.Int         `boil:"retention_max_logs" json:"retention_max_logs,omitempty" toml:"retention_max_logs" yaml:"retention_max_logs,omitempty"`
 
 	R *guildLoggingConfigR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L guildLoggingConfigL  `boil:"-" json:"-" toml:"-" yaml:"-"`
@@ -49,6 +57,14 @@ var GuildLoggingConfigColumns = struct {
 	ManageMessagesCanViewDeleted string
 	EveryoneCanViewDeleted       string
 	MessageLogsAllowedRoles      string
+	MessageLogChannel            string
+	MessageLogDeleted            string
+	MessageLogEdited             string
+	MessageLogIgnoredChannels    string
+	MessageLogIgnoredRoles       string
+	ArchiveAttachments           string
+	RetentionDays                string
+	RetentionMaxLogs             string
 }{
 	GuildID:                      "guild_id",
 	CreatedAt:                    "created_at",
@@ -59,6 +75,14 @@ var GuildLoggingConfigColumns = struct {
 	ManageMessagesCanViewDeleted: "manage_messages_can_view_deleted",
 	EveryoneCanViewDeleted:       "everyone_can_view_deleted",
 	MessageLogsAllowedRoles:      "message_logs_allowed_roles",
+	MessageLogChannel:            "message_log_channel",
+	MessageLogDeleted:            "message_log_deleted",
+	MessageLogEdited:             "message_log_edited",
+	MessageLogIgnoredChannels:    "message_log_ignored_channels",
+	MessageLogIgnoredRoles:       "message_log_ignored_roles",
+	ArchiveAttachments:           "archive_attachments",
+	RetentionDays:                "retention_days",
+	RetentionMaxLogs:             "retention_max_logs",
 }
 
 // Generated where
@@ -174,6 +198,14 @@ var GuildLoggingConfigWhere = struct {
 	ManageMessagesCanViewDeleted whereHelpernull_Bool
 	EveryoneCanViewDeleted       whereHelpernull_Bool
 	MessageLogsAllowedRoles      whereHelpertypes_Int64Array
+	MessageLogChannel            whereHelpernull_Int64
+	MessageLogDeleted            whereHelpernull_Bool
+	MessageLogEdited             whereHelpernull_Bool
+	MessageLogIgnoredChannels    whereHelpertypes_Int64Array
+	MessageLogIgnoredRoles       whereHelpertypes_Int64Array
+	ArchiveAttachments           whereHelpernull_Bool
+	RetentionDays                whereHelpernull_Int
+	RetentionMaxLogs             whereHelpernull_Int
 }{
 	GuildID:                      whereHelperint64{field: "\"guild_logging_configs\".\"guild_id\""},
 	CreatedAt:                    whereHelpernull_Time{field: "\"guild_logging_configs\".\"created_at\""},
@@ -184,6 +216,14 @@ var GuildLoggingConfigWhere = struct {
 	ManageMessagesCanViewDeleted: whereHelpernull_Bool{field: "\"guild_logging_configs\".\"manage_messages_can_view_deleted\""},
 	EveryoneCanViewDeleted:       whereHelpernull_Bool{field: "\"guild_logging_configs\".\"everyone_can_view_deleted\""},
 	MessageLogsAllowedRoles:      whereHelpertypes_Int64Array{field: "\"guild_logging_configs\".\"message_logs_allowed_roles\""},
+	MessageLogChannel:            whereHelpernull_Int64{field: "\"guild_logging_configs\".\"message_log_channel\""},
+	MessageLogDeleted:            whereHelpernull_Bool{field: "\"guild_logging_configs\".\"message_log_deleted\""},
+	MessageLogEdited:             whereHelpernull_Bool{field: "\"guild_logging_configs\".\"message_log_edited\""},
+	MessageLogIgnoredChannels:    whereHelpertypes_Int64Array{field: "\"guild_logging_configs\".\"message_log_ignored_channels\""},
+	MessageLogIgnoredRoles:       whereHelpertypes_Int64Array{field: "\"guild_logging_configs\".\"message_log_ignored_roles\""},
+	ArchiveAttachments:           whereHelpernull_Bool{field: "\"guild_logging_configs\".\"archive_attachments\""},
+	RetentionDays:                whereHelpernull_Int{field: "\"guild_logging_configs\".\"retention_days\""},
+	RetentionMaxLogs:             whereHelpernull_Int{field: "\"guild_logging_configs\".\"retention_max_logs\""},
 }
 
 // GuildLoggingConfigRels is where relationship names are stored.
@@ -203,8 +243,8 @@ func (*guildLoggingConfigR) NewStruct() *guildLoggingConfigR {
 type guildLoggingConfigL struct{}
 
 var (
-	guildLoggingConfigAllColumns            = []string{"guild_id", "created_at", "updated_at", "username_logging_enabled", "nickname_logging_enabled", "blacklisted_channels", "manage_messages_can_view_deleted", "everyone_can_view_deleted", "message_logs_allowed_roles"}
-	guildLoggingConfigColumnsWithoutDefault = []string{"guild_id", "created_at", "updated_at", "username_logging_enabled", "nickname_logging_enabled", "blacklisted_channels", "manage_messages_can_view_deleted", "everyone_can_view_deleted", "message_logs_allowed_roles"}
+	guildLoggingConfigAllColumns            = []string{"guild_id", "created_at", "updated_at", "username_logging_enabled", "nickname_logging_enabled", "blacklisted_channels", "manage_messages_can_view_deleted", "everyone_can_view_deleted", "message_logs_allowed_roles", "message_log_channel", "message_log_deleted", "message_log_edited", "message_log_ignored_channels", "message_log_ignored_roles", "archive_attachments", "retention_days", "retention_max_logs"}
+	guildLoggingConfigColumnsWithoutDefault = []string{"guild_id", "created_at", "updated_at", "username_logging_enabled", "nickname_logging_enabled", "blacklisted_channels", "manage_messages_can_view_deleted", "everyone_can_view_deleted", "message_logs_allowed_roles", "message_log_channel", "message_log_deleted", "message_log_edited", "message_log_ignored_channels", "message_log_ignored_roles", "archive_attachments", "retention_days", "retention_max_logs"}
 	guildLoggingConfigColumnsWithDefault    = []string{}
 	guildLoggingConfigPrimaryKeyColumns     = []string{"guild_id"}
 )
@@ -27,13 +27,14 @@ var _ bot.BotInitHandler = (*Plugin)(nil)
 var _ commands.CommandProvider = (*Plugin)(nil)
 
 func (p *Plugin) AddCommands() {
-	commands.AddRootCommands(p, cmdLogs, cmdWhois, cmdNicknames, cmdUsernames, cmdMigrate, cmdClearNames)
+	commands.AddRootCommands(p, cmdLogs, cmdSearchLogs, cmdWhois, cmdNicknames, cmdUsernames, cmdMigrate, cmdClearNames)
 }
 
 func (p *Plugin) BotInit() {
 	eventsystem.AddHandlerAsyncLastLegacy(p, bot.ConcurrentEventHandler(HandleQueueEvt), eventsystem.EventGuildMemberUpdate, eventsystem.EventGuildMemberAdd, eventsystem.EventMemberFetched)
 	// eventsystem.AddHandlerAsyncLastLegacy(bot.ConcurrentEventHandler(HandleGC), eventsystem.EventGuildCreate)
 	eventsystem.AddHandlerAsyncLast(p, HandleMsgDelete, eventsystem.EventMessageDelete, eventsystem.EventMessageDeleteBulk)
+	eventsystem.AddHandlerFirstLegacy(p, handleMessageLogEvent, eventsystem.EventMessageDelete, eventsystem.EventMessageDeleteBulk, eventsystem.EventMessageUpdate)
 
 	eventsystem.AddHandlerFirstLegacy(p, HandlePresenceUpdate, eventsystem.EventPresenceUpdate)
 
@@ -67,6 +68,149 @@ var cmdLogs = &commands.YAGCommand{
 	},
 }
 
+var cmdSearchLogs = &commands.YAGCommand{
+	Cooldown:            5,
+	CmdCategory:         commands.CategoryTool,
+	Name:                "SearchLogs",
+	Aliases:             []string{"logsearch", "sl"},
+	Description:         "Searches the messages stored in this server's message logs.",
+	LongDescription:     "Searches by content, use -regex to search with a regex instead (PostgreSQL syntax). Narrow down the search with -user, -channel, -after and -before (how long ago, e.g 2d).\nOnly messages in channels you can read are searched, deleted messages are only shown if the server allows viewing them.",
+	RequireDiscordPerms: []int64{discordgo.PermissionManageMessages},
+	Arguments: []*dcmd.ArgDef{
+		&dcmd.ArgDef{Name: "Query", Type: dcmd.String},
+	},
+	ArgSwitches: []*dcmd.ArgDef{
+		&dcmd.ArgDef{Switch: "regex", Name: "Search with a regex"},
+		&dcmd.ArgDef{Switch: "user", Name: "User", Type: dcmd.UserID},
+		&dcmd.ArgDef{Switch: "channel", Name: "Channel", Type: dcmd.Channel},
+		&dcmd.ArgDef{Switch: "after", Default: time.Duration(0), Name: "Sent less than this long ago", Type: &commands.DurationArg{}},
+		&dcmd.ArgDef{Switch: "before", Default: time.Duration(0), Name: "Sent more than this long ago", Type: &commands.DurationArg{}},
+		&dcmd.ArgDef{Switch: "deleted", Name: "Only deleted messages"},
+		&dcmd.ArgDef{Switch: "nodeleted", Name: "Exclude deleted messages"},
+	},
+	RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+		config, err := GetConfig(common.PQ, parsed.Context(), parsed.GS.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(config.MessageLogsAllowedRoles) > 0 && !common.ContainsInt64SliceOneOf(parsed.MS.Roles, config.MessageLogsAllowedRoles) {
+			return "This server has restricted log access to certain roles, you don't have any of them.", nil
+		}
+
+		q := &LogSearchQuery{}
+		if parsed.Switch("regex").Bool() {
+			q.Regex = parsed.Args[0].Str()
+		} else {
+			q.Content = parsed.Args[0].Str()
+		}
+
+		if parsed.Switches["user"].Value != nil {
+			q.AuthorID = parsed.Switches["user"].Int64()
+		}
+
+		q.ChannelIDs = readableChannels(parsed.GS, parsed.MS)
+		if parsed.Switches["channel"].Value != nil {
+			q.ChannelID = parsed.Switches["channel"].Value.(*dstate.ChannelState).ID
+			if !common.ContainsInt64Slice(q.ChannelIDs, q.ChannelID) {
+				return "You can't read messages in that channel.", nil
+			}
+		}
+
+		now := time.Now()
+		if after := parsed.Switches["after"].Value.(time.Duration); after > 0 {
+			q.After = now.Add(-after)
+		}
+		if before := parsed.Switches["before"].Value.(time.Duration); before > 0 {
+			q.Before = now.Add(-before)
+		}
+
+		// the command already requires manage messages
+		canViewDeleted := config.EveryoneCanViewDeleted.Bool || config.ManageMessagesCanViewDeleted.Bool
+		if !canViewDeleted || parsed.Switch("nodeleted").Bool() {
+			q.Deleted = SearchDeletedExclude
+		} else if parsed.Switch("deleted").Bool() {
+			q.Deleted = SearchDeletedOnly
+		}
+
+		if err := q.Validate(parsed.Context()); err != nil {
+			if _, ok := err.(SearchError); ok {
+				return err.Error(), nil
+			}
+			return nil, err
+		}
+
+		_, err = paginatedmessages.CreatePaginatedMessage(parsed.GS.ID, parsed.CS.ID, 1, 0, func(p *paginatedmessages.PaginatedMessage, page int) (*discordgo.MessageEmbed, error) {
+			results, err := SearchLogMessages(context.Background(), parsed.GS.ID, q, 10, (page-1)*10)
+			if err != nil {
+				return nil, err
+			}
+
+			if page > 1 && len(results) < 1 {
+				return nil, paginatedmessages.ErrNoResults
+			}
+
+			embed := &discordgo.MessageEmbed{
+				Color: 0x277ee3,
+				Title: "Message log search",
+			}
+
+			if len(results) < 1 {
+				embed.Description = "No matching messages found"
+				return embed, nil
+			}
+
+			for _, v := range results {
+				embed.Description += searchResultLine(parsed.GS.ID, v)
+			}
+
+			return embed, nil
+		})
+
+		return nil, err
+	},
+}
+
+// readableChannels returns the channels the member can read messages in,
+// the manage messages permission required by the search only applies to the current channel
+func readableChannels(gs *dstate.GuildState, ms *dstate.MemberState) []int64 {
+	gs.RLock()
+	defer gs.RUnlock()
+
+	result := make([]int64, 0, len(gs.Channels))
+	for id := range gs.Channels {
+		perms, err := gs.MemberPermissionsMS(false, id, ms)
+		if err != nil {
+			continue
+		}
+
+		if perms&discordgo.PermissionReadMessages != 0 {
+			result = append(result, id)
+		}
+	}
+
+	return result
+}
+
+func searchResultLine(guildID int64, r *LogSearchResult) string {
+	content := common.CutStringShort(r.Message.Content, 150)
+	if content == "" {
+		content = "*No text content*"
+	}
+
+	deleted := ""
+	if r.Message.Deleted {
+		deleted = " (deleted)"
+	}
+
+	out := fmt.Sprintf("**%s** (%d)%s - %s", r.Message.AuthorUsername, r.Message.AuthorID, deleted, r.Message.CreatedAt.UTC().Format(time.RFC822))
+	if r.LogID != 0 {
+		out += fmt.Sprintf(" in [#%s](%s)", r.ChannelName, CreateLink(guildID, r.LogID))
+	}
+
+	return out + "\n" + content + "\n\n"
+}
+
 var cmdWhois = &commands.YAGCommand{
 	CmdCategory: commands.CategoryTool,
 	Name:        "Whois",
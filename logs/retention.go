@@ -0,0 +1,361 @@
+package logs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/common/backgroundworkers"
+	"github.com/jonas747/yagpdb/logs/models"
+	"github.com/jonas747/yagpdb/premium"
+	"github.com/lib/pq"
+)
+
+// RetentionLimits are the max settings a guild can have, retention is opt in so guilds without settings keep their logs forever
+type RetentionLimits struct {
+	MaxDays int
+	MaxLogs int
+}
+
+var (
+	RetentionLimitsFree    = RetentionLimits{MaxDays: 90, MaxLogs: 1000}
+	RetentionLimitsPremium = RetentionLimits{MaxDays: 365, MaxLogs: 10000}
+)
+
+const (
+	retentionPruneInterval = time.Hour
+	// number of logs deleted per query
+	retentionBatchSize = 100
+
+	// how long the storage usage shown in the control panel is cached for, in seconds
+	storageUsageCacheExpiry = 60 * 60
+)
+
+// GetRetentionLimits returns the retention limits for the guild depending on if it's premium
+func GetRetentionLimits(guildID int64) (RetentionLimits, error) {
+	isPremium, err := premium.IsGuildPremium(guildID)
+	if err != nil {
+		return RetentionLimitsFree, err
+	}
+
+	if isPremium {
+		return RetentionLimitsPremium, nil
+	}
+
+	return RetentionLimitsFree, nil
+}
+
+// EffectiveRetention returns the max age and number of logs to keep for the guild, 0 meaning there's no limit.
+// Only the settings the guild has set itself are used, capped to the limits
+func EffectiveRetention(config *models.GuildLoggingConfig, limits RetentionLimits) (maxAge time.Duration, maxLogs int) {
+	days := config.RetentionDays.Int
+	if days > limits.MaxDays {
+		days = limits.MaxDays
+	}
+	if days < 0 {
+		days = 0
+	}
+
+	maxLogs = config.RetentionMaxLogs.Int
+	if maxLogs > limits.MaxLogs {
+		maxLogs = limits.MaxLogs
+	}
+	if maxLogs < 0 {
+		maxLogs = 0
+	}
+
+	return time.Duration(days) * time.Hour * 24, maxLogs
+}
+
+var _ backgroundworkers.BackgroundWorkerPlugin = (*Plugin)(nil)
+
+func (p *Plugin) RunBackgroundWorker() {
+	ticker := time.NewTicker(retentionPruneInterval)
+	for {
+		started := time.Now()
+		n, stopWG, err := pruneAllGuildLogs(p.stopWorker)
+		if err != nil {
+			logger.WithError(err).Error("failed pruning message logs")
+		} else {
+			logger.Infof("pruned %d message logs in %s", n, time.Since(started))
+		}
+
+		if stopWG != nil {
+			ticker.Stop()
+			stopWG.Done()
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case wg := <-p.stopWorker:
+			ticker.Stop()
+			wg.Done()
+			return
+		}
+	}
+}
+
+func (p *Plugin) StopBackgroundWorker(wg *sync.WaitGroup) {
+	p.stopWorker <- wg
+}
+
+// pruneAllGuildLogs goes through all the guilds with retention settings and prunes the logs past them,
+// if the worker is stopped while it's running it returns early with the stop waitgroup
+func pruneAllGuildLogs(stop chan *sync.WaitGroup) (int, *sync.WaitGroup, error) {
+	lastGuildID := int64(-1)
+	total := 0
+
+	for {
+		rows, err := common.PQ.Query(`SELECT guild_id FROM guild_logging_configs
+WHERE guild_id > $1 AND (retention_days > 0 OR retention_max_logs > 0) ORDER BY guild_id LIMIT 500`, lastGuildID)
+		if err != nil {
+			return total, nil, errors.WithStackIf(err)
+		}
+
+		guilds := make([]int64, 0, 500)
+		for rows.Next() {
+			var guildID int64
+			if err := rows.Scan(&guildID); err != nil {
+				rows.Close()
+				return total, nil, errors.WithStackIf(err)
+			}
+			guilds = append(guilds, guildID)
+		}
+		rows.Close()
+
+		if len(guilds) < 1 {
+			return total, nil, nil
+		}
+
+		for _, g := range guilds {
+			n, err := PruneGuildLogs(context.Background(), g)
+			if err != nil {
+				logger.WithError(err).WithField("guild", g).Error("failed pruning message logs")
+			}
+			total += n
+
+			// don't hold up shutdown until we've gone through every guild
+			select {
+			case wg := <-stop:
+				return total, wg, nil
+			default:
+			}
+		}
+
+		lastGuildID = guilds[len(guilds)-1]
+	}
+}
+
+// PruneGuildLogs deletes the logs of the guild that are older than the retention period or past the max number of logs,
+// along with their messages and archived attachments that are not in any other log
+func PruneGuildLogs(ctx context.Context, guildID int64) (int, error) {
+	config, err := GetConfig(common.PQ, ctx, guildID)
+	if err != nil {
+		return 0, err
+	}
+
+	// don't risk pruning a premium guild with the free limits, it will be tried again on the next run
+	limits, err := GetRetentionLimits(guildID)
+	if err != nil {
+		return 0, errors.WrapIf(err, "premium status")
+	}
+
+	maxAge, maxLogs := EffectiveRetention(config, limits)
+	if maxAge <= 0 && maxLogs <= 0 {
+		return 0, nil
+	}
+
+	// the zero time matches nothing, when there's no max age
+	var olderThan time.Time
+	if maxAge > 0 {
+		olderThan = time.Now().Add(-maxAge)
+	}
+
+	// logs with an id at or below this are past the max number of logs
+	var maxLogID int
+	if maxLogs > 0 {
+		err = common.PQ.QueryRowContext(ctx, "SELECT id FROM message_logs2 WHERE guild_id = $1 ORDER BY id DESC OFFSET $2 LIMIT 1", guildID, maxLogs).Scan(&maxLogID)
+		if err != nil && err != sql.ErrNoRows {
+			return 0, errors.WithStackIf(err)
+		}
+	}
+
+	total := 0
+	for {
+		messageIDs, n, err := deleteLogsBatch(ctx, guildID, olderThan, maxLogID)
+		if err != nil {
+			return total, err
+		}
+		total += n
+
+		if len(messageIDs) > 0 {
+			err = pruneUnusedMessages(ctx, guildID, messageIDs)
+			if err != nil {
+				return total, err
+			}
+		}
+
+		if n < retentionBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// deleteLogsBatch deletes up to retentionBatchSize logs and returns the messages they contained
+func deleteLogsBatch(ctx context.Context, guildID int64, olderThan time.Time, maxLogID int) ([]int64, int, error) {
+	const q = `DELETE FROM message_logs2 WHERE guild_id = $1 AND id IN (
+	SELECT id FROM message_logs2 WHERE guild_id = $1 AND (created_at < $2 OR id <= $3) ORDER BY id LIMIT $4
+) RETURNING messages`
+
+	rows, err := common.PQ.QueryContext(ctx, q, guildID, olderThan, maxLogID, retentionBatchSize)
+	if err != nil {
+		return nil, 0, errors.WithStackIf(err)
+	}
+	defer rows.Close()
+
+	n := 0
+	var messageIDs []int64
+	for rows.Next() {
+		var messages pq.Int64Array
+		if err := rows.Scan(&messages); err != nil {
+			return nil, n, errors.WithStackIf(err)
+		}
+
+		messageIDs = append(messageIDs, messages...)
+		n++
+	}
+
+	return messageIDs, n, errors.WithStackIf(rows.Err())
+}
+
+// pruneUnusedMessages deletes the messages that are no longer in any log, messages can be shared between overlapping logs
+func pruneUnusedMessages(ctx context.Context, guildID int64, messageIDs []int64) error {
+	const q = `DELETE FROM messages2 WHERE guild_id = $1 AND id = ANY($2) AND NOT EXISTS (
+	SELECT 1 FROM message_logs2 WHERE message_logs2.guild_id = messages2.guild_id AND message_logs2.messages @> ARRAY[messages2.id]
+) RETURNING id`
+
+	rows, err := common.PQ.QueryContext(ctx, q, guildID, pq.Int64Array(messageIDs))
+	if err != nil {
+		return errors.WithStackIf(err)
+	}
+
+	var deleted []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return errors.WithStackIf(err)
+		}
+		deleted = append(deleted, id)
+	}
+	rows.Close()
+
+	if len(deleted) < 1 {
+		return nil
+	}
+
+	return deleteArchivedAttachments(ctx, guildID, deleted)
+}
+
+// deleteArchivedAttachments removes the archived attachments of the messages from storage and the database
+func deleteArchivedAttachments(ctx context.Context, guildID int64, messageIDs []int64) error {
+	attachments, err := GetArchivedAttachments(ctx, guildID, messageIDs)
+	if err != nil {
+		return errors.WithStackIf(err)
+	}
+
+	if len(attachments) < 1 {
+		return nil
+	}
+
+	if AttachmentStorage != nil {
+		for _, msgAttachments := range attachments {
+			for _, a := range msgAttachments {
+				err := AttachmentStorage.Delete(ctx, a.StorageKey)
+				if err != nil {
+					logger.WithError(err).WithField("guild", guildID).Error("failed deleting archived attachment")
+				}
+			}
+		}
+	}
+
+	_, err = common.PQ.ExecContext(ctx, "DELETE FROM message_log_attachments WHERE guild_id = $1 AND message_id = ANY($2)", guildID, pq.Int64Array(messageIDs))
+	return errors.WithStackIf(err)
+}
+
+// StorageUsage is how much a guild is storing in message logs
+type StorageUsage struct {
+	Logs     int
+	Messages int
+	// approximate size of the message contents
+	MessagesSize int64
+
+	Attachments     int
+	AttachmentsSize int64
+}
+
+func cacheKeyStorageUsage(guildID int64) string {
+	return "logs_storage_usage:" + strconv.FormatInt(guildID, 10)
+}
+
+// GetStorageUsage returns how much the guild is storing in message logs,
+// this goes through all the guild's messages so it's cached for a while
+func GetStorageUsage(ctx context.Context, guildID int64) (*StorageUsage, error) {
+	var cached StorageUsage
+	if err := common.GetCacheDataJson(cacheKeyStorageUsage(guildID), &cached); err == nil {
+		return &cached, nil
+	}
+
+	usage, err := calculateStorageUsage(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = common.SetCacheDataJson(cacheKeyStorageUsage(guildID), storageUsageCacheExpiry, usage)
+	common.LogIgnoreError(err, "[logs] failed caching storage usage", nil)
+
+	return usage, nil
+}
+
+func calculateStorageUsage(ctx context.Context, guildID int64) (*StorageUsage, error) {
+	usage := &StorageUsage{}
+
+	err := common.PQ.QueryRowContext(ctx, "SELECT count(*) FROM message_logs2 WHERE guild_id = $1", guildID).Scan(&usage.Logs)
+	if err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+
+	err = common.PQ.QueryRowContext(ctx, "SELECT count(*), COALESCE(sum(pg_column_size(content)), 0) FROM messages2 WHERE guild_id = $1", guildID).Scan(&usage.Messages, &usage.MessagesSize)
+	if err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+
+	err = common.PQ.QueryRowContext(ctx, "SELECT count(*), COALESCE(sum(size), 0) FROM message_log_attachments WHERE guild_id = $1", guildID).Scan(&usage.Attachments, &usage.AttachmentsSize)
+	if err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+
+	return usage, nil
+}
+
+// FormatBytes formats the number of bytes in a human readable form
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
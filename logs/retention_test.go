@@ -0,0 +1,68 @@
+package logs
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jonas747/yagpdb/logs/models"
+	"github.com/volatiletech/null"
+)
+
+func TestEffectiveRetention(t *testing.T) {
+	cases := []struct {
+		Days, MaxLogs   null.Int
+		Limits          RetentionLimits
+		ExpectedDays    int
+		ExpectedMaxLogs int
+	}{
+		{ // 0, not set keeps everything
+			Limits: RetentionLimitsFree,
+		},
+		{ // 1, set to 0 keeps everything
+			Days: null.IntFrom(0), MaxLogs: null.IntFrom(0),
+			Limits: RetentionLimitsFree,
+		},
+		{ // 2, within the limits
+			Days: null.IntFrom(30), MaxLogs: null.IntFrom(500),
+			Limits:       RetentionLimitsFree,
+			ExpectedDays: 30, ExpectedMaxLogs: 500,
+		},
+		{ // 3, capped to the free limits
+			Days: null.IntFrom(365), MaxLogs: null.IntFrom(10000),
+			Limits:       RetentionLimitsFree,
+			ExpectedDays: 90, ExpectedMaxLogs: 1000,
+		},
+		{ // 4, premium limits
+			Days: null.IntFrom(365), MaxLogs: null.IntFrom(10000),
+			Limits:       RetentionLimitsPremium,
+			ExpectedDays: 365, ExpectedMaxLogs: 10000,
+		},
+		{ // 5, only one of them set
+			Days:         null.IntFrom(7),
+			Limits:       RetentionLimitsFree,
+			ExpectedDays: 7,
+		},
+		{ // 6, negative
+			Days: null.IntFrom(-1), MaxLogs: null.IntFrom(-5),
+			Limits: RetentionLimitsFree,
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(fmt.Sprintf("Case %d", k), func(t *testing.T) {
+			config := &models.GuildLoggingConfig{
+				RetentionDays:    v.Days,
+				RetentionMaxLogs: v.MaxLogs,
+			}
+
+			maxAge, maxLogs := EffectiveRetention(config, v.Limits)
+			if maxAge != time.Duration(v.ExpectedDays)*time.Hour*24 {
+				t.Errorf("Mismatched max age, GOT %s EXPECTED %d days", maxAge, v.ExpectedDays)
+			}
+			if maxLogs != v.ExpectedMaxLogs {
+				t.Errorf("Mismatched max logs, GOT %d EXPECTED %d", maxLogs, v.ExpectedMaxLogs)
+			}
+		})
+	}
+}
@@ -52,6 +52,31 @@ CREATE TABLE IF NOT EXISTS guild_logging_configs (
 
 	`ALTER TABLE guild_logging_configs ADD COLUMN IF NOT EXISTS message_logs_allowed_roles BIGINT[];`,
 
+	`ALTER TABLE guild_logging_configs ADD COLUMN IF NOT EXISTS message_log_channel BIGINT;`,
+	`ALTER TABLE guild_logging_configs ADD COLUMN IF NOT EXISTS message_log_deleted BOOLEAN;`,
+	`ALTER TABLE guild_logging_configs ADD COLUMN IF NOT EXISTS message_log_edited BOOLEAN;`,
+	`ALTER TABLE guild_logging_configs ADD COLUMN IF NOT EXISTS message_log_ignored_channels BIGINT[];`,
+	`ALTER TABLE guild_logging_configs ADD COLUMN IF NOT EXISTS message_log_ignored_roles BIGINT[];`,
+	`ALTER TABLE guild_logging_configs ADD COLUMN IF NOT EXISTS archive_attachments BOOLEAN;`,
+	`ALTER TABLE guild_logging_configs ADD COLUMN IF NOT EXISTS retention_days INT;`,
+	`ALTER TABLE guild_logging_configs ADD COLUMN IF NOT EXISTS retention_max_logs INT;`,
+
+	`CREATE TABLE IF NOT EXISTS message_log_attachments (
+	guild_id BIGINT NOT NULL,
+	message_id BIGINT NOT NULL,
+	attachment_id BIGINT NOT NULL,
+
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+
+	filename TEXT NOT NULL,
+	size INT NOT NULL,
+	storage_key TEXT NOT NULL,
+
+	PRIMARY KEY(message_id, attachment_id)
+);`,
+
+	`CREATE INDEX IF NOT EXISTS message_log_attachments_guild_id_idx ON message_log_attachments(guild_id);`,
+
 	`CREATE TABLE IF NOT EXISTS username_listings (
 	id SERIAL PRIMARY KEY,
 
@@ -0,0 +1,237 @@
+package logs
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/logs/models"
+	"github.com/lib/pq"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+	"github.com/volatiletech/sqlboiler/types"
+)
+
+const (
+	SearchDeletedAny     = ""
+	SearchDeletedOnly    = "only"
+	SearchDeletedExclude = "exclude"
+)
+
+const (
+	// max length of the search regex
+	maxSearchRegexLength = 200
+
+	// how long postgres is allowed to spend on a search, user provided regexes can be slow
+	searchStatementTimeout = time.Second * 10
+)
+
+// SearchError is a problem with the search that should be shown to the user
+type SearchError string
+
+func (s SearchError) Error() string {
+	return string(s)
+}
+
+const (
+	ErrEmptySearch   = SearchError("Specify at least one of content, regex, author or channel to search by")
+	ErrSearchTimeout = SearchError("The search took too long, try narrowing it down")
+)
+
+// LogSearchQuery filters the messages stored in message logs, empty fields are ignored
+type LogSearchQuery struct {
+	// Case insensitive substring of the message content
+	Content string
+	// Case insensitive regex matched against the message content
+	Regex string
+
+	AuthorID  int64
+	ChannelID int64
+
+	// Only search messages in these channels if not nil, used to hide channels the searcher can't read
+	ChannelIDs []int64
+
+	After  time.Time
+	Before time.Time
+
+	Deleted string
+}
+
+// Validate checks that the query has something to search by and that the regex is valid,
+// problems with the query are returned as a SearchError
+func (q *LogSearchQuery) Validate(ctx context.Context) error {
+	if q.Content == "" && q.Regex == "" && q.AuthorID == 0 && q.ChannelID == 0 {
+		return ErrEmptySearch
+	}
+
+	if q.Regex == "" {
+		return nil
+	}
+
+	if len(q.Regex) > maxSearchRegexLength {
+		return SearchError("The regex is too long")
+	}
+
+	// the regex is run by postgres, which has a different syntax than go's regexp package, so let postgres check it
+	var matched bool
+	err := common.PQ.QueryRowContext(ctx, "SELECT '' ~* $1", q.Regex).Scan(&matched)
+	if err != nil {
+		if cast, ok := errors.Cause(err).(*pq.Error); ok && cast.Code == "2201B" {
+			return SearchError("Invalid regex: " + cast.Message)
+		}
+
+		return errors.WithStackIf(err)
+	}
+
+	return nil
+}
+
+// LogSearchResult is a message matching the search along with the newest log it's in
+type LogSearchResult struct {
+	Message *models.Messages2
+
+	LogID       int
+	ChannelID   int64
+	ChannelName string
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (q *LogSearchQuery) queryMods(guildID int64) []qm.QueryMod {
+	mods := []qm.QueryMod{
+		models.Messages2Where.GuildID.EQ(guildID),
+	}
+
+	if q.Content != "" {
+		mods = append(mods, qm.Where("content ILIKE ?", "%"+likeEscaper.Replace(q.Content)+"%"))
+	}
+
+	if q.Regex != "" {
+		mods = append(mods, qm.Where("content ~* ?", q.Regex))
+	}
+
+	if q.AuthorID != 0 {
+		mods = append(mods, models.Messages2Where.AuthorID.EQ(q.AuthorID))
+	}
+
+	if q.ChannelID != 0 {
+		// messages don't store the channel, only the logs they're in do
+		mods = append(mods, qm.Where(`EXISTS (SELECT 1 FROM message_logs2 WHERE message_logs2.guild_id = messages2.guild_id
+			AND message_logs2.channel_id = ? AND message_logs2.messages @> ARRAY[messages2.id])`, q.ChannelID))
+	}
+
+	if q.ChannelIDs != nil {
+		mods = append(mods, qm.Where(`EXISTS (SELECT 1 FROM message_logs2 WHERE message_logs2.guild_id = messages2.guild_id
+			AND message_logs2.channel_id = ANY(?) AND message_logs2.messages @> ARRAY[messages2.id])`, types.Int64Array(q.ChannelIDs)))
+	}
+
+	if !q.After.IsZero() {
+		mods = append(mods, models.Messages2Where.CreatedAt.GT(q.After))
+	}
+
+	if !q.Before.IsZero() {
+		mods = append(mods, models.Messages2Where.CreatedAt.LT(q.Before))
+	}
+
+	switch q.Deleted {
+	case SearchDeletedOnly:
+		mods = append(mods, models.Messages2Where.Deleted.EQ(true))
+	case SearchDeletedExclude:
+		mods = append(mods, models.Messages2Where.Deleted.EQ(false))
+	}
+
+	return mods
+}
+
+// SearchLogMessages searches the messages in all the message logs of the guild, newest first
+func SearchLogMessages(ctx context.Context, guildID int64, q *LogSearchQuery, limit, offset int) ([]*LogSearchResult, error) {
+	err := q.Validate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, searchStatementTimeout+time.Second*5)
+	defer cancel()
+
+	messages, err := searchMessages(ctx, q.queryMods(guildID), limit, offset)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, ErrSearchTimeout
+		}
+
+		// query_canceled, from the statement timeout
+		if cast, ok := errors.Cause(err).(*pq.Error); ok && cast.Code == "57014" {
+			return nil, ErrSearchTimeout
+		}
+
+		return nil, errors.WrapIf(err, "messages2")
+	}
+
+	if len(messages) < 1 {
+		return nil, nil
+	}
+
+	ids := make([]int64, len(messages))
+	for i, v := range messages {
+		ids[i] = v.ID
+	}
+
+	msgLogs, err := models.MessageLogs2s(
+		models.MessageLogs2Where.GuildID.EQ(guildID),
+		qm.Where("messages && ?", types.Int64Array(ids)),
+		qm.OrderBy("id desc")).AllG(ctx)
+	if err != nil {
+		return nil, errors.WrapIf(err, "message_logs2")
+	}
+
+	results := make([]*LogSearchResult, len(messages))
+	for i, m := range messages {
+		results[i] = &LogSearchResult{Message: m}
+
+		// logs are newest first, so the first one we find is the newest
+		for _, l := range msgLogs {
+			if !containsMessage(l.Messages, m.ID) {
+				continue
+			}
+
+			results[i].LogID = l.ID
+			results[i].ChannelID = l.ChannelID
+			results[i].ChannelName = l.ChannelName
+			break
+		}
+	}
+
+	return results, nil
+}
+
+// searchMessages runs the message search in a read only transaction with a statement timeout,
+// so slow regexes are stopped by postgres and not just abandoned by us
+func searchMessages(ctx context.Context, mods []qm.QueryMod, limit, offset int) (models.Messages2Slice, error) {
+	tx, err := common.PQ.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// SET doesn't take parameters, set_config with is_local is the same as SET LOCAL
+	_, err = tx.ExecContext(ctx, "SELECT set_config('statement_timeout', $1, true)", strconv.FormatInt(int64(searchStatementTimeout/time.Millisecond), 10))
+	if err != nil {
+		return nil, err
+	}
+
+	mods = append(mods, qm.OrderBy("id desc"), qm.Limit(limit), qm.Offset(offset))
+	return models.Messages2s(mods...).All(ctx, tx)
+}
+
+func containsMessage(messages types.Int64Array, id int64) bool {
+	for _, v := range messages {
+		if v == id {
+			return true
+		}
+	}
+
+	return false
+}
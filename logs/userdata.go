@@ -0,0 +1,112 @@
+package logs
+
+import (
+	"context"
+
+	"emperror.dev/errors"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/logs/models"
+	"github.com/volatiletech/null"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+var _ common.PluginWithUserData = (*Plugin)(nil)
+
+type userDataExport struct {
+	Usernames       models.UsernameListingSlice `json:"usernames"`
+	Nicknames       models.NicknameListingSlice `json:"nicknames"`
+	LoggedMessages  models.Messages2Slice       `json:"logged_messages"`
+	CreatedLogs     models.MessageLogs2Slice    `json:"created_message_logs"`
+	ArchivedUploads []*ArchivedAttachment       `json:"archived_attachments"`
+}
+
+func (p *Plugin) ExportUserData(ctx context.Context, userID int64) (interface{}, error) {
+	export := &userDataExport{}
+
+	var err error
+	export.Usernames, err = models.UsernameListings(models.UsernameListingWhere.UserID.EQ(null.Int64From(userID)), qm.OrderBy("id asc")).AllG(ctx)
+	if err != nil {
+		return nil, errors.WrapIf(err, "usernames")
+	}
+
+	export.Nicknames, err = models.NicknameListings(models.NicknameListingWhere.UserID.EQ(null.Int64From(userID)), qm.OrderBy("id asc")).AllG(ctx)
+	if err != nil {
+		return nil, errors.WrapIf(err, "nicknames")
+	}
+
+	export.LoggedMessages, err = models.Messages2s(models.Messages2Where.AuthorID.EQ(userID), qm.OrderBy("id asc")).AllG(ctx)
+	if err != nil {
+		return nil, errors.WrapIf(err, "messages2")
+	}
+
+	export.CreatedLogs, err = models.MessageLogs2s(models.MessageLogs2Where.AuthorID.EQ(userID), qm.OrderBy("guild_id, id")).AllG(ctx)
+	if err != nil {
+		return nil, errors.WrapIf(err, "message_logs2")
+	}
+
+	export.ArchivedUploads, err = userArchivedAttachments(ctx, export.LoggedMessages)
+	if err != nil {
+		return nil, errors.WrapIf(err, "attachments")
+	}
+
+	return export, nil
+}
+
+func userArchivedAttachments(ctx context.Context, messages models.Messages2Slice) ([]*ArchivedAttachment, error) {
+	byGuild := make(map[int64][]int64)
+	for _, v := range messages {
+		byGuild[v.GuildID] = append(byGuild[v.GuildID], v.ID)
+	}
+
+	var result []*ArchivedAttachment
+	for guildID, ids := range byGuild {
+		attachments, err := GetArchivedAttachments(ctx, guildID, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, v := range attachments {
+			result = append(result, v...)
+		}
+	}
+
+	return result, nil
+}
+
+// EraseUserData deletes the users past names and logged messages, logs they created are kept but with the author anonymized
+func (p *Plugin) EraseUserData(ctx context.Context, userID int64) error {
+	_, err := models.UsernameListings(models.UsernameListingWhere.UserID.EQ(null.Int64From(userID))).DeleteAll(ctx, common.PQ)
+	if err != nil {
+		return errors.WrapIf(err, "usernames")
+	}
+
+	_, err = models.NicknameListings(models.NicknameListingWhere.UserID.EQ(null.Int64From(userID))).DeleteAll(ctx, common.PQ)
+	if err != nil {
+		return errors.WrapIf(err, "nicknames")
+	}
+
+	messages, err := models.Messages2s(models.Messages2Where.AuthorID.EQ(userID), qm.Select("id", "guild_id")).AllG(ctx)
+	if err != nil {
+		return errors.WrapIf(err, "messages2")
+	}
+
+	byGuild := make(map[int64][]int64)
+	for _, v := range messages {
+		byGuild[v.GuildID] = append(byGuild[v.GuildID], v.ID)
+	}
+
+	for guildID, ids := range byGuild {
+		err = deleteArchivedAttachments(ctx, guildID, ids)
+		if err != nil {
+			return errors.WrapIf(err, "attachments")
+		}
+	}
+
+	_, err = models.Messages2s(models.Messages2Where.AuthorID.EQ(userID)).DeleteAll(ctx, common.PQ)
+	if err != nil {
+		return errors.WrapIf(err, "messages2")
+	}
+
+	_, err = models.MessageLogs2s(models.MessageLogs2Where.AuthorID.EQ(userID)).UpdateAllG(ctx, models.M{"author_id": 0, "author_username": "Deleted user"})
+	return errors.WrapIf(err, "message_logs2")
+}
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"html/template"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jonas747/discordgo"
 	"github.com/jonas747/yagpdb/bot"
@@ -43,6 +45,17 @@ type ConfigFormData struct {
 	EveryoneCanViewDeleted       bool
 	BlacklistedChannels          []string
 	MessageLogsAllowedRoles      []int64
+
+	MessageLogChannel         int64 `valid:"channel,true"`
+	MessageLogDeleted         bool
+	MessageLogEdited          bool
+	MessageLogIgnoredChannels []int64 `valid:"channel,true"`
+	MessageLogIgnoredRoles    []int64 `valid:"role,true"`
+
+	ArchiveAttachments bool
+
+	RetentionDays    int `valid:"0,365"`
+	RetentionMaxLogs int `valid:"0,10000"`
 }
 
 var (
@@ -54,6 +67,8 @@ var (
 func (lp *Plugin) InitWeb() {
 	web.LoadHTMLTemplate("../../logs/assets/logs_control_panel.html", "templates/plugins/logs_control_panel.html")
 	web.LoadHTMLTemplate("../../logs/assets/logs_view.html", "templates/plugins/logs_view.html")
+	web.LoadHTMLTemplate("../../logs/assets/logs_search.html", "templates/plugins/logs_search.html")
+	web.LoadHTMLTemplate("../../logs/assets/logs_export.html", "templates/plugins/logs_export.html")
 
 	web.AddSidebarItem(web.SidebarCategoryTools, &web.SidebarItem{
 		Name: "Logging",
@@ -67,6 +82,10 @@ func (lp *Plugin) InitWeb() {
 	web.ServerPublicMux.Handle(pat.Get("/log/:id"), web.RenderHandler(LogFetchMW(HandleLogsHTML, false), "public_server_logs"))
 	web.ServerPublicMux.Handle(pat.Get("/log/:id/"), web.RenderHandler(LogFetchMW(HandleLogsHTML, false), "public_server_logs"))
 
+	web.ServerPublicMux.Handle(pat.Get("/log/:id/export/html"), web.RenderHandler(LogFetchMW(HandleLogsExportHTML, false), "logs_export"))
+	web.ServerPublicMux.Handle(pat.Get("/log/:id/export/json"), web.APIHandler(logFetchErrToJSON(LogFetchMW(HandleLogsExportJSON, false))))
+	web.ServerPublicMux.Handle(pat.Get("/logs/attachments/:message_id/:attachment_id"), http.HandlerFunc(HandleArchivedAttachment))
+
 	logCPMux := goji.SubMux()
 	web.CPMux.Handle(pat.New("/logging"), logCPMux)
 	web.CPMux.Handle(pat.New("/logging/*"), logCPMux)
@@ -82,6 +101,8 @@ func (lp *Plugin) InitWeb() {
 	logCPMux.Handle(pat.Post("/"), saveHandler)
 	logCPMux.Handle(pat.Post(""), saveHandler)
 
+	logCPMux.Handle(pat.Get("/search"), web.ControllerHandler(HandleLogsCPSearch, "cp_logging_search"))
+
 	logCPMux.Handle(pat.Post("/fulldelete2"), fullDeleteHandler)
 	logCPMux.Handle(pat.Post("/msgdelete2"), msgDeleteHandler)
 }
@@ -128,6 +149,22 @@ func HandleLogsCP(w http.ResponseWriter, r *http.Request) (web.TemplateData, err
 		return nil, err
 	}
 	tmpl["Config"] = general
+	tmpl["AttachmentArchivalAvailable"] = AttachmentStorage != nil
+
+	limits, err := GetRetentionLimits(g.ID)
+	web.CheckErr(tmpl, err, "Failed checking premium status", web.CtxLogger(ctx).Error)
+	maxAge, maxLogs := EffectiveRetention(general, limits)
+	tmpl["RetentionLimits"] = limits
+	tmpl["EffectiveRetentionDays"] = int(maxAge.Hours() / 24)
+	tmpl["EffectiveRetentionMaxLogs"] = maxLogs
+
+	usage, err := GetStorageUsage(ctx, g.ID)
+	web.CheckErr(tmpl, err, "Failed retrieving storage usage", web.CtxLogger(ctx).Error)
+	if err == nil {
+		tmpl["StorageUsage"] = usage
+		tmpl["StorageUsageMessagesSize"] = FormatBytes(usage.MessagesSize)
+		tmpl["StorageUsageAttachmentsSize"] = FormatBytes(usage.AttachmentsSize)
+	}
 
 	// dealing with legacy code is a pain, gah
 	// so way back i didn't know about arrays in postgres, so i made the blacklisted channels field a single TEXT field, with a comma seperator
@@ -144,6 +181,106 @@ func HandleLogsCP(w http.ResponseWriter, r *http.Request) (web.TemplateData, err
 	return tmpl, nil
 }
 
+const searchResultsPerPage = 25
+
+func HandleLogsCPSearch(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
+	ctx := r.Context()
+	g, tmpl := web.GetBaseCPContextData(ctx)
+
+	config, err := GetConfig(common.PQ, ctx, g.ID)
+	if err != nil {
+		return tmpl, err
+	}
+
+	canViewDeleted := CanViewDeleted(r, config)
+	tmpl["CanViewDeleted"] = canViewDeleted
+
+	values := r.URL.Query()
+	tmpl["SearchQuery"] = values
+
+	// the form hasn't been submitted yet
+	if len(values) < 1 {
+		return tmpl, nil
+	}
+
+	q := &LogSearchQuery{
+		Content: values.Get("content"),
+		Deleted: values.Get("deleted"),
+	}
+
+	if values.Get("regex") != "" {
+		q.Regex = q.Content
+		q.Content = ""
+	}
+
+	if !canViewDeleted {
+		q.Deleted = SearchDeletedExclude
+	}
+
+	q.AuthorID, _ = strconv.ParseInt(values.Get("author"), 10, 64)
+	q.ChannelID, _ = strconv.ParseInt(values.Get("channel"), 10, 64)
+
+	// from <input type="date">
+	const dateFormat = "2006-01-02"
+	if after := values.Get("after"); after != "" {
+		q.After, err = time.Parse(dateFormat, after)
+		if err != nil {
+			return tmpl.AddAlerts(web.ErrorAlert("Invalid after date")), nil
+		}
+	}
+	if before := values.Get("before"); before != "" {
+		q.Before, err = time.Parse(dateFormat, before)
+		if err != nil {
+			return tmpl.AddAlerts(web.ErrorAlert("Invalid before date")), nil
+		}
+
+		// include the whole day
+		q.Before = q.Before.Add(time.Hour * 24)
+	}
+
+	if err := q.Validate(ctx); err != nil {
+		if _, ok := err.(SearchError); ok {
+			return tmpl.AddAlerts(web.ErrorAlert(err.Error())), nil
+		}
+		return tmpl, err
+	}
+
+	page, _ := strconv.Atoi(values.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	// fetch one more than we show to know if there's a next page
+	results, err := SearchLogMessages(ctx, g.ID, q, searchResultsPerPage+1, (page-1)*searchResultsPerPage)
+	if err != nil {
+		if _, ok := err.(SearchError); ok {
+			return tmpl.AddAlerts(web.ErrorAlert(err.Error())), nil
+		}
+		return tmpl, err
+	}
+
+	if len(results) > searchResultsPerPage {
+		results = results[:searchResultsPerPage]
+		tmpl["NextPage"] = pageURL(values, page+1)
+	}
+	if page > 1 {
+		tmpl["PrevPage"] = pageURL(values, page-1)
+	}
+
+	tmpl["Results"] = results
+	tmpl["Searched"] = true
+	return tmpl, nil
+}
+
+func pageURL(values url.Values, page int) string {
+	cop := url.Values{}
+	for k, v := range values {
+		cop[k] = v
+	}
+	cop.Set("page", strconv.Itoa(page))
+	return "?" + cop.Encode()
+}
+
 func HandleLogsCPSaveGeneral(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
 	ctx := r.Context()
 	g, tmpl := web.GetBaseCPContextData(ctx)
@@ -159,6 +296,17 @@ func HandleLogsCPSaveGeneral(w http.ResponseWriter, r *http.Request) (web.Templa
 		EveryoneCanViewDeleted:       null.BoolFrom(form.EveryoneCanViewDeleted),
 		ManageMessagesCanViewDeleted: null.BoolFrom(form.ManageMessagesCanViewDeleted),
 		MessageLogsAllowedRoles:      form.MessageLogsAllowedRoles,
+
+		MessageLogChannel:         null.Int64From(form.MessageLogChannel),
+		MessageLogDeleted:         null.BoolFrom(form.MessageLogDeleted),
+		MessageLogEdited:          null.BoolFrom(form.MessageLogEdited),
+		MessageLogIgnoredChannels: form.MessageLogIgnoredChannels,
+		MessageLogIgnoredRoles:    form.MessageLogIgnoredRoles,
+
+		ArchiveAttachments: null.BoolFrom(form.ArchiveAttachments),
+
+		RetentionDays:    null.IntFrom(form.RetentionDays),
+		RetentionMaxLogs: null.IntFrom(form.RetentionMaxLogs),
 	}
 
 	err := config.UpsertG(ctx, true, []string{"guild_id"}, boil.Infer(), boil.Infer())
@@ -271,11 +419,27 @@ func LogFetchMW(inner web.CustomHandlerFunc, legacy bool) web.CustomHandlerFunc
 	}
 }
 
+// CanViewDeleted returns true if the user making the request is allowed to view deleted messages
+func CanViewDeleted(r *http.Request, config *models.GuildLoggingConfig) bool {
+	isAdmin, _ := web.IsAdminRequest(r.Context(), r)
+
+	if isAdmin && !web.GetIsReadOnly(r.Context()) {
+		return true
+	} else if config.EveryoneCanViewDeleted.Bool {
+		return true
+	} else if config.ManageMessagesCanViewDeleted.Bool {
+		return web.HasPermissionCTX(r.Context(), discordgo.PermissionManageMessages)
+	}
+
+	return false
+}
+
 type MessageView struct {
 	Model *models.Messages2
 
-	Color     string
-	Timestamp string
+	Color       string
+	Timestamp   string
+	Attachments []*ArchivedAttachment
 }
 
 func HandleLogsHTML(w http.ResponseWriter, r *http.Request) interface{} {
@@ -285,38 +449,40 @@ func HandleLogsHTML(w http.ResponseWriter, r *http.Request) interface{} {
 	messages := r.Context().Value(ctxKeyMessages).([]*models.Messages2)
 	config := r.Context().Value(ctxKeyConfig).(*models.GuildLoggingConfig)
 
-	// check if were allowed to view deleted messages
-	isAdmin, _ := web.IsAdminRequest(r.Context(), r)
+	tmpl["CanViewDeleted"] = CanViewDeleted(r, config)
+	tmpl["Logs"] = logs
+	tmpl["Messages"] = CreateMessageViews(r.Context(), g.ID, messages)
 
-	var canViewDeleted = false
-	if isAdmin && !web.GetIsReadOnly(r.Context()) {
-		canViewDeleted = true
-	} else if config.EveryoneCanViewDeleted.Bool {
-		canViewDeleted = true
-	} else if config.ManageMessagesCanViewDeleted.Bool && !canViewDeleted {
-		canViewDeleted = web.HasPermissionCTX(r.Context(), discordgo.PermissionManageMessages)
+	return tmpl
+}
+
+// CreateMessageViews converts the messages into views with formatted dates, colors and archived attachments
+func CreateMessageViews(ctx context.Context, guildID int64, messages []*models.Messages2) []*MessageView {
+	const TimeFormat = "2006 Jan 02 15:04"
+
+	ids := make([]int64, len(messages))
+	for i, m := range messages {
+		ids[i] = m.ID
 	}
 
-	tmpl["CanViewDeleted"] = canViewDeleted
+	attachments, err := GetArchivedAttachments(ctx, guildID, ids)
+	if err != nil {
+		web.CtxLogger(ctx).WithError(err).Error("failed retrieving archived attachments")
+	}
 
-	// Convert into views with formatted dates and colors
-	const TimeFormat = "2006 Jan 02 15:04"
 	messageViews := make([]*MessageView, len(messages))
 	for i, _ := range messageViews {
 		m := messages[i]
 		v := &MessageView{
-			Model:     m,
-			Timestamp: m.CreatedAt.Format(TimeFormat),
+			Model:       m,
+			Timestamp:   m.CreatedAt.Format(TimeFormat),
+			Attachments: attachments[m.ID],
 		}
 		messageViews[i] = v
 	}
 
-	SetMessageLogsColors(g.ID, messageViews)
-
-	tmpl["Logs"] = logs
-	tmpl["Messages"] = messageViews
-
-	return tmpl
+	SetMessageLogsColors(guildID, messageViews)
+	return messageViews
 }
 
 func SetMessageLogsColors(guildID int64, views []*MessageView) {
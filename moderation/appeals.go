@@ -0,0 +1,461 @@
+package moderation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/jinzhu/gorm"
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/dstate/v2"
+	"github.com/jonas747/yagpdb/bot"
+	"github.com/jonas747/yagpdb/bot/eventsystem"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/common/scheduledevents2"
+	seventsmodels "github.com/jonas747/yagpdb/common/scheduledevents2/models"
+	"github.com/jonas747/yagpdb/web"
+	"github.com/mediocregopher/radix/v3"
+)
+
+const (
+	// The appeal link was sent but nothing has been submitted yet
+	BanAppealStatusAwaiting = "awaiting"
+	BanAppealStatusPending  = "pending"
+	BanAppealStatusApproved = "approved"
+	BanAppealStatusDenied   = "denied"
+)
+
+const (
+	BanAppealEmojiApprove = "✅"
+	BanAppealEmojiDeny    = "❌"
+)
+
+const MaxBanAppealLength = 2000
+
+// Values of the RedisKeyUnbannedUser key, telling HandleGuildBanAddRemove how the bot unbanned the user
+const (
+	unbanKeyTimedBan = 1
+	// The unban was already logged in the modlog by whatever did it
+	unbanKeyLogged = 2
+)
+
+// BanAppealModel is a ban appeal session created when a user is banned,
+// the user submits the appeal through the web page linked in the ban DM
+type BanAppealModel struct {
+	common.SmallModel
+
+	GuildID  int64 `gorm:"index:idx_moderation_ban_appeals_guild_user"`
+	UserID   int64 `gorm:"index:idx_moderation_ban_appeals_guild_user"`
+	Username string
+	Token    string
+
+	// Set when the appeal is submitted
+	AppealID  int64
+	BanReason string
+	Content   string
+	Status    string
+
+	HandledByID       int64
+	HandledByUsername string
+
+	StaffChannelID int64
+	StaffMessageID int64 `gorm:"index"`
+}
+
+func (b *BanAppealModel) TableName() string {
+	return "moderation_ban_appeals"
+}
+
+type ScheduledBanAppealSubmittedData struct {
+	AppealID int64 `json:"appeal_id"`
+}
+
+var (
+	ErrBanAppealNotFound       = errors.New("Ban appeal not found")
+	ErrBanAppealAlreadyHandled = errors.New("That appeal has already been handled")
+	ErrNoBanAppealChannel      = errors.New("No ban appeal channel set up")
+)
+
+// BanAppealLink returns the link to the appeal page of the session
+func BanAppealLink(guildID int64, userID int64, token string) string {
+	return fmt.Sprintf("%s/public/%d/appeal/%d/%s", web.BaseURL(), guildID, userID, token)
+}
+
+// NewBanAppealSession creates a new appeal session for the banned user without storing it,
+// it's stored with StoreBanAppealSession once the ban went through
+func NewBanAppealSession(guildID int64, user *discordgo.User, banReason string) *BanAppealModel {
+	return &BanAppealModel{
+		GuildID:   guildID,
+		UserID:    user.ID,
+		Username:  user.Username + "#" + user.Discriminator,
+		Token:     web.RandBase64(24),
+		BanReason: banReason,
+		Status:    BanAppealStatusAwaiting,
+	}
+}
+
+// Link returns the link to the appeal page of the session
+func (b *BanAppealModel) Link() string {
+	return BanAppealLink(b.GuildID, b.UserID, b.Token)
+}
+
+// StoreBanAppealSession stores the appeal session, any previous session of the user that wasn't submitted is replaced
+func StoreBanAppealSession(model *BanAppealModel) error {
+	err := common.GORM.Where("guild_id = ? AND user_id = ? AND status = ?", model.GuildID, model.UserID, BanAppealStatusAwaiting).Delete(&BanAppealModel{}).Error
+	if err != nil {
+		return errors.WithStackIf(err)
+	}
+
+	return errors.WithStackIf(common.GORM.Create(model).Error)
+}
+
+// GetBanAppealSession returns the appeal session with the token, regardless of its status
+func GetBanAppealSession(guildID, userID int64, token string) (*BanAppealModel, error) {
+	var appeal BanAppealModel
+	err := common.GORM.Where("guild_id = ? AND user_id = ? AND token = ?", guildID, userID, token).First(&appeal).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrBanAppealNotFound
+		}
+
+		return nil, errors.WithStackIf(err)
+	}
+
+	return &appeal, nil
+}
+
+func getBanAppeal(where string, args ...interface{}) (*BanAppealModel, error) {
+	var appeal BanAppealModel
+	err := common.GORM.Where(where, args...).First(&appeal).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrBanAppealNotFound
+		}
+
+		return nil, errors.WithStackIf(err)
+	}
+
+	return &appeal, nil
+}
+
+// SubmitBanAppeal stores the appeal and queues it up for the bot to post in the staff channel
+func SubmitBanAppeal(appeal *BanAppealModel, content string) error {
+	if appeal.Status != BanAppealStatusAwaiting {
+		return ErrBanAppealAlreadyHandled
+	}
+
+	appealID, err := common.GenLocalIncrIDPQ(nil, appeal.GuildID, "moderation_ban_appeals")
+	if err != nil {
+		return err
+	}
+
+	// only one of multiple submits at the same time goes through
+	result := common.GORM.Model(appeal).Where("status = ?", BanAppealStatusAwaiting).Updates(map[string]interface{}{
+		"appeal_id": appealID,
+		"content":   content,
+		"status":    BanAppealStatusPending,
+	})
+	if result.Error != nil {
+		return errors.WithStackIf(result.Error)
+	}
+	if result.RowsAffected < 1 {
+		return ErrBanAppealAlreadyHandled
+	}
+
+	appeal.AppealID = appealID
+	appeal.Content = content
+	appeal.Status = BanAppealStatusPending
+
+	return scheduledevents2.ScheduleEvent("moderation_ban_appeal_submitted", appeal.GuildID, time.Now(), &ScheduledBanAppealSubmittedData{
+		AppealID: appealID,
+	})
+}
+
+func handleScheduledBanAppealSubmitted(evt *seventsmodels.ScheduledEvent, data interface{}) (retry bool, err error) {
+	appealData := data.(*ScheduledBanAppealSubmittedData)
+
+	config, err := GetConfig(evt.GuildID)
+	if err != nil {
+		return true, errors.WithStackIf(err)
+	}
+
+	appeal, err := getBanAppeal("guild_id = ? AND appeal_id = ?", evt.GuildID, appealData.AppealID)
+	if err != nil {
+		if err == ErrBanAppealNotFound {
+			return false, nil
+		}
+		return true, err
+	}
+
+	err = postBanAppeal(config, appeal)
+	if err == ErrNoBanAppealChannel {
+		return false, nil
+	}
+
+	return scheduledevents2.CheckDiscordErrRetry(err), err
+}
+
+// postBanAppeal sends the appeal embed to the staff channel, or updates the existing one
+func postBanAppeal(config *Config, appeal *BanAppealModel) error {
+	embed := BanAppealEmbed(appeal)
+
+	if appeal.StaffMessageID != 0 {
+		_, err := common.BotSession.ChannelMessageEditEmbed(appeal.StaffChannelID, appeal.StaffMessageID, embed)
+		if err == nil || !common.IsDiscordErr(err, discordgo.ErrCodeUnknownMessage, discordgo.ErrCodeUnknownChannel) {
+			return err
+		}
+
+		// the message or channel is gone, only repost it if it still needs handling
+		if appeal.Status != BanAppealStatusPending {
+			return nil
+		}
+	}
+
+	channelID := config.IntBanAppealChannel()
+	if channelID == 0 {
+		return ErrNoBanAppealChannel
+	}
+
+	m, err := common.BotSession.ChannelMessageSendEmbed(channelID, embed)
+	if err != nil {
+		return err
+	}
+
+	appeal.StaffChannelID = channelID
+	appeal.StaffMessageID = m.ID
+	err = common.GORM.Model(appeal).Updates(map[string]interface{}{
+		"staff_channel_id": channelID,
+		"staff_message_id": m.ID,
+	}).Error
+	if err != nil {
+		return errors.WithStackIf(err)
+	}
+
+	if appeal.Status == BanAppealStatusPending {
+		for _, emoji := range []string{BanAppealEmojiApprove, BanAppealEmojiDeny} {
+			err = common.BotSession.MessageReactionAdd(channelID, m.ID, emoji)
+			if err != nil {
+				logger.WithError(err).WithField("guild", appeal.GuildID).Warn("failed adding ban appeal reactions")
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+func banAppealStatusColor(status string) int {
+	switch status {
+	case BanAppealStatusApproved:
+		return 0x62c65f
+	case BanAppealStatusDenied:
+		return 0x57728e
+	}
+
+	return 0xf2a013
+}
+
+// BanAppealEmbed creates the embed displaying the appeal to staff
+func BanAppealEmbed(appeal *BanAppealModel) *discordgo.MessageEmbed {
+	banReason := appeal.BanReason
+	if banReason == "" {
+		banReason = "(no reason specified)"
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("Ban appeal #%d - %s", appeal.AppealID, strings.Title(appeal.Status)),
+		Description: fmt.Sprintf("**User:** %s <@%d> *(ID %d)*\n**Ban reason:** %s", appeal.Username, appeal.UserID, appeal.UserID, common.CutStringShort(banReason, 500)),
+		Color:       banAppealStatusColor(appeal.Status),
+		Fields: []*discordgo.MessageEmbedField{
+			&discordgo.MessageEmbedField{
+				Name:  "Appeal",
+				Value: common.CutStringShort(appeal.Content, 1000),
+			},
+		},
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("%s approve and unban, %s deny", BanAppealEmojiApprove, BanAppealEmojiDeny),
+		},
+		Timestamp: appeal.UpdatedAt.Format(time.RFC3339),
+	}
+
+	if appeal.HandledByID != 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "Status",
+			Value: fmt.Sprintf("%s by %s", strings.Title(appeal.Status), appeal.HandledByUsername),
+		})
+		embed.Footer = nil
+	}
+
+	return embed
+}
+
+// CanHandleBanAppeals returns true if the member has one of the ban command roles or ban members permissions in the channel
+func CanHandleBanAppeals(config *Config, channelID int64, ms *dstate.MemberState) bool {
+	for _, r := range ms.Roles {
+		if common.ContainsInt64Slice(config.BanCmdRoles, r) {
+			return true
+		}
+	}
+
+	hasPerms, err := bot.AdminOrPermMS(channelID, ms, discordgo.PermissionBanMembers)
+	return err == nil && hasPerms
+}
+
+// HandleBanAppeal approves or denies the appeal, approving unbans the user. The user is DM'd the outcome either way
+func HandleBanAppeal(config *Config, appeal *BanAppealModel, approve bool, handler *discordgo.User) error {
+	if appeal.Status != BanAppealStatusPending {
+		return ErrBanAppealAlreadyHandled
+	}
+
+	status := BanAppealStatusDenied
+	if approve {
+		status = BanAppealStatusApproved
+	}
+
+	// claim it first so two staff members reacting at the same time can't both handle it
+	result := common.GORM.Model(appeal).Where("status = ?", BanAppealStatusPending).Updates(map[string]interface{}{
+		"status":              status,
+		"handled_by_id":       handler.ID,
+		"handled_by_username": handler.Username + "#" + handler.Discriminator,
+	})
+	if result.Error != nil {
+		return errors.WithStackIf(result.Error)
+	}
+	if result.RowsAffected < 1 {
+		return ErrBanAppealAlreadyHandled
+	}
+
+	appeal.Status = status
+	appeal.HandledByID = handler.ID
+	appeal.HandledByUsername = handler.Username + "#" + handler.Discriminator
+
+	guildName := bot.GuildName(appeal.GuildID)
+	dmMsg := fmt.Sprintf("**%s:** Your ban appeal was denied.", guildName)
+
+	if approve {
+		err := unbanAppealedUser(config, appeal, handler)
+		if err != nil {
+			// put it back so it can be handled again once whatever went wrong is fixed
+			rollbackErr := common.GORM.Model(appeal).Where("status = ?", status).Updates(map[string]interface{}{
+				"status":              BanAppealStatusPending,
+				"handled_by_id":       0,
+				"handled_by_username": "",
+			}).Error
+			common.LogIgnoreError(rollbackErr, "[moderation] failed rolling back ban appeal status", nil)
+
+			appeal.Status = BanAppealStatusPending
+			appeal.HandledByID = 0
+			appeal.HandledByUsername = ""
+			return err
+		}
+
+		dmMsg = fmt.Sprintf("**%s:** Your ban appeal was approved, you have been unbanned.", guildName)
+	}
+
+	// we don't share a server with them anymore most of the time, so this will often fail
+	err := bot.SendDM(appeal.UserID, dmMsg)
+	if err != nil {
+		logger.WithError(err).WithField("guild", appeal.GuildID).Debug("failed sending ban appeal outcome DM")
+	}
+
+	err = postBanAppeal(config, appeal)
+	if err != nil {
+		return err
+	}
+
+	if appeal.StaffMessageID != 0 {
+		err = common.BotSession.MessageReactionsRemoveAll(appeal.StaffChannelID, appeal.StaffMessageID)
+		common.LogIgnoreError(err, "[moderation] failed removing ban appeal reactions", nil)
+	}
+
+	return nil
+}
+
+func unbanAppealedUser(config *Config, appeal *BanAppealModel, handler *discordgo.User) error {
+	// let HandleGuildBanAddRemove know we're logging this one ourselves
+	common.RedisPool.Do(radix.FlatCmd(nil, "SETEX", RedisKeyUnbannedUser(appeal.GuildID, appeal.UserID), 30, unbanKeyLogged))
+
+	err := common.BotSession.GuildBanDelete(appeal.GuildID, appeal.UserID)
+	if err != nil {
+		common.RedisPool.Do(radix.Cmd(nil, "DEL", RedisKeyUnbannedUser(appeal.GuildID, appeal.UserID)))
+		return err
+	}
+
+	// there's no point in a pending timed unban anymore
+	err = scheduleUnban(appeal.GuildID, appeal.UserID, 0)
+	common.LogIgnoreError(err, "[moderation] failed clearing unban after appeal", nil)
+
+	user := &discordgo.User{
+		ID:       appeal.UserID,
+		Username: appeal.Username,
+	}
+	if i := strings.LastIndex(appeal.Username, "#"); i != -1 {
+		user.Username = appeal.Username[:i]
+		user.Discriminator = appeal.Username[i+1:]
+	}
+
+	// the user is unbanned at this point, so this doesn't fail the appeal
+	err = CreateModlogEmbed(config, handler, MAUnbanned, user, fmt.Sprintf("Ban appeal #%d approved", appeal.AppealID), "")
+	common.LogIgnoreError(err, "[moderation] failed creating ban appeal modlog entry", nil)
+	return nil
+}
+
+func handleBanAppealReactionAdd(evt *eventsystem.EventData) {
+	ra := evt.MessageReactionAdd()
+	if ra.UserID == common.BotUser.ID || ra.GuildID == 0 {
+		return
+	}
+
+	var approve bool
+	switch ra.Emoji.Name {
+	case BanAppealEmojiApprove:
+		approve = true
+	case BanAppealEmojiDeny:
+	default:
+		return
+	}
+
+	config, err := GetConfig(ra.GuildID)
+	if err != nil {
+		logger.WithError(err).WithField("guild", ra.GuildID).Error("failed retrieving config")
+		return
+	}
+
+	if !config.BanAppealsEnabled || config.IntBanAppealChannel() != ra.ChannelID {
+		return
+	}
+
+	appeal, err := getBanAppeal("guild_id = ? AND staff_message_id = ?", ra.GuildID, ra.MessageID)
+	if err != nil {
+		if err != ErrBanAppealNotFound {
+			logger.WithError(err).WithField("guild", ra.GuildID).Error("failed retrieving ban appeal")
+		}
+		return
+	}
+
+	ms, err := bot.GetMember(ra.GuildID, ra.UserID)
+	if err != nil || ms == nil {
+		return
+	}
+
+	if !CanHandleBanAppeals(config, ra.ChannelID, ms) {
+		common.BotSession.MessageReactionRemove(ra.ChannelID, ra.MessageID, ra.Emoji.APIName(), ra.UserID)
+		return
+	}
+
+	err = HandleBanAppeal(config, appeal, approve, ms.DGoUser())
+	if err == nil || errors.Cause(err) == ErrBanAppealAlreadyHandled {
+		return
+	}
+
+	// the appeal is still pending, remove the reaction so it can be tried again
+	common.BotSession.MessageReactionRemove(ra.ChannelID, ra.MessageID, ra.Emoji.APIName(), ra.UserID)
+
+	if code, msg := common.DiscordError(err); code != 0 {
+		common.BotSession.ChannelMessageSend(ra.ChannelID, fmt.Sprintf("Failed unbanning the user for ban appeal #%d: %s", appeal.AppealID, msg))
+		return
+	}
+
+	logger.WithError(err).WithField("guild", ra.GuildID).Error("failed handling ban appeal")
+}
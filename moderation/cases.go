@@ -0,0 +1,220 @@
+package moderation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/jinzhu/gorm"
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/yagpdb/common"
+)
+
+// CaseModel is a persistent record of a moderation action, every action that goes through the modlog creates one
+type CaseModel struct {
+	common.SmallModel
+
+	GuildID    int64 `gorm:"index:idx_moderation_cases_guild_case"`
+	CaseNumber int64 `gorm:"index:idx_moderation_cases_guild_case"`
+
+	Action string
+	Emoji  string
+
+	UserID       int64 `gorm:"index"`
+	UserUsername string
+
+	AuthorID       int64
+	AuthorUsername string
+
+	Reason string
+
+	// Duration in seconds, 0 if permanent or not applicable
+	Duration int64
+	LogsLink string
+
+	ModlogChannelID int64
+	ModlogMessageID int64 `gorm:"index"`
+}
+
+func (c *CaseModel) TableName() string {
+	return "moderation_cases"
+}
+
+// ModlogMessageLink returns a link to the modlog message for this case, or an empty string if there is none
+func (c *CaseModel) ModlogMessageLink() string {
+	if c.ModlogMessageID == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("https://discord.com/channels/%d/%d/%d", c.GuildID, c.ModlogChannelID, c.ModlogMessageID)
+}
+
+// ErrCaseNotFound is returned when a case could not be found
+var ErrCaseNotFound = errors.New("Case not found")
+
+// case numbers above this are assumed to be modlog message id's, from before cases were introduced
+const maxCaseNumber = 1 << 32
+
+// CreateCase stores a new numbered case for the action
+func CreateCase(guildID int64, author *discordgo.User, action ModlogAction, target *discordgo.User, reason, logLink string) (*CaseModel, error) {
+	caseNumber, err := common.GenLocalIncrIDPQ(nil, guildID, "moderation_cases")
+	if err != nil {
+		return nil, err
+	}
+
+	modCase := &CaseModel{
+		GuildID:    guildID,
+		CaseNumber: caseNumber,
+
+		Action: strings.TrimSpace(action.Prefix),
+		Emoji:  action.Emoji,
+
+		UserID:       target.ID,
+		UserUsername: target.Username + "#" + target.Discriminator,
+
+		Reason:   reason,
+		Duration: int64(action.Duration.Seconds()),
+		LogsLink: logLink,
+	}
+
+	if author != nil {
+		modCase.AuthorID = author.ID
+		modCase.AuthorUsername = author.Username + "#" + author.Discriminator
+	}
+
+	err = common.GORM.Create(modCase).Error
+	if err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+
+	return modCase, nil
+}
+
+// GetCase returns the case with the specified case number
+func GetCase(guildID int64, caseNumber int64) (*CaseModel, error) {
+	var modCase CaseModel
+	err := common.GORM.Where("guild_id = ? AND case_number = ?", guildID, caseNumber).First(&modCase).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrCaseNotFound
+		}
+
+		return nil, errors.WithStackIf(err)
+	}
+
+	return &modCase, nil
+}
+
+// GetCaseByModlogMessage returns the case that was posted as the specified modlog message
+func GetCaseByModlogMessage(guildID int64, messageID int64) (*CaseModel, error) {
+	var modCase CaseModel
+	err := common.GORM.Where("guild_id = ? AND modlog_message_id = ?", guildID, messageID).First(&modCase).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrCaseNotFound
+		}
+
+		return nil, errors.WithStackIf(err)
+	}
+
+	return &modCase, nil
+}
+
+// UpdateCaseReason updates the reason and moderator of the case, as well as the modlog message if there is one
+func UpdateCaseReason(modCase *CaseModel, author *discordgo.User, reason string) error {
+	modCase.Reason = reason
+	modCase.AuthorID = author.ID
+	modCase.AuthorUsername = author.Username + "#" + author.Discriminator
+
+	err := common.GORM.Model(modCase).Updates(map[string]interface{}{
+		"reason":          modCase.Reason,
+		"author_id":       modCase.AuthorID,
+		"author_username": modCase.AuthorUsername,
+	}).Error
+	if err != nil {
+		return errors.WithStackIf(err)
+	}
+
+	if modCase.ModlogMessageID == 0 {
+		return nil
+	}
+
+	msg, err := common.BotSession.ChannelMessage(modCase.ModlogChannelID, modCase.ModlogMessageID)
+	if err != nil {
+		if common.IsDiscordErr(err, discordgo.ErrCodeUnknownMessage, discordgo.ErrCodeUnknownChannel) {
+			// modlog message was deleted, the case is still updated
+			return nil
+		}
+		return err
+	}
+
+	if len(msg.Embeds) < 1 {
+		return nil
+	}
+
+	embed := msg.Embeds[0]
+	updateEmbedReason(author, reason, embed)
+	_, err = common.BotSession.ChannelMessageEditEmbed(modCase.ModlogChannelID, msg.ID, embed)
+	return err
+}
+
+// CaseEmbed creates a embed displaying all the info about the case
+func CaseEmbed(modCase *CaseModel) *discordgo.MessageEmbed {
+	author := modCase.AuthorUsername
+	if author == "" {
+		author = "Unknown"
+	}
+
+	reason := modCase.Reason
+	if reason == "" {
+		reason = "(no reason specified)"
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("Case #%d - %s%s", modCase.CaseNumber, modCase.Emoji, modCase.Action),
+		Fields: []*discordgo.MessageEmbedField{
+			&discordgo.MessageEmbedField{
+				Name:   "User",
+				Value:  fmt.Sprintf("%s (%d)", modCase.UserUsername, modCase.UserID),
+				Inline: true,
+			},
+			&discordgo.MessageEmbedField{
+				Name:   "Moderator",
+				Value:  fmt.Sprintf("%s (%d)", author, modCase.AuthorID),
+				Inline: true,
+			},
+			&discordgo.MessageEmbedField{
+				Name:  "Reason",
+				Value: common.CutStringShort(reason, 1000),
+			},
+		},
+		Timestamp: modCase.CreatedAt.Format(time.RFC3339),
+	}
+
+	if modCase.Duration > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Duration",
+			Value:  common.HumanizeDuration(common.DurationPrecisionMinutes, time.Duration(modCase.Duration)*time.Second),
+			Inline: true,
+		})
+	}
+
+	links := make([]string, 0, 2)
+	if modCase.LogsLink != "" {
+		links = append(links, "[Logs]("+modCase.LogsLink+")")
+	}
+	if link := modCase.ModlogMessageLink(); link != "" {
+		links = append(links, "[Modlog entry]("+link+")")
+	}
+
+	if len(links) > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Links",
+			Value:  strings.Join(links, " - "),
+			Inline: true,
+		})
+	}
+
+	return embed
+}
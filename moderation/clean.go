@@ -0,0 +1,229 @@
+package moderation
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/yagpdb/bot"
+	"github.com/jonas747/yagpdb/common"
+)
+
+const (
+	// MaxCleanDelete is the max number of messages the clean command deletes in one go
+	MaxCleanDelete = 1000
+	// MaxCleanScan is the max number of messages the clean command looks through
+	MaxCleanScan = 5000
+)
+
+// CleanFilters decides which messages the clean command deletes.
+// The content type filters (attachments, embeds, links, invites, mentions) match if the message has any of the selected types,
+// all the other filters have to match.
+type CleanFilters struct {
+	User         int64
+	Regex        *regexp.Regexp
+	ToID         int64
+	MaxAge       time.Duration
+	MinAge       time.Duration
+	IgnorePinned bool
+
+	BotsOnly    bool
+	NonBotsOnly bool
+
+	Attachments bool
+	Embeds      bool
+	Links       bool
+	Invites     bool
+	Mentions    bool
+
+	pinned map[int64]struct{}
+}
+
+func (f *CleanFilters) contentFiltered() bool {
+	return f.Attachments || f.Embeds || f.Links || f.Invites || f.Mentions
+}
+
+// Filtered returns true if any filter is set, meaning not every message is deleted
+func (f *CleanFilters) Filtered() bool {
+	return f.User != 0 || f.Regex != nil || f.ToID != 0 || f.MaxAge != 0 || f.MinAge != 0 || f.IgnorePinned ||
+		f.BotsOnly || f.NonBotsOnly || f.contentFiltered()
+}
+
+// Matches returns true if the message should be deleted
+func (f *CleanFilters) Matches(msg *discordgo.Message, now time.Time) bool {
+	if f.User != 0 && msg.Author.ID != f.User {
+		return false
+	}
+
+	if f.BotsOnly && !msg.Author.Bot {
+		return false
+	}
+
+	if f.NonBotsOnly && msg.Author.Bot {
+		return false
+	}
+
+	if f.Regex != nil && !f.Regex.MatchString(msg.Content) {
+		return false
+	}
+
+	age := now.Sub(bot.SnowflakeToTime(msg.ID))
+	if f.MaxAge != 0 && age > f.MaxAge {
+		return false
+	}
+
+	if f.MinAge != 0 && age < f.MinAge {
+		return false
+	}
+
+	if f.IgnorePinned {
+		if _, found := f.pinned[msg.ID]; found {
+			return false
+		}
+	}
+
+	if !f.contentFiltered() {
+		return true
+	}
+
+	switch {
+	case f.Attachments && len(msg.Attachments) > 0:
+	case f.Embeds && len(msg.Embeds) > 0:
+	case f.Links && common.LinkRegex.MatchString(msg.Content):
+	case f.Invites && common.ContainsInvite(msg.Content, true, true) != nil:
+	case f.Mentions && (len(msg.Mentions) > 0 || len(msg.MentionRoles) > 0 || msg.MentionEveryone):
+	default:
+		return false
+	}
+
+	return true
+}
+
+// CleanMessages looks through up to scanNum of the latest messages in the channel and deletes up to deleteNum of the ones matching the filters.
+// Messages older than 2 weeks can't be bulk deleted so it stops when it reaches those.
+// If progress is not nil it's called after every batch of messages scanned.
+func CleanMessages(channelID int64, filters *CleanFilters, deleteNum, scanNum int, progress func(scanned, deleted int)) (int, error) {
+	if filters.IgnorePinned {
+		// Fetch pinned messages from channel and make a map with ids as keys which will make it easy to verify if a message with a given ID is pinned message
+		messageSlice, err := common.BotSession.ChannelMessagesPinned(channelID)
+		if err != nil {
+			return 0, err
+		}
+		filters.pinned = make(map[int64]struct{}, len(messageSlice))
+		for _, msg := range messageSlice {
+			filters.pinned[msg.ID] = struct{}{}
+		}
+	}
+
+	if scanNum > MaxCleanScan {
+		scanNum = MaxCleanScan
+	}
+
+	scanned := 0
+	deleted := 0
+	before := int64(0)
+	done := false
+
+	for !done && scanned < scanNum && deleted < deleteNum {
+		toFetch := scanNum - scanned
+		if toFetch > 100 {
+			toFetch = 100
+		}
+
+		msgs, err := common.BotSession.ChannelMessages(channelID, toFetch, before, 0, 0)
+		if err != nil {
+			return deleted, err
+		}
+
+		if len(msgs) < toFetch {
+			done = true
+		}
+
+		now := time.Now()
+		toDelete := make([]int64, 0, len(msgs))
+		for _, msg := range msgs {
+			// messages are newest first, so all the remaining ones are past the stop message or too old as well
+			if filters.ToID != 0 && msg.ID < filters.ToID {
+				done = true
+				break
+			}
+
+			// Can only bulk delete messages up to 2 weeks (but add 1 minute buffer account for time sync issues and other smallies)
+			if now.Sub(bot.SnowflakeToTime(msg.ID)) > (time.Hour*24*14)-time.Minute {
+				done = true
+				break
+			}
+
+			scanned++
+			if !filters.Matches(msg, now) {
+				continue
+			}
+
+			toDelete = append(toDelete, msg.ID)
+			if deleted+len(toDelete) >= deleteNum {
+				break
+			}
+		}
+
+		if len(msgs) > 0 {
+			before = msgs[len(msgs)-1].ID
+		}
+
+		if len(toDelete) == 1 {
+			err = common.BotSession.ChannelMessageDelete(channelID, toDelete[0])
+		} else if len(toDelete) > 1 {
+			err = common.BotSession.ChannelMessagesBulkDelete(channelID, toDelete)
+		}
+		if err != nil {
+			return deleted, err
+		}
+
+		deleted += len(toDelete)
+		if progress != nil {
+			progress(scanned, deleted)
+		}
+	}
+
+	return deleted, nil
+}
+
+// cleanProgress shows the progress of a clean in the channel, the message is only sent if the clean takes a while
+type cleanProgress struct {
+	channelID  int64
+	scanTotal  int
+	lastUpdate time.Time
+	messageID  int64
+}
+
+func newCleanProgress(channelID int64, scanTotal int) *cleanProgress {
+	return &cleanProgress{
+		channelID:  channelID,
+		scanTotal:  scanTotal,
+		lastUpdate: time.Now(),
+	}
+}
+
+func (c *cleanProgress) update(scanned, deleted int) {
+	if time.Since(c.lastUpdate) < time.Second*3 {
+		return
+	}
+	c.lastUpdate = time.Now()
+
+	content := fmt.Sprintf("Cleaning... looked through %d/%d messages, deleted %d so far", scanned, c.scanTotal, deleted)
+	if c.messageID == 0 {
+		m, err := common.BotSession.ChannelMessageSend(c.channelID, content)
+		if err == nil {
+			c.messageID = m.ID
+		}
+		return
+	}
+
+	common.BotSession.ChannelMessageEdit(c.channelID, c.messageID, content)
+}
+
+func (c *cleanProgress) finish() {
+	if c.messageID != 0 {
+		common.BotSession.ChannelMessageDelete(c.channelID, c.messageID)
+	}
+}
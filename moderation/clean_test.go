@@ -0,0 +1,111 @@
+package moderation
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/yagpdb/bot"
+)
+
+func TestCleanFiltersMatches(t *testing.T) {
+	const msgID = 700000000000000000
+	// the message is an hour old
+	now := bot.SnowflakeToTime(msgID).Add(time.Hour)
+
+	user := &discordgo.User{ID: 1}
+	botUser := &discordgo.User{ID: 2, Bot: true}
+
+	cases := []struct {
+		Filters  *CleanFilters
+		Message  *discordgo.Message
+		Expected bool
+	}{
+		{ // 0, no filters
+			Filters:  &CleanFilters{},
+			Message:  &discordgo.Message{ID: msgID, Author: user, Content: "hello"},
+			Expected: true,
+		},
+		{ // 1, other user
+			Filters:  &CleanFilters{User: 3},
+			Message:  &discordgo.Message{ID: msgID, Author: user},
+			Expected: false,
+		},
+		{ // 2, bots only
+			Filters:  &CleanFilters{BotsOnly: true},
+			Message:  &discordgo.Message{ID: msgID, Author: user},
+			Expected: false,
+		},
+		{ // 3, bots only from a bot
+			Filters:  &CleanFilters{BotsOnly: true},
+			Message:  &discordgo.Message{ID: msgID, Author: botUser},
+			Expected: true,
+		},
+		{ // 4, non bots only from a bot
+			Filters:  &CleanFilters{NonBotsOnly: true},
+			Message:  &discordgo.Message{ID: msgID, Author: botUser},
+			Expected: false,
+		},
+		{ // 5, regex
+			Filters:  &CleanFilters{Regex: regexp.MustCompile("^hel+o$")},
+			Message:  &discordgo.Message{ID: msgID, Author: user, Content: "hello"},
+			Expected: true,
+		},
+		{ // 6, too old
+			Filters:  &CleanFilters{MaxAge: time.Minute},
+			Message:  &discordgo.Message{ID: msgID, Author: user},
+			Expected: false,
+		},
+		{ // 7, too new
+			Filters:  &CleanFilters{MinAge: time.Hour * 2},
+			Message:  &discordgo.Message{ID: msgID, Author: user},
+			Expected: false,
+		},
+		{ // 8, attachments without any
+			Filters:  &CleanFilters{Attachments: true},
+			Message:  &discordgo.Message{ID: msgID, Author: user, Content: "hello"},
+			Expected: false,
+		},
+		{ // 9, attachments
+			Filters:  &CleanFilters{Attachments: true},
+			Message:  &discordgo.Message{ID: msgID, Author: user, Attachments: []*discordgo.MessageAttachment{{ID: 1}}},
+			Expected: true,
+		},
+		{ // 10, any of the content types
+			Filters:  &CleanFilters{Attachments: true, Embeds: true},
+			Message:  &discordgo.Message{ID: msgID, Author: user, Embeds: []*discordgo.MessageEmbed{{Title: "a"}}},
+			Expected: true,
+		},
+		{ // 11, links
+			Filters:  &CleanFilters{Links: true},
+			Message:  &discordgo.Message{ID: msgID, Author: user, Content: "look at https://example.com/a"},
+			Expected: true,
+		},
+		{ // 12, mentions
+			Filters:  &CleanFilters{Mentions: true},
+			Message:  &discordgo.Message{ID: msgID, Author: user, Content: "hi <@2>", Mentions: []*discordgo.User{botUser}},
+			Expected: true,
+		},
+		{ // 13, content type matches but the user doesn't
+			Filters:  &CleanFilters{User: 3, Mentions: true},
+			Message:  &discordgo.Message{ID: msgID, Author: user, MentionEveryone: true},
+			Expected: false,
+		},
+		{ // 14, pinned
+			Filters:  &CleanFilters{IgnorePinned: true, pinned: map[int64]struct{}{msgID: struct{}{}}},
+			Message:  &discordgo.Message{ID: msgID, Author: user},
+			Expected: false,
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(fmt.Sprintf("Case %d", k), func(t *testing.T) {
+			result := v.Filters.Matches(v.Message, now)
+			if result != v.Expected {
+				t.Errorf("GOT %t EXPECTED %t", result, v.Expected)
+			}
+		})
+	}
+}
@@ -120,6 +120,175 @@ func GenericCmdResp(action ModlogAction, target *discordgo.User, duration time.D
 	return fmt.Sprintf("%s %s `%s`%s", action.Emoji, action.Prefix, userStr, durStr)
 }
 
+func massActionSwitches(ban bool) []*dcmd.ArgDef {
+	switches := []*dcmd.ArgDef{
+		&dcmd.ArgDef{Switch: "reason", Name: "Reason", Type: dcmd.String},
+		&dcmd.ArgDef{Switch: "joined", Default: time.Duration(0), Name: "Joined within", Type: &commands.DurationArg{}},
+		&dcmd.ArgDef{Switch: "age", Default: time.Duration(0), Name: "Account younger than", Type: &commands.DurationArg{}},
+		&dcmd.ArgDef{Switch: "name", Name: "Username regex", Type: dcmd.String},
+		&dcmd.ArgDef{Switch: "confirm", Name: "Go through with it"},
+	}
+
+	if ban {
+		switches = append(switches,
+			&dcmd.ArgDef{Switch: "d", Default: time.Duration(0), Name: "Duration", Type: &commands.DurationArg{}},
+			&dcmd.ArgDef{Switch: "ddays", Name: "Days", Type: dcmd.Int},
+		)
+	}
+
+	return switches
+}
+
+func massActionCmdFunc(p Punishment) dcmd.RunFunc {
+	return func(parsed *dcmd.Data) (interface{}, error) {
+		config, _, err := MBaseCmd(parsed, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		reason := ""
+		if parsed.Switches["reason"].Value != nil {
+			reason = parsed.Switches["reason"].Str()
+		}
+
+		action := MAKick
+		if p == PunishmentBan {
+			action = MABanned
+			reason, err = MBaseCmdSecond(parsed, reason, config.BanReasonOptional, discordgo.PermissionBanMembers, config.BanCmdRoles, config.BanEnabled)
+		} else {
+			reason, err = MBaseCmdSecond(parsed, reason, config.KickReasonOptional, discordgo.PermissionKickMembers, config.KickCmdRoles, config.KickEnabled)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		filter := &MassActionFilter{
+			UserIDs:            ParseMassActionUserIDs(SafeArgString(parsed, 0)),
+			JoinedWithin:       parsed.Switches["joined"].Value.(time.Duration),
+			AccountYoungerThan: parsed.Switches["age"].Value.(time.Duration),
+		}
+
+		if parsed.Switches["name"].Value != nil {
+			filter.UsernameRegex, err = regexp.Compile(parsed.Switches["name"].Str())
+			if err != nil {
+				return "Invalid username regex: " + err.Error(), nil
+			}
+		}
+
+		if len(filter.UserIDs) > 0 && filter.HasMemberFilters() {
+			return "Specify either a list of users or filters, not both", nil
+		} else if len(filter.UserIDs) < 1 && !filter.HasMemberFilters() {
+			return "No users or filters specified, see `help " + parsed.Cmd.Trigger.Names[0] + "`", nil
+		}
+
+		if len(filter.UserIDs) > MaxMassActionTargets {
+			return fmt.Sprintf("Specified %d users, max is %d at a time", len(filter.UserIDs), MaxMassActionTargets), nil
+		}
+
+		targets, skipped := FindMassActionTargets(parsed.GS, parsed.MS, filter, p == PunishmentKick)
+		if len(targets) < 1 {
+			return fmt.Sprintf("No users matched (skipped %d you can't target)", skipped), nil
+		}
+
+		if len(targets) > MaxMassActionTargets {
+			return fmt.Sprintf("Matched %d users, max is %d at a time, narrow down your filters", len(targets), MaxMassActionTargets), nil
+		}
+
+		if parsed.Switches["confirm"].Value == nil || !parsed.Switches["confirm"].Value.(bool) {
+			return MassActionPreview(action, targets, skipped), nil
+		}
+
+		var duration time.Duration
+		ddays := 0
+		if p == PunishmentBan {
+			duration = parsed.Switches["d"].Value.(time.Duration)
+			ddays = int(config.DefaultBanDeleteDays.Int64)
+			if parsed.Switches["ddays"].Value != nil {
+				ddays = parsed.Switches["ddays"].Int()
+			}
+		}
+
+		if !lockMassAction(parsed.GS.ID) {
+			return "There's already a mass action running on this server, wait for it to finish", nil
+		}
+
+		// this can take a long time so it's done in the background, with the progress posted in the channel
+		progress := newMassActionProgress(parsed.CS.ID, action, len(targets))
+		go func() {
+			defer unlockMassAction(parsed.GS.ID)
+
+			punished, failed, err := MassPunish(config, p, parsed.GS.ID, parsed.Msg.Author, reason, targets, duration, ddays, progress.update)
+			if err != nil {
+				logger.WithError(err).WithField("guild", parsed.GS.ID).Error("failed creating mass action modlog entry")
+			}
+
+			progress.finish(len(punished), failed, err)
+		}()
+
+		return nil, nil
+	}
+}
+
+// reportTargetUser returns the user being reported, falling back to a placeholder if they can't be found
+func reportTargetUser(guildID, userID int64) *discordgo.User {
+	if ms, err := bot.GetMember(guildID, userID); err == nil && ms != nil {
+		return ms.DGoUser()
+	}
+
+	if user, err := common.BotSession.User(userID); err == nil {
+		return user
+	}
+
+	return &discordgo.User{
+		Username:      "unknown",
+		Discriminator: "????",
+		ID:            userID,
+	}
+}
+
+func reportStatusCmd(name string, aliases []string, status string, description string) *commands.YAGCommand {
+	return &commands.YAGCommand{
+		CustomEnabled: true,
+		CmdCategory:   commands.CategoryModeration,
+		Name:          name,
+		Aliases:       aliases,
+		Description:   description,
+		RequiredArgs:  1,
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "Report", Type: dcmd.Int},
+			&dcmd.ArgDef{Name: "Note", Type: dcmd.String},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			config, _, err := MBaseCmd(parsed, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			if !CanHandleReports(config, parsed.CS.ID, parsed.MS) {
+				return nil, commands.NewUserError(ErrNoReportHandlingPerms.Error())
+			}
+
+			report, err := GetReport(parsed.GS.ID, parsed.Args[0].Int64())
+			if err != nil {
+				if err == ErrReportNotFound {
+					return fmt.Sprintf("Report #%d does not exist", parsed.Args[0].Int64()), nil
+				}
+				return nil, err
+			}
+
+			err = SetReportStatus(config, report, status, parsed.Msg.Author, SafeArgString(parsed, 1))
+			if err != nil {
+				if err == ErrReportAlreadyClaimed || err == ErrReportAlreadyHandled {
+					return err.Error(), nil
+				}
+				return nil, err
+			}
+
+			return fmt.Sprintf("Report #%d marked as %s", report.ReportID, status), nil
+		},
+	}
+}
+
 var ModerationCommands = []*commands.YAGCommand{
 	&commands.YAGCommand{
 		CustomEnabled: true,
@@ -190,6 +359,30 @@ var ModerationCommands = []*commands.YAGCommand{
 			return GenericCmdResp(MAKick, target, 0, true, true), nil
 		},
 	},
+	&commands.YAGCommand{
+		CustomEnabled:   true,
+		CmdCategory:     commands.CategoryModeration,
+		Name:            "MassBan",
+		Description:     "Bans multiple users at once, either a list of user ID's/mentions or everyone matching the filters",
+		LongDescription: "Without `-confirm` this only shows who would be banned.\nFilters: `-joined 10m` joined within, `-age 1d` account younger than, `-name regex` username matches.\nSpecify a ban duration with -d and number of days of messages to delete with -ddays (0 to 7)",
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "Users", Type: dcmd.String},
+		},
+		ArgSwitches: massActionSwitches(true),
+		RunFunc:     massActionCmdFunc(PunishmentBan),
+	},
+	&commands.YAGCommand{
+		CustomEnabled:   true,
+		CmdCategory:     commands.CategoryModeration,
+		Name:            "MassKick",
+		Description:     "Kicks multiple members at once, either a list of user ID's/mentions or everyone matching the filters",
+		LongDescription: "Without `-confirm` this only shows who would be kicked.\nFilters: `-joined 10m` joined within, `-age 1d` account younger than, `-name regex` username matches.",
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "Users", Type: dcmd.String},
+		},
+		ArgSwitches: massActionSwitches(false),
+		RunFunc:     massActionCmdFunc(PunishmentKick),
+	},
 	&commands.YAGCommand{
 		CustomEnabled: true,
 		CmdCategory:   commands.CategoryModeration,
@@ -279,6 +472,81 @@ var ModerationCommands = []*commands.YAGCommand{
 			return GenericCmdResp(MAUnmute, target, 0, false, true), nil
 		},
 	},
+	&commands.YAGCommand{
+		CustomEnabled: true,
+		CmdCategory:   commands.CategoryModeration,
+		Name:          "Timeout",
+		Aliases:       []string{"to"},
+		Description:   "Puts a member in a timeout using discord's native timeouts, they can't talk, react or join voice channels until it expires",
+		RequiredArgs:  1,
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "User", Type: dcmd.UserID},
+			&dcmd.ArgDef{Name: "Duration", Type: &commands.DurationArg{}},
+			&dcmd.ArgDef{Name: "Reason", Type: dcmd.String},
+		},
+		ArgumentCombos: [][]int{[]int{0, 1, 2}, []int{0, 2, 1}, []int{0, 1}, []int{0, 2}, []int{0}},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			config, target, err := MBaseCmd(parsed, parsed.Args[0].Int64())
+			if err != nil {
+				return nil, err
+			}
+
+			reason := parsed.Args[2].Str()
+			reason, err = MBaseCmdSecond(parsed, reason, config.TimeoutReasonOptional, discordgo.PermissionKickMembers, config.TimeoutCmdRoles, config.TimeoutEnabled)
+			if err != nil {
+				return nil, err
+			}
+
+			var d time.Duration
+			if parsed.Args[1].Value != nil {
+				d = parsed.Args[1].Value.(time.Duration)
+			}
+			d = config.TimeoutDuration(d)
+
+			member, err := bot.GetMember(parsed.GS.ID, target.ID)
+			if err != nil || member == nil {
+				return "Member not found", err
+			}
+
+			err = TimeoutUser(config, parsed.GS.ID, parsed.CS, parsed.Msg, parsed.Msg.Author, reason, member, d)
+			if err != nil {
+				return nil, err
+			}
+
+			return GenericCmdResp(MATimeoutAdded, target, d, true, false), nil
+		},
+	},
+	&commands.YAGCommand{
+		CustomEnabled: true,
+		CmdCategory:   commands.CategoryModeration,
+		Name:          "RemoveTimeout",
+		Aliases:       []string{"untimeout", "unto"},
+		Description:   "Removes a member's timeout",
+		RequiredArgs:  1,
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "User", Type: dcmd.UserID},
+			&dcmd.ArgDef{Name: "Reason", Type: dcmd.String},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			config, target, err := MBaseCmd(parsed, parsed.Args[0].Int64())
+			if err != nil {
+				return nil, err
+			}
+
+			reason := parsed.Args[1].Str()
+			reason, err = MBaseCmdSecond(parsed, reason, config.TimeoutRemoveReasonOptional, discordgo.PermissionKickMembers, config.TimeoutCmdRoles, config.TimeoutEnabled)
+			if err != nil {
+				return nil, err
+			}
+
+			err = RemoveTimeout(config, parsed.GS.ID, parsed.Msg.Author, reason, target)
+			if err != nil {
+				return nil, err
+			}
+
+			return GenericCmdResp(MATimeoutRemoved, target, 0, false, true), nil
+		},
+	},
 	&commands.YAGCommand{
 		CustomEnabled: true,
 		Cooldown:      5,
@@ -301,45 +569,269 @@ var ModerationCommands = []*commands.YAGCommand{
 				return nil, err
 			}
 
-			target := parsed.Args[0].Int64()
-
-			logLink := CreateLogs(parsed.GS.ID, parsed.CS.ID, parsed.Msg.Author)
+			target := reportTargetUser(parsed.GS.ID, parsed.Args[0].Int64())
+			if target.ID == parsed.Msg.Author.ID {
+				return ErrCantReportYourself.Error(), nil
+			}
 
 			channelID := config.IntReportChannel()
 			if channelID == 0 {
 				return "No report channel set up", nil
 			}
 
-			reportBody := fmt.Sprintf("<@%d> Reported <@%d> in <#%d> For `%s`\nLast 100 messages from channel: <%s>", parsed.Msg.Author.ID, target, parsed.Msg.ChannelID, parsed.Args[1].Str(), logLink)
-
-			_, err = common.BotSession.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
-				Content: reportBody,
-				AllowedMentions: discordgo.AllowedMentions{
-					Users: []int64{parsed.Msg.Author.ID, target},
-				},
-			})
+			logLink := CreateLogs(parsed.GS.ID, parsed.CS.ID, parsed.Msg.Author)
 
+			report, merged, err := CreateReport(config, parsed.GS.ID, parsed.Msg.Author, target, parsed.CS.ID, parsed.Args[1].Str(), logLink, false)
 			if err != nil {
 				return nil, err
 			}
 
 			// don't bother sending confirmation if it's in the same channel
-			if channelID != parsed.Msg.ChannelID {
-				return "User reported to the proper authorities", nil
+			if channelID == parsed.Msg.ChannelID {
+				return nil, nil
+			}
+
+			if merged {
+				return fmt.Sprintf("User reported to the proper authorities, added to the existing report #%d", report.ReportID), nil
 			}
-			return nil, nil
+			return fmt.Sprintf("User reported to the proper authorities (report #%d)", report.ReportID), nil
+		},
+	},
+	&commands.YAGCommand{
+		CmdCategory:  commands.CategoryModeration,
+		Name:         "AnonReport",
+		Description:  "Anonymously reports a member to a server's staff, use this in the bot's DM's",
+		RunInDM:      true,
+		RequiredArgs: 3,
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "Server-ID", Type: dcmd.Int},
+			&dcmd.ArgDef{Name: "User", Type: dcmd.UserID},
+			&dcmd.ArgDef{Name: "Reason", Type: dcmd.String},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			if parsed.Source != dcmd.DMSource {
+				common.BotSession.ChannelMessageDelete(parsed.CS.ID, parsed.Msg.ID)
+				return "Anonymous reports can only be made in DM's, i deleted your message so nobody saw it", nil
+			}
+
+			guildID := parsed.Args[0].Int64()
+			config, err := GetConfig(guildID)
+			if err != nil {
+				return nil, err
+			}
+
+			if !config.ReportEnabled || !config.ReportAnonymousEnabled {
+				return ErrAnonymousReportsOff.Error(), nil
+			}
+
+			if ms, err := bot.GetMember(guildID, parsed.Msg.Author.ID); err != nil || ms == nil {
+				return ErrReporterNotInGuild.Error(), nil
+			}
+
+			target := reportTargetUser(guildID, parsed.Args[1].Int64())
+			if target.ID == parsed.Msg.Author.ID {
+				return ErrCantReportYourself.Error(), nil
+			}
+
+			ok, err := checkAnonymousReportCooldown(parsed.Msg.Author.ID)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return ErrReportCooldown.Error(), nil
+			}
+
+			report, _, err := CreateReport(config, guildID, parsed.Msg.Author, target, 0, parsed.Args[2].Str(), "", true)
+			if err != nil {
+				if err == ErrNoReportChannel {
+					return "That server has no report channel set up", nil
+				}
+				return nil, err
+			}
+
+			return fmt.Sprintf("Reported anonymously to the staff of %s (report #%d)", bot.GuildName(guildID), report.ReportID), nil
+		},
+	},
+	reportStatusCmd("ClaimReport", []string{"claim"}, ReportStatusClaimed, "Claims a report, marking you as the one dealing with it"),
+	reportStatusCmd("ResolveReport", []string{"resolve"}, ReportStatusResolved, "Marks a report as resolved, optionally with a note on what was done"),
+	reportStatusCmd("DismissReport", []string{"dismiss"}, ReportStatusDismissed, "Dismisses a report, optionally with a note on why"),
+	&commands.YAGCommand{
+		CustomEnabled: true,
+		CmdCategory:   commands.CategoryModeration,
+		Name:          "Reports",
+		Description:   "Lists the reports against a user, or the currently open reports if no user is specified",
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "User", Type: dcmd.UserID, Default: 0},
+			&dcmd.ArgDef{Name: "Page", Type: &dcmd.IntArg{Max: 10000}, Default: 0},
+		},
+		RunFunc: paginatedmessages.PaginatedCommand(1, func(parsed *dcmd.Data, p *paginatedmessages.PaginatedMessage, page int) (*discordgo.MessageEmbed, error) {
+			config, _, err := MBaseCmd(parsed, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			if !CanHandleReports(config, parsed.CS.ID, parsed.MS) {
+				return nil, commands.NewUserError(ErrNoReportHandlingPerms.Error())
+			}
+
+			userID := parsed.Args[0].Int64()
+
+			query := common.GORM.Where("guild_id = ? AND target_id = ?", parsed.GS.ID, userID)
+			title := fmt.Sprintf("Reports - User : %d", userID)
+			if userID == 0 {
+				query = common.GORM.Where("guild_id = ? AND status IN (?)", parsed.GS.ID, []string{ReportStatusOpen, ReportStatusClaimed})
+				title = "Active reports"
+			}
+
+			var count int
+			err = query.Model(&ReportModel{}).Count(&count).Error
+			if err != nil {
+				return nil, err
+			}
+
+			var result []*ReportModel
+			err = query.Preload("Reasons").Order("report_id desc").Offset((page - 1) * 10).Limit(10).Find(&result).Error
+			if err != nil {
+				return nil, err
+			}
+
+			if len(result) < 1 && p != nil && p.LastResponse != nil { //Don't send No Results error on first execution.
+				return nil, paginatedmessages.ErrNoResults
+			}
+
+			desc := fmt.Sprintf("**Total :** `%d`\n\n", count)
+			if len(result) < 1 {
+				desc += "No reports"
+			}
+
+			for _, v := range result {
+				reason := ""
+				if len(v.Reasons) > 0 {
+					reason = v.Reasons[0].Reason
+				}
+
+				desc += fmt.Sprintf("**#%d** `%s` **%s** %s (%d reports) - %s\n", v.ReportID, v.CreatedAt.UTC().Format(time.RFC822), strings.Title(v.Status), v.TargetUsername, len(v.Reasons), common.CutStringShort(reason, 80))
+			}
+
+			return &discordgo.MessageEmbed{
+				Title:       title,
+				Description: desc,
+			}, nil
+		}),
+	},
+	&commands.YAGCommand{
+		CustomEnabled: true,
+		CmdCategory:   commands.CategoryModeration,
+		Name:          "Lockdown",
+		Aliases:       []string{"lock"},
+		Description:   "Stops everyone from sending messages in a channel, all channels in a category or the whole server",
+		LongDescription: "Target is `here` (default), a channel, a category ID or `server`.\n" +
+			"Specify a duration with -d, also deny adding reactions with -reactions and creating threads with -threads.\n" +
+			"The previous permissions are restored exactly when unlocking.",
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "Target", Type: dcmd.String},
+			&dcmd.ArgDef{Name: "Reason", Type: dcmd.String},
+		},
+		ArgSwitches: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Switch: "d", Default: time.Duration(0), Name: "Duration", Type: &commands.DurationArg{}},
+			&dcmd.ArgDef{Switch: "reactions", Name: "Also deny adding reactions"},
+			&dcmd.ArgDef{Switch: "threads", Name: "Also deny creating threads"},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			config, _, err := MBaseCmd(parsed, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			reason, err := MBaseCmdSecond(parsed, SafeArgString(parsed, 1), true, discordgo.PermissionManageChannels, config.LockdownCmdRoles, config.LockdownEnabled)
+			if err != nil {
+				return nil, err
+			}
+
+			channels, targetDescription, err := LockdownTargets(parsed.GS, parsed.CS.ID, SafeArgString(parsed, 0))
+			if err != nil {
+				return err.Error(), nil
+			}
+
+			perms := discordgo.PermissionSendMessages
+			if parsed.Switches["reactions"].Value != nil && parsed.Switches["reactions"].Value.(bool) {
+				perms |= discordgo.PermissionAddReactions
+			}
+			if parsed.Switches["threads"].Value != nil && parsed.Switches["threads"].Value.(bool) {
+				perms |= LockdownThreadPerms
+			}
+
+			duration := parsed.Switches["d"].Value.(time.Duration)
+			locked, err := LockdownChannels(config, parsed.GS, parsed.Msg.Author, channels, perms, duration, targetDescription, reason)
+			if err != nil {
+				if err == ErrNoLockdownTargets {
+					return err.Error(), nil
+				}
+				return nil, err
+			}
+
+			if locked < 1 {
+				return "Nothing was locked down, either it's already locked down or i'm missing permissions", nil
+			}
+
+			durStr := ""
+			if duration > 0 {
+				durStr = " for `" + common.HumanizeDuration(common.DurationPrecisionMinutes, duration) + "`"
+			}
+
+			return fmt.Sprintf("%s Locked down %s (%d channels)%s", MALockdown.Emoji, targetDescription, locked, durStr), nil
 		},
 	},
 	&commands.YAGCommand{
 		CustomEnabled:   true,
 		CmdCategory:     commands.CategoryModeration,
-		Name:            "Clean",
-		Description:     "Delete the last number of messages from chat, optionally filtering by user, max age and regex or ignoring pinned messages.",
-		LongDescription: "Specify a regex with \"-r regex_here\" and max age with \"-ma 1h10m\"\nNote: Will only look in the last 1k messages",
-		Aliases:         []string{"clear", "cl"},
-		RequiredArgs:    1,
+		Name:            "Unlock",
+		Description:     "Lifts a lockdown, restoring the previous permissions",
+		LongDescription: "Target is `here` (default), a channel, a category ID or `server`.",
 		Arguments: []*dcmd.ArgDef{
-			&dcmd.ArgDef{Name: "Num", Type: &dcmd.IntArg{Min: 1, Max: 100}},
+			&dcmd.ArgDef{Name: "Target", Type: dcmd.String},
+			&dcmd.ArgDef{Name: "Reason", Type: dcmd.String},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			config, _, err := MBaseCmd(parsed, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			reason, err := MBaseCmdSecond(parsed, SafeArgString(parsed, 1), true, discordgo.PermissionManageChannels, config.LockdownCmdRoles, config.LockdownEnabled)
+			if err != nil {
+				return nil, err
+			}
+
+			channels, targetDescription, err := LockdownTargets(parsed.GS, parsed.CS.ID, SafeArgString(parsed, 0))
+			if err != nil {
+				return err.Error(), nil
+			}
+
+			unlocked, err := UnlockChannels(config, parsed.GS.ID, parsed.Msg.Author, channels, 0, targetDescription, reason)
+			if err != nil {
+				if err == ErrNotLockedDown {
+					return err.Error(), nil
+				}
+				return nil, err
+			}
+
+			return fmt.Sprintf("%s Unlocked %s (%d channels)", MAUnlock.Emoji, targetDescription, unlocked), nil
+		},
+	},
+	&commands.YAGCommand{
+		CustomEnabled: true,
+		CmdCategory:   commands.CategoryModeration,
+		Name:          "Clean",
+		Description:   "Delete the last number of messages from chat, optionally filtering by user, max age, regex, content type or ignoring pinned messages.",
+		LongDescription: "Specify a regex with \"-r regex_here\" and max age with \"-ma 1h10m\"\n" +
+			"Content filters: -attachments, -embeds, -links, -invites and -mentions deletes messages having any of the specified types, " +
+			"-bots and -nobots limits it to messages from bots or from regular users.\n" +
+			"Note: Will only look in the last 1k messages by default when filtering, use -scan to look further back (max 5k messages)",
+		Aliases:      []string{"clear", "cl"},
+		RequiredArgs: 1,
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "Num", Type: &dcmd.IntArg{Min: 1, Max: MaxCleanDelete}},
 			&dcmd.ArgDef{Name: "User", Type: dcmd.UserID, Default: 0},
 		},
 		ArgSwitches: []*dcmd.ArgDef{
@@ -349,6 +841,14 @@ var ModerationCommands = []*commands.YAGCommand{
 			&dcmd.ArgDef{Switch: "i", Name: "Regex case insensitive"},
 			&dcmd.ArgDef{Switch: "nopin", Name: "Ignore pinned messages"},
 			&dcmd.ArgDef{Switch: "to", Name: "Stop at this msg ID", Type: dcmd.Int},
+			&dcmd.ArgDef{Switch: "attachments", Name: "Messages with attachments"},
+			&dcmd.ArgDef{Switch: "embeds", Name: "Messages with embeds"},
+			&dcmd.ArgDef{Switch: "links", Name: "Messages with links"},
+			&dcmd.ArgDef{Switch: "invites", Name: "Messages with invites"},
+			&dcmd.ArgDef{Switch: "mentions", Name: "Messages with mentions"},
+			&dcmd.ArgDef{Switch: "bots", Name: "Only messages from bots"},
+			&dcmd.ArgDef{Switch: "nobots", Name: "Only messages from non-bots"},
+			&dcmd.ArgDef{Switch: "scan", Name: "Number of messages to look through", Type: &dcmd.IntArg{Min: 1, Max: MaxCleanScan}},
 		},
 		ArgumentCombos: [][]int{[]int{0}, []int{0, 1}, []int{1, 0}},
 		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
@@ -362,15 +862,53 @@ var ModerationCommands = []*commands.YAGCommand{
 				return nil, err
 			}
 
-			userFilter := parsed.Args[1].Int64()
+			filters := &CleanFilters{
+				User:         parsed.Args[1].Int64(),
+				MaxAge:       parsed.Switches["ma"].Value.(time.Duration),
+				MinAge:       parsed.Switches["minage"].Value.(time.Duration),
+				IgnorePinned: parsed.Switch("nopin").Bool(),
+				Attachments:  parsed.Switch("attachments").Bool(),
+				Embeds:       parsed.Switch("embeds").Bool(),
+				Links:        parsed.Switch("links").Bool(),
+				Invites:      parsed.Switch("invites").Bool(),
+				Mentions:     parsed.Switch("mentions").Bool(),
+				BotsOnly:     parsed.Switch("bots").Bool(),
+				NonBotsOnly:  parsed.Switch("nobots").Bool(),
+			}
+
+			if filters.BotsOnly && filters.NonBotsOnly {
+				return "Can't use both -bots and -nobots", nil
+			}
+
+			// Check if we should regex match this
+			if parsed.Switches["r"].Value != nil {
+				re := parsed.Switches["r"].Str()
+
+				// Add the case insensitive flag if needed
+				if parsed.Switches["i"].Value != nil && parsed.Switches["i"].Value.(bool) {
+					if !strings.HasPrefix(re, "(?i)") {
+						re = "(?i)" + re
+					}
+				}
+
+				filters.Regex, err = regexp.Compile(re)
+				if err != nil {
+					return "Invalid regex: " + err.Error(), nil
+				}
+			}
+
+			// Check if set to break at a certain ID
+			if parsed.Switches["to"].Value != nil {
+				filters.ToID = parsed.Switches["to"].Int64()
+			}
 
 			num := parsed.Args[0].Int()
-			if (userFilter == 0 || userFilter == parsed.Msg.Author.ID) && parsed.Source != 0 {
-				num++ // Automatically include our own message if not triggeded by exec/execAdmin
+			if parsed.Source != 0 && filters.Matches(parsed.Msg, time.Now()) {
+				num++ // Automatically include our own message if not triggeded by exec/execAdmin and it will be deleted
 			}
 
-			if num > 100 {
-				num = 100
+			if num > MaxCleanDelete {
+				num = MaxCleanDelete
 			}
 
 			if num < 1 {
@@ -380,77 +918,155 @@ var ModerationCommands = []*commands.YAGCommand{
 				return errors.New("Can't delete nothing"), nil
 			}
 
-			filtered := false
+			limitFetch := num
+			if filters.Filtered() {
+				limitFetch = num * 50 // Maybe just change to full fetch?
+				if limitFetch > 1000 {
+					limitFetch = 1000
+				}
+			}
+
+			if parsed.Switches["scan"].Value != nil {
+				limitFetch = parsed.Switches["scan"].Int()
+			}
+
+			if limitFetch < num {
+				limitFetch = num
+			}
+
+			// Wait a second so the client dosen't gltich out
+			time.Sleep(time.Second)
+
+			progress := newCleanProgress(parsed.Msg.ChannelID, limitFetch)
+			numDeleted, err := CleanMessages(parsed.Msg.ChannelID, filters, num, limitFetch, progress.update)
+			progress.finish()
+
+			return dcmd.NewTemporaryResponse(time.Second*5, fmt.Sprintf("Deleted %d message(s)! :')", numDeleted), true), err
+		},
+	},
+	&commands.YAGCommand{
+		CustomEnabled: true,
+		CmdCategory:   commands.CategoryModeration,
+		Name:          "Reason",
+		Description:   "Add/Edit the reason of a case, also updating its modlog entry",
+		LongDescription: "The case number is shown in the footer of the modlog entry, " +
+			"modlog message ID's are also accepted for entries made before cases were introduced.",
+		RequiredArgs: 2,
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "Case", Type: dcmd.Int},
+			&dcmd.ArgDef{Name: "Reason", Type: dcmd.String},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			config, _, err := MBaseCmd(parsed, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			_, err = MBaseCmdSecond(parsed, "", true, discordgo.PermissionKickMembers, nil, true)
+			if err != nil {
+				return nil, err
+			}
+
+			caseID := parsed.Args[0].Int64()
+			reason := parsed.Args[1].Str()
+
+			var modCase *CaseModel
+			if caseID > maxCaseNumber {
+				// probably a modlog message id
+				modCase, err = GetCaseByModlogMessage(parsed.GS.ID, caseID)
+			} else {
+				modCase, err = GetCase(parsed.GS.ID, caseID)
+			}
+
+			if err == nil {
+				err = UpdateCaseReason(modCase, parsed.Msg.Author, reason)
+				if err != nil {
+					return nil, err
+				}
+
+				return "👌", nil
+			}
+
+			if err != ErrCaseNotFound {
+				return nil, err
+			}
 
-			// Check if we should regex match this
-			re := ""
-			if parsed.Switches["r"].Value != nil {
-				filtered = true
-				re = parsed.Switches["r"].Str()
+			if caseID <= maxCaseNumber {
+				return fmt.Sprintf("Case #%d does not exist", caseID), nil
+			}
 
-				// Add the case insensitive flag if needed
-				if parsed.Switches["i"].Value != nil && parsed.Switches["i"].Value.(bool) {
-					if !strings.HasPrefix(re, "(?i)") {
-						re = "(?i)" + re
-					}
-				}
+			// legacy modlog entry without a case
+			if config.ActionChannel == "" {
+				return "No mod log channel set up", nil
 			}
 
-			// Check if we have a max age
-			ma := parsed.Switches["ma"].Value.(time.Duration)
-			if ma != 0 {
-				filtered = true
+			msg, err := common.BotSession.ChannelMessage(config.IntActionChannel(), caseID)
+			if err != nil {
+				return nil, err
 			}
 
-			// Check if we have a min age
-			minAge := parsed.Switches["minage"].Value.(time.Duration)
-			if minAge != 0 {
-				filtered = true
+			if msg.Author.ID != common.BotUser.ID {
+				return "I didn't make that message", nil
 			}
 
-			// Check if set to break at a certain ID
-			toID := int64(0)
-			if parsed.Switches["to"].Value != nil {
-				filtered = true
-				toID = parsed.Switches["to"].Int64()
+			if len(msg.Embeds) < 1 {
+				return "This entry is either too old or you're trying to mess with me...", nil
 			}
 
-			// Check if we should ignore pinned messages
-			pe := false
-			if parsed.Switches["nopin"].Value != nil && parsed.Switches["nopin"].Value.(bool) {
-				pe = true
-				filtered = true
+			embed := msg.Embeds[0]
+			updateEmbedReason(parsed.Msg.Author, reason, embed)
+			_, err = common.BotSession.ChannelMessageEditEmbed(config.IntActionChannel(), msg.ID, embed)
+			if err != nil {
+				return nil, err
 			}
 
-			limitFetch := num
-			if userFilter != 0 || filtered {
-				limitFetch = num * 50 // Maybe just change to full fetch?
+			return "👌", nil
+		},
+	},
+	&commands.YAGCommand{
+		CustomEnabled: true,
+		CmdCategory:   commands.CategoryModeration,
+		Name:          "Case",
+		Description:   "Shows a moderation case",
+		RequiredArgs:  1,
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "Case", Type: dcmd.Int},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			_, _, err := MBaseCmd(parsed, 0)
+			if err != nil {
+				return nil, err
 			}
 
-			if limitFetch > 1000 {
-				limitFetch = 1000
+			_, err = MBaseCmdSecond(parsed, "", true, discordgo.PermissionKickMembers, nil, true)
+			if err != nil {
+				return nil, err
 			}
 
-			// Wait a second so the client dosen't gltich out
-			time.Sleep(time.Second)
+			modCase, err := GetCase(parsed.GS.ID, parsed.Args[0].Int64())
+			if err != nil {
+				if err == ErrCaseNotFound {
+					return fmt.Sprintf("Case #%d does not exist", parsed.Args[0].Int64()), nil
+				}
 
-			numDeleted, err := AdvancedDeleteMessages(parsed.Msg.ChannelID, userFilter, re, toID, ma, minAge, pe, num, limitFetch)
+				return nil, err
+			}
 
-			return dcmd.NewTemporaryResponse(time.Second*5, fmt.Sprintf("Deleted %d message(s)! :')", numDeleted), true), err
+			return CaseEmbed(modCase), nil
 		},
 	},
 	&commands.YAGCommand{
 		CustomEnabled: true,
 		CmdCategory:   commands.CategoryModeration,
-		Name:          "Reason",
-		Description:   "Add/Edit a modlog reason",
-		RequiredArgs:  2,
+		Name:          "Cases",
+		Description:   "Lists the moderation cases of a user",
+		RequiredArgs:  1,
 		Arguments: []*dcmd.ArgDef{
-			&dcmd.ArgDef{Name: "Message ID", Type: dcmd.Int},
-			&dcmd.ArgDef{Name: "Reason", Type: dcmd.String},
+			&dcmd.ArgDef{Name: "User", Type: dcmd.UserID},
+			&dcmd.ArgDef{Name: "Page", Type: &dcmd.IntArg{Max: 10000}, Default: 0},
 		},
-		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
-			config, _, err := MBaseCmd(parsed, 0)
+		RunFunc: paginatedmessages.PaginatedCommand(1, func(parsed *dcmd.Data, p *paginatedmessages.PaginatedMessage, page int) (*discordgo.MessageEmbed, error) {
+			_, _, err := MBaseCmd(parsed, 0)
 			if err != nil {
 				return nil, err
 			}
@@ -460,30 +1076,69 @@ var ModerationCommands = []*commands.YAGCommand{
 				return nil, err
 			}
 
-			if config.ActionChannel == "" {
-				return "No mod log channel set up", nil
+			userID := parsed.Args[0].Int64()
+
+			var count int
+			err = common.GORM.Model(&CaseModel{}).Where("guild_id = ? AND user_id = ?", parsed.GS.ID, userID).Count(&count).Error
+			if err != nil {
+				return nil, err
 			}
 
-			msg, err := common.BotSession.ChannelMessage(config.IntActionChannel(), parsed.Args[0].Int64())
+			var result []*CaseModel
+			err = common.GORM.Where("guild_id = ? AND user_id = ?", parsed.GS.ID, userID).Order("case_number desc").Offset((page - 1) * 10).Limit(10).Find(&result).Error
 			if err != nil {
 				return nil, err
 			}
 
-			if msg.Author.ID != common.BotUser.ID {
-				return "I didn't make that message", nil
+			if len(result) < 1 && p != nil && p.LastResponse != nil { //Don't send No Results error on first execution.
+				return nil, paginatedmessages.ErrNoResults
 			}
 
-			if len(msg.Embeds) < 1 {
-				return "This entry is either too old or you're trying to mess with me...", nil
+			desc := fmt.Sprintf("**Total :** `%d`\n\n", count)
+			if len(result) < 1 {
+				desc += "No cases"
 			}
 
-			embed := msg.Embeds[0]
-			updateEmbedReason(parsed.Msg.Author, parsed.Args[1].Str(), embed)
-			_, err = common.BotSession.ChannelMessageEditEmbed(config.IntActionChannel(), msg.ID, embed)
+			for _, v := range result {
+				reason := v.Reason
+				if reason == "" {
+					reason = "(no reason specified)"
+				}
+
+				desc += fmt.Sprintf("**#%d** `%s` %s%s - %s\n", v.CaseNumber, v.CreatedAt.UTC().Format(time.RFC822), v.Emoji, v.Action, common.CutStringShort(reason, 100))
+			}
+
+			return &discordgo.MessageEmbed{
+				Title:       fmt.Sprintf("Cases - User : %d", userID),
+				Description: desc,
+			}, nil
+		}),
+	},
+	&commands.YAGCommand{
+		CustomEnabled: true,
+		CmdCategory:   commands.CategoryModeration,
+		Name:          "DelCase",
+		Description:   "Deletes a moderation case, the modlog entry is left untouched",
+		RequiredArgs:  1,
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "Case", Type: dcmd.Int},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			_, _, err := MBaseCmd(parsed, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			_, err = MBaseCmdSecond(parsed, "", true, discordgo.PermissionBanMembers, nil, true)
 			if err != nil {
 				return nil, err
 			}
 
+			rows := common.GORM.Where("guild_id = ? AND case_number = ?", parsed.GS.ID, parsed.Args[0].Int64()).Delete(CaseModel{}).RowsAffected
+			if rows < 1 {
+				return "Failed deleting, most likely couldn't find the case", nil
+			}
+
 			return "👌", nil
 		},
 	},
@@ -568,6 +1223,35 @@ var ModerationCommands = []*commands.YAGCommand{
 			return nil, err
 		},
 	},
+	&commands.YAGCommand{
+		CustomEnabled: true,
+		CmdCategory:   commands.CategoryModeration,
+		Name:          "History",
+		Aliases:       []string{"infractions", "userhistory"},
+		Description:   "Shows a summary of everything known about a user: warnings, cases, bans, automod violations, reputation, past names and tickets",
+		RequiredArgs:  1,
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "User", Type: dcmd.UserID},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			_, _, err := MBaseCmd(parsed, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			_, err = MBaseCmdSecond(parsed, "", true, discordgo.PermissionKickMembers, nil, true)
+			if err != nil {
+				return nil, err
+			}
+
+			history, err := GetUserHistory(parsed.Context(), parsed.GS.ID, parsed.Args[0].Int64())
+			if err != nil {
+				return nil, err
+			}
+
+			return history.Embed(), nil
+		},
+	},
 	&commands.YAGCommand{
 		CustomEnabled: true,
 		CmdCategory:   commands.CategoryModeration,
@@ -786,9 +1470,10 @@ var ModerationCommands = []*commands.YAGCommand{
 
 			action := MAGiveRole
 			action.Prefix = "Gave the role " + role.Name + " to "
-			if config.GiveRoleCmdModlog && config.IntActionChannel() != 0 {
+			if config.GiveRoleCmdModlog && config.ModlogChannel(action) != 0 {
 				if dur > 0 {
 					action.Footer = "Duration: " + common.HumanizeDuration(common.DurationPrecisionMinutes, dur)
+					action.Duration = dur
 				}
 				CreateModlogEmbed(config, parsed.Msg.Author, action, target, "", "")
 			}
@@ -842,109 +1527,208 @@ var ModerationCommands = []*commands.YAGCommand{
 			}
 
 			// cancel the event to remove the role
-			scheduledevents2.CancelRemoveRole(parsed.Context(), parsed.GS.ID, parsed.Msg.Author.ID, role.ID)
+			scheduledevents2.CancelRemoveRole(parsed.Context(), parsed.GS.ID, target.ID, role.ID)
 
 			action := MARemoveRole
 			action.Prefix = "Removed the role " + role.Name + " from "
-			if config.GiveRoleCmdModlog && config.IntActionChannel() != 0 {
+			if config.GiveRoleCmdModlog && config.ModlogChannel(action) != 0 {
 				CreateModlogEmbed(config, parsed.Msg.Author, action, target, "", "")
 			}
 
 			return GenericCmdResp(action, target, 0, true, true), nil
 		},
 	},
-}
+	&commands.YAGCommand{
+		CustomEnabled: true,
+		CmdCategory:   commands.CategoryModeration,
+		Name:          "TempRoles",
+		Description:   "Lists the temporary roles of a user, or of everyone on the server if no user is specified",
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "User", Type: dcmd.UserID, Default: 0},
+			&dcmd.ArgDef{Name: "Page", Type: &dcmd.IntArg{Max: 10000}, Default: 0},
+		},
+		RunFunc: paginatedmessages.PaginatedCommand(1, func(parsed *dcmd.Data, p *paginatedmessages.PaginatedMessage, page int) (*discordgo.MessageEmbed, error) {
+			config, _, err := MBaseCmd(parsed, 0)
+			if err != nil {
+				return nil, err
+			}
 
-func AdvancedDeleteMessages(channelID int64, filterUser int64, regex string, toID int64, maxAge time.Duration, minAge time.Duration, pinFilterEnable bool, deleteNum, fetchNum int) (int, error) {
-	var compiledRegex *regexp.Regexp
-	if regex != "" {
-		// Start by compiling the regex
-		var err error
-		compiledRegex, err = regexp.Compile(regex)
-		if err != nil {
-			return 0, err
-		}
-	}
+			_, err = MBaseCmdSecond(parsed, "", true, discordgo.PermissionManageRoles, config.GiveRoleCmdRoles, config.GiveRoleCmdEnabled)
+			if err != nil {
+				return nil, err
+			}
 
-	var pinnedMessages map[int64]struct{}
-	if pinFilterEnable {
-		//Fetch pinned messages from channel and make a map with ids as keys which will make it easy to verify if a message with a given ID is pinned message
-		messageSlice, err := common.BotSession.ChannelMessagesPinned(channelID)
-		if err != nil {
-			return 0, err
-		}
-		pinnedMessages = make(map[int64]struct{}, len(messageSlice))
-		for _, msg := range messageSlice {
-			pinnedMessages[msg.ID] = struct{}{} //empty struct works because we are not really interested in value
-		}
-	}
+			userID := parsed.Args[0].Int64()
+			tempRoles, total, err := GetTempRoles(parsed.Context(), parsed.GS.ID, userID, 10, (page-1)*10)
+			if err != nil {
+				return nil, err
+			}
 
-	msgs, err := bot.GetMessages(channelID, fetchNum, false)
-	if err != nil {
-		return 0, err
-	}
+			if len(tempRoles) < 1 && p != nil && p.LastResponse != nil { //Don't send No Results error on first execution.
+				return nil, paginatedmessages.ErrNoResults
+			}
 
-	toDelete := make([]int64, 0)
-	now := time.Now()
-	for i := len(msgs) - 1; i >= 0; i-- {
-		if filterUser != 0 && msgs[i].Author.ID != filterUser {
-			continue
-		}
+			desc := fmt.Sprintf("**Total :** `%d`\n\n", total)
+			if len(tempRoles) < 1 {
+				desc += "No temporary roles"
+			}
 
-		// Can only bulk delete messages up to 2 weeks (but add 1 minute buffer account for time sync issues and other smallies)
-		if now.Sub(msgs[i].ParsedCreated) > (time.Hour*24*14)-time.Minute {
-			continue
-		}
+			for _, v := range tempRoles {
+				source := ""
+				if v.FromRoleCommands() {
+					source = " (role command)"
+				}
 
-		// Check regex
-		if compiledRegex != nil {
-			if !compiledRegex.MatchString(msgs[i].Content) {
-				continue
+				desc += fmt.Sprintf("<@%d> <@&%d> expires in `%s`%s\n", v.UserID, v.RoleID, common.HumanizeDuration(common.DurationPrecisionMinutes, time.Until(v.ExpiresAt)), source)
 			}
-		}
 
-		// Check max age
-		if maxAge != 0 && now.Sub(msgs[i].ParsedCreated) > maxAge {
-			continue
-		}
+			title := "Temporary roles"
+			if userID != 0 {
+				title = fmt.Sprintf("Temporary roles - User : %d", userID)
+			}
 
-		// Check min age
-		if minAge != 0 && now.Sub(msgs[i].ParsedCreated) < minAge {
-			continue
-		}
+			return &discordgo.MessageEmbed{
+				Title:       title,
+				Description: desc,
+			}, nil
+		}),
+	},
+	&commands.YAGCommand{
+		CustomEnabled: true,
+		CmdCategory:   commands.CategoryModeration,
+		Name:          "ExtendRole",
+		Description:   "Extends the duration of a temporary role, or shortens it with -shorten",
+		RequiredArgs:  3,
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "User", Type: dcmd.UserID},
+			&dcmd.ArgDef{Name: "Role", Type: dcmd.String},
+			&dcmd.ArgDef{Name: "Duration", Type: &commands.DurationArg{}},
+		},
+		ArgSwitches: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Switch: "shorten", Name: "Shorten instead of extending"},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			config, target, err := MBaseCmd(parsed, parsed.Args[0].Int64())
+			if err != nil {
+				return nil, err
+			}
 
-		// Check if pinned message to ignore
-		if pinFilterEnable {
-			if _, found := pinnedMessages[msgs[i].ID]; found {
-				continue
+			_, err = MBaseCmdSecond(parsed, "", true, discordgo.PermissionManageRoles, config.GiveRoleCmdRoles, config.GiveRoleCmdEnabled)
+			if err != nil {
+				return nil, err
 			}
-		}
 
-		// Continue only if current msg ID is < toID
-		if toID > msgs[i].ID {
-			continue
-		}
+			role := FindRole(parsed.GS, parsed.Args[1].Str())
+			if role == nil {
+				return "Couldn't find the specified role", nil
+			}
 
-		toDelete = append(toDelete, msgs[i].ID)
-		//log.Println("Deleting", msgs[i].ContentWithMentionsReplaced())
-		if len(toDelete) >= deleteNum || len(toDelete) >= 100 {
-			break
-		}
+			parsed.GS.RLock()
+			if !bot.IsMemberAboveRole(parsed.GS, parsed.MS, role) {
+				parsed.GS.RUnlock()
+				return "Can't change roles above you", nil
+			}
+			parsed.GS.RUnlock()
+
+			dur := parsed.Args[2].Value.(time.Duration)
+			if parsed.Switches["shorten"].Value != nil && parsed.Switches["shorten"].Value.(bool) {
+				dur = -dur
+			}
+
+			tempRole, err := ExtendTempRole(parsed.Context(), parsed.GS.ID, target.ID, role.ID, dur)
+			if err != nil {
+				if err == ErrTempRoleNotFound {
+					return err.Error(), nil
+				}
+				return nil, err
+			}
+
+			return fmt.Sprintf("The role %s will now be removed from `%s` in `%s`", role.Name, target.Username, common.HumanizeDuration(common.DurationPrecisionMinutes, time.Until(tempRole.ExpiresAt))), nil
+		},
+	},
+	&commands.YAGCommand{
+		CustomEnabled: true,
+		CmdCategory:   commands.CategoryModeration,
+		Name:          "CancelTempRole",
+		Description:   "Cancels a temporary role, removing it right away, or with -keep, letting the user keep it permanently",
+		RequiredArgs:  2,
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "User", Type: dcmd.UserID},
+			&dcmd.ArgDef{Name: "Role", Type: dcmd.String},
+		},
+		ArgSwitches: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Switch: "keep", Name: "Keep the role permanently"},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			config, target, err := MBaseCmd(parsed, parsed.Args[0].Int64())
+			if err != nil {
+				return nil, err
+			}
+
+			_, err = MBaseCmdSecond(parsed, "", true, discordgo.PermissionManageRoles, config.GiveRoleCmdRoles, config.GiveRoleCmdEnabled)
+			if err != nil {
+				return nil, err
+			}
+
+			role := FindRole(parsed.GS, parsed.Args[1].Str())
+			if role == nil {
+				return "Couldn't find the specified role", nil
+			}
+
+			parsed.GS.RLock()
+			if !bot.IsMemberAboveRole(parsed.GS, parsed.MS, role) {
+				parsed.GS.RUnlock()
+				return "Can't change roles above you", nil
+			}
+			parsed.GS.RUnlock()
+
+			keep := parsed.Switches["keep"].Value != nil && parsed.Switches["keep"].Value.(bool)
+			err = CancelTempRole(parsed.Context(), parsed.GS.ID, target.ID, role.ID, !keep)
+			if err != nil {
+				if err == ErrTempRoleNotFound {
+					return err.Error(), nil
+				}
+				return nil, err
+			}
+
+			if keep {
+				return fmt.Sprintf("`%s` now has the role %s permanently", target.Username, role.Name), nil
+			}
+
+			action := MARemoveRole
+			action.Prefix = "Removed the role " + role.Name + " from "
+			if config.GiveRoleCmdModlog && config.ModlogChannel(action) != 0 {
+				CreateModlogEmbed(config, parsed.Msg.Author, action, target, "Temporary role cancelled", "")
+			}
+
+			return GenericCmdResp(action, target, 0, true, true), nil
+		},
+	},
+}
+
+func AdvancedDeleteMessages(channelID int64, filterUser int64, regex string, toID int64, maxAge time.Duration, minAge time.Duration, pinFilterEnable bool, deleteNum, fetchNum int) (int, error) {
+	filters := &CleanFilters{
+		User:         filterUser,
+		ToID:         toID,
+		MaxAge:       maxAge,
+		MinAge:       minAge,
+		IgnorePinned: pinFilterEnable,
 	}
 
-	if len(toDelete) < 1 {
-		return 0, nil
+	if regex != "" {
+		// Start by compiling the regex
+		var err error
+		filters.Regex, err = regexp.Compile(regex)
+		if err != nil {
+			return 0, err
+		}
 	}
 
-	if len(toDelete) < 1 {
-		return 0, nil
-	} else if len(toDelete) == 1 {
-		err = common.BotSession.ChannelMessageDelete(channelID, toDelete[0])
-	} else {
-		err = common.BotSession.ChannelMessagesBulkDelete(channelID, toDelete)
+	if deleteNum > 100 {
+		deleteNum = 100
 	}
 
-	return len(toDelete), err
+	return CleanMessages(channelID, filters, deleteNum, fetchNum, nil)
 }
 
 func FindRole(gs *dstate.GuildState, roleS string) *discordgo.Role {
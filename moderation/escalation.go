@@ -0,0 +1,135 @@
+package moderation
+
+import (
+	"fmt"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/dstate/v2"
+	"github.com/jonas747/yagpdb/bot"
+	"github.com/jonas747/yagpdb/common"
+)
+
+const (
+	WarnEscalationActionMute    = "mute"
+	WarnEscalationActionTimeout = "timeout"
+	WarnEscalationActionKick    = "kick"
+	WarnEscalationActionBan     = "ban"
+)
+
+// MaxWarnEscalations is the max number of escalation thresholds that can be configured
+const MaxWarnEscalations = 5
+
+// WarnEscalation is a punishment that is applied when a user reaches Count warnings within the escalation period
+type WarnEscalation struct {
+	Count  int64
+	Action string
+
+	// Duration of the mute, timeout or ban, 0 for permanent (or the default duration for timeouts)
+	Duration time.Duration
+}
+
+// WarnEscalations returns the configured escalation thresholds, skipping incomplete ones
+func (c *Config) WarnEscalations() []*WarnEscalation {
+	result := make([]*WarnEscalation, 0, len(c.WarnEscalationCounts))
+	for i, count := range c.WarnEscalationCounts {
+		if count < 1 || i >= len(c.WarnEscalationActions) || c.WarnEscalationActions[i] == "" {
+			continue
+		}
+
+		escalation := &WarnEscalation{
+			Count:  count,
+			Action: c.WarnEscalationActions[i],
+		}
+
+		if i < len(c.WarnEscalationDurations) && c.WarnEscalationDurations[i] > 0 {
+			escalation.Duration = time.Duration(c.WarnEscalationDurations[i]) * time.Minute
+		}
+
+		result = append(result, escalation)
+	}
+
+	return result
+}
+
+// WarnEscalationFormRows returns the escalations padded to MaxWarnEscalations, for the control panel form
+func (c *Config) WarnEscalationFormRows() []*WarnEscalation {
+	rows := c.WarnEscalations()
+	for len(rows) < MaxWarnEscalations {
+		rows = append(rows, &WarnEscalation{})
+	}
+
+	return rows
+}
+
+// DurationMinutes returns the duration in minutes, for the control panel form
+func (w *WarnEscalation) DurationMinutes() int64 {
+	return int64(w.Duration / time.Minute)
+}
+
+func (c *Config) warnEscalationPeriod() time.Duration {
+	days := c.WarnEscalationPeriod.Int64
+	if !c.WarnEscalationPeriod.Valid || days < 1 {
+		days = 30
+	}
+
+	return time.Duration(days) * time.Hour * 24
+}
+
+// FindWarnEscalation returns the escalation that applies at exactly warnCount warnings, or nil if there is none.
+// Only an exact match triggers, so the same punishment isn't applied again on every warning after reaching it
+func (c *Config) FindWarnEscalation(warnCount int64) *WarnEscalation {
+	for _, v := range c.WarnEscalations() {
+		if v.Count == warnCount {
+			return v
+		}
+	}
+
+	return nil
+}
+
+// checkWarnEscalation counts the recent warnings of the target and applies the matching escalation, if any
+func checkWarnEscalation(config *Config, guildID int64, channel *dstate.ChannelState, msg *discordgo.Message, target *discordgo.User) error {
+	if !config.WarnEscalationEnabled {
+		return nil
+	}
+
+	period := config.warnEscalationPeriod()
+
+	var count int64
+	err := common.GORM.Model(&WarningModel{}).Where("guild_id = ? AND user_id = ? AND created_at > ?", guildID, discordgo.StrID(target.ID), time.Now().Add(-period)).Count(&count).Error
+	if err != nil {
+		return errors.WithStackIf(err)
+	}
+
+	escalation := config.FindWarnEscalation(count)
+	if escalation == nil {
+		return nil
+	}
+
+	reason := fmt.Sprintf("Automatic escalation: reached %d warnings within %s", count, common.HumanizeDuration(common.DurationPrecisionDays, period))
+	author := common.BotUser
+
+	switch escalation.Action {
+	case WarnEscalationActionKick:
+		return KickUser(config, guildID, channel, msg, author, reason, target)
+	case WarnEscalationActionBan:
+		return BanUserWithDuration(config, guildID, channel, msg, author, reason, target, escalation.Duration, int(config.DefaultBanDeleteDays.Int64))
+	}
+
+	// mute and timeout needs the member
+	member, err := bot.GetMember(guildID, target.ID)
+	if err != nil || member == nil {
+		return nil
+	}
+
+	switch escalation.Action {
+	case WarnEscalationActionMute:
+		return MuteUnmuteUser(config, true, guildID, channel, msg, author, reason, member, int(escalation.DurationMinutes()))
+	case WarnEscalationActionTimeout:
+		return TimeoutUser(config, guildID, channel, msg, author, reason, member, escalation.Duration)
+	}
+
+	return nil
+}
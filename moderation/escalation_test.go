@@ -0,0 +1,92 @@
+package moderation
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jonas747/yagpdb/web"
+	"github.com/lib/pq"
+)
+
+func TestFindWarnEscalation(t *testing.T) {
+	config := &Config{
+		WarnEscalationCounts:    pq.Int64Array{3, 0, 5, 7, 10},
+		WarnEscalationActions:   pq.StringArray{WarnEscalationActionMute, WarnEscalationActionKick, WarnEscalationActionTimeout, "", WarnEscalationActionBan},
+		WarnEscalationDurations: pq.Int64Array{30, 0, 60},
+	}
+
+	cases := []struct {
+		Count            int64
+		ExpectedAction   string
+		ExpectedDuration time.Duration
+	}{
+		{1, "", 0},
+		{3, WarnEscalationActionMute, time.Minute * 30},
+		{4, "", 0},
+		{5, WarnEscalationActionTimeout, time.Hour},
+		{7, "", 0}, // no action set
+		{10, WarnEscalationActionBan, 0},
+		{11, "", 0}, // only exact matches
+		{0, "", 0},  // incomplete row with a count of 0
+	}
+
+	for _, v := range cases {
+		t.Run(fmt.Sprintf("Count %d", v.Count), func(t *testing.T) {
+			result := config.FindWarnEscalation(v.Count)
+			if v.ExpectedAction == "" {
+				if result != nil {
+					t.Errorf("GOT %+v EXPECTED nil", result)
+				}
+				return
+			}
+
+			if result == nil {
+				t.Fatalf("GOT nil EXPECTED %s", v.ExpectedAction)
+			}
+
+			if result.Action != v.ExpectedAction || result.Duration != v.ExpectedDuration {
+				t.Errorf("GOT %s %s EXPECTED %s %s", result.Action, result.Duration, v.ExpectedAction, v.ExpectedDuration)
+			}
+		})
+	}
+}
+
+func TestValidateWarnEscalations(t *testing.T) {
+	cases := []struct {
+		Name      string
+		Counts    pq.Int64Array
+		Actions   pq.StringArray
+		Durations pq.Int64Array
+		Valid     bool
+	}{
+		{"valid", pq.Int64Array{3, 5}, pq.StringArray{WarnEscalationActionMute, WarnEscalationActionBan}, pq.Int64Array{30, 0}, true},
+		{"empty", nil, nil, nil, true},
+		{"duplicate counts", pq.Int64Array{3, 3}, pq.StringArray{WarnEscalationActionMute, WarnEscalationActionKick}, nil, false},
+		{"duplicate counts without action", pq.Int64Array{3, 3}, pq.StringArray{WarnEscalationActionMute, ""}, nil, true},
+		{"duplicate incomplete rows", pq.Int64Array{0, 0}, pq.StringArray{WarnEscalationActionMute, WarnEscalationActionKick}, nil, true},
+		{"too many", pq.Int64Array{1, 2, 3, 4, 5, 6}, nil, nil, false},
+		{"count out of range", pq.Int64Array{1001}, pq.StringArray{WarnEscalationActionKick}, nil, false},
+		{"unknown action", pq.Int64Array{3}, pq.StringArray{"explode"}, nil, false},
+		{"negative duration", pq.Int64Array{3}, pq.StringArray{WarnEscalationActionMute}, pq.Int64Array{-5}, false},
+	}
+
+	for _, v := range cases {
+		t.Run(v.Name, func(t *testing.T) {
+			config := &Config{
+				WarnEscalationCounts:    v.Counts,
+				WarnEscalationActions:   v.Actions,
+				WarnEscalationDurations: v.Durations,
+			}
+
+			tmpl := web.TemplateData{}
+			if ok := config.Validate(tmpl); ok != v.Valid {
+				t.Errorf("GOT %t EXPECTED %t, alerts: %v", ok, v.Valid, tmpl["Alerts"])
+			}
+
+			if !v.Valid && len(tmpl.Alerts()) < 1 {
+				t.Errorf("No alert added for invalid config")
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package moderation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/dstate/v2"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/common/scheduledevents2"
+	seventsmodels "github.com/jonas747/yagpdb/common/scheduledevents2/models"
+)
+
+// Thread permissions, not in our discordgo version yet
+const (
+	PermissionCreatePublicThreads   = 1 << 35
+	PermissionCreatePrivateThreads  = 1 << 36
+	PermissionSendMessagesInThreads = 1 << 38
+
+	LockdownThreadPerms = PermissionCreatePublicThreads | PermissionCreatePrivateThreads | PermissionSendMessagesInThreads
+)
+
+var (
+	ErrNoLockdownTargets = errors.New("No channels to lock down")
+	ErrNotLockedDown     = errors.New("None of those channels are locked down")
+)
+
+// LockdownModel stores the @everyone overwrite of a channel from before it was locked down, so it can be restored exactly
+type LockdownModel struct {
+	common.SmallModel
+
+	GuildID    int64 `gorm:"index"`
+	ChannelID  int64 `gorm:"unique_index"`
+	LockdownID int64
+
+	// false if there was no @everyone overwrite before, in which case unlocking removes it
+	HadOverwrite bool
+	PrevAllow    int
+	PrevDeny     int
+
+	LockedByID int64
+	Reason     string
+}
+
+func (l *LockdownModel) TableName() string {
+	return "moderation_lockdowns"
+}
+
+type ScheduledLockdownExpiredData struct {
+	LockdownID int64 `json:"lockdown_id"`
+}
+
+// LockdownTargets resolves what channels a lockdown or unlock command applies to:
+// "server"/"all" for every text channel, a category for the text channels in it, "here" or nothing for the current channel,
+// otherwise the channel itself
+func LockdownTargets(gs *dstate.GuildState, currentChannel int64, target string) (channels []int64, description string, err error) {
+	gs.RLock()
+	defer gs.RUnlock()
+
+	target = strings.TrimSpace(target)
+	if target == "" || strings.EqualFold(target, "here") {
+		return []int64{currentChannel}, fmt.Sprintf("<#%d>", currentChannel), nil
+	}
+
+	isText := func(cs *dstate.ChannelState) bool {
+		return cs.Type == discordgo.ChannelTypeGuildText || cs.Type == discordgo.ChannelTypeGuildNews
+	}
+
+	if strings.EqualFold(target, "server") || strings.EqualFold(target, "all") {
+		for _, v := range gs.Channels {
+			if isText(v) {
+				channels = append(channels, v.ID)
+			}
+		}
+
+		return channels, "the whole server", nil
+	}
+
+	channelID, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(target, "<#"), ">"), 10, 64)
+	if err != nil {
+		return nil, "", errors.New("Unknown channel, specify a channel, category ID or `server`")
+	}
+
+	cs := gs.Channel(false, channelID)
+	if cs == nil {
+		return nil, "", errors.New("Unknown channel, specify a channel, category ID or `server`")
+	}
+
+	if cs.Type == discordgo.ChannelTypeGuildCategory {
+		for _, v := range gs.Channels {
+			if v.ParentID == cs.ID && isText(v) {
+				channels = append(channels, v.ID)
+			}
+		}
+
+		return channels, "category **" + cs.Name + "**", nil
+	}
+
+	if !isText(cs) {
+		return nil, "", errors.New("Only text channels can be locked down")
+	}
+
+	return []int64{cs.ID}, fmt.Sprintf("<#%d>", cs.ID), nil
+}
+
+// LockdownChannels denies the perms for @everyone in the channels, storing the previous overwrites.
+// Channels that are already locked down are skipped. Returns the number of channels locked
+func LockdownChannels(config *Config, gs *dstate.GuildState, author *discordgo.User, channels []int64, perms int, duration time.Duration, targetDescription, reason string) (int, error) {
+	if len(channels) < 1 {
+		return 0, ErrNoLockdownTargets
+	}
+
+	var alreadyLocked []int64
+	err := common.GORM.Model(&LockdownModel{}).Where("guild_id = ? AND channel_id IN (?)", gs.ID, channels).Pluck("channel_id", &alreadyLocked).Error
+	if err != nil {
+		return 0, errors.WithStackIf(err)
+	}
+
+	lockdownID, err := common.GenLocalIncrIDPQ(nil, gs.ID, "moderation_lockdowns")
+	if err != nil {
+		return 0, err
+	}
+
+	locked := 0
+	for _, channelID := range channels {
+		if common.ContainsInt64Slice(alreadyLocked, channelID) {
+			continue
+		}
+
+		gs.RLock()
+		cs := gs.Channel(false, channelID)
+		var prev *discordgo.PermissionOverwrite
+		if cs != nil {
+			for _, v := range cs.PermissionOverwrites {
+				if v.Type == "role" && v.ID == gs.ID {
+					cop := *v
+					prev = &cop
+					break
+				}
+			}
+		}
+		gs.RUnlock()
+
+		if cs == nil {
+			continue
+		}
+
+		model := &LockdownModel{
+			GuildID:    gs.ID,
+			ChannelID:  channelID,
+			LockdownID: lockdownID,
+			LockedByID: author.ID,
+			Reason:     reason,
+		}
+
+		allow, deny := 0, perms
+		if prev != nil {
+			model.HadOverwrite = true
+			model.PrevAllow = prev.Allow
+			model.PrevDeny = prev.Deny
+
+			allow = prev.Allow &^ perms
+			deny = prev.Deny | perms
+		}
+
+		err = common.GORM.Create(model).Error
+		if err != nil {
+			return locked, errors.WithStackIf(err)
+		}
+
+		err = common.BotSession.ChannelPermissionSet(channelID, gs.ID, "role", allow, deny)
+		if err != nil {
+			common.GORM.Delete(model)
+			if common.IsDiscordErr(err, discordgo.ErrCodeMissingPermissions, discordgo.ErrCodeMissingAccess) {
+				continue
+			}
+			return locked, err
+		}
+
+		locked++
+	}
+
+	if locked < 1 {
+		return 0, nil
+	}
+
+	if duration > 0 {
+		err = scheduledevents2.ScheduleEvent("moderation_lockdown_expired", gs.ID, time.Now().Add(duration), &ScheduledLockdownExpiredData{
+			LockdownID: lockdownID,
+		})
+		if err != nil {
+			return locked, errors.WithMessage(err, "lockdown,sched_unlock")
+		}
+	}
+
+	action := MALockdown
+	if duration > 0 {
+		action.Footer = "Expires after: " + common.HumanizeDuration(common.DurationPrecisionMinutes, duration)
+		action.Duration = duration
+	}
+
+	err = createChannelModlogEmbed(config, author, action, fmt.Sprintf("%s (%d channels)", targetDescription, locked), reason)
+	return locked, err
+}
+
+// UnlockChannels restores the overwrites of the locked down channels, use lockdownID 0 to unlock regardless of which lockdown locked them.
+// Returns the number of channels unlocked
+func UnlockChannels(config *Config, guildID int64, author *discordgo.User, channels []int64, lockdownID int64, targetDescription, reason string) (int, error) {
+	query := common.GORM.Where("guild_id = ?", guildID)
+	if channels != nil {
+		query = query.Where("channel_id IN (?)", channels)
+	}
+	if lockdownID != 0 {
+		query = query.Where("lockdown_id = ?", lockdownID)
+	}
+
+	var lockdowns []*LockdownModel
+	err := query.Find(&lockdowns).Error
+	if err != nil {
+		return 0, errors.WithStackIf(err)
+	}
+
+	if len(lockdowns) < 1 {
+		return 0, ErrNotLockedDown
+	}
+
+	unlocked := 0
+	for _, v := range lockdowns {
+		if v.HadOverwrite {
+			err = common.BotSession.ChannelPermissionSet(v.ChannelID, guildID, "role", v.PrevAllow, v.PrevDeny)
+		} else {
+			err = common.BotSession.ChannelPermissionDelete(v.ChannelID, guildID)
+		}
+
+		if err != nil && !common.IsDiscordErr(err, discordgo.ErrCodeUnknownChannel) {
+			logger.WithError(err).WithField("guild", guildID).WithField("channel", v.ChannelID).Error("failed restoring lockdown overwrite")
+			continue
+		}
+
+		err = common.GORM.Delete(v).Error
+		if err != nil {
+			return unlocked, errors.WithStackIf(err)
+		}
+
+		unlocked++
+	}
+
+	if unlocked < 1 {
+		return 0, nil
+	}
+
+	err = createChannelModlogEmbed(config, author, MAUnlock, fmt.Sprintf("%s (%d channels)", targetDescription, unlocked), reason)
+	return unlocked, err
+}
+
+func handleScheduledLockdownExpired(evt *seventsmodels.ScheduledEvent, data interface{}) (retry bool, err error) {
+	lockdownData := data.(*ScheduledLockdownExpiredData)
+
+	config, err := GetConfig(evt.GuildID)
+	if err != nil {
+		return true, errors.WithStackIf(err)
+	}
+
+	_, err = UnlockChannels(config, evt.GuildID, common.BotUser, nil, lockdownData.LockdownID, "lockdown", "Lockdown Duration Expired")
+	if err == ErrNotLockedDown {
+		// already unlocked manually
+		return false, nil
+	}
+
+	return scheduledevents2.CheckDiscordErrRetry(err), err
+}
@@ -0,0 +1,401 @@
+package moderation
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/dstate/v2"
+	"github.com/jonas747/yagpdb/bot"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/mediocregopher/radix/v3"
+)
+
+const (
+	// MaxMassActionTargets is the max number of users a single massban/masskick can target
+	MaxMassActionTargets = 500
+
+	// delay between each punishment in a mass action, so we don't hammer the api
+	massActionDelay = time.Millisecond * 500
+
+	// max number of users to list in previews and modlog entries
+	massActionListLimit = 30
+)
+
+var massActionIDRegex = regexp.MustCompile(`\d{15,20}`)
+
+// MassActionFilter selects the targets of a mass action, either by explicit user ID's or by matching members in state
+type MassActionFilter struct {
+	UserIDs []int64
+
+	// members that joined within this duration
+	JoinedWithin time.Duration
+	// members whose accounts are younger than this
+	AccountYoungerThan time.Duration
+	// members whose username matches this
+	UsernameRegex *regexp.Regexp
+}
+
+// ParseMassActionUserIDs extracts all user ID's and mentions from the input
+func ParseMassActionUserIDs(input string) []int64 {
+	matches := massActionIDRegex.FindAllString(input, -1)
+
+	result := make([]int64, 0, len(matches))
+	for _, v := range matches {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || common.ContainsInt64Slice(result, parsed) {
+			continue
+		}
+
+		result = append(result, parsed)
+	}
+
+	return result
+}
+
+// HasMemberFilters returns true if any of the member matching filters are set
+func (f *MassActionFilter) HasMemberFilters() bool {
+	return f.JoinedWithin > 0 || f.AccountYoungerThan > 0 || f.UsernameRegex != nil
+}
+
+func (f *MassActionFilter) matchMember(ms *dstate.MemberState, now time.Time) bool {
+	if f.JoinedWithin > 0 && (!ms.MemberSet || ms.JoinedAt.IsZero() || now.Sub(ms.JoinedAt) > f.JoinedWithin) {
+		return false
+	}
+
+	if f.AccountYoungerThan > 0 && now.Sub(bot.SnowflakeToTime(ms.ID)) > f.AccountYoungerThan {
+		return false
+	}
+
+	if f.UsernameRegex != nil && !f.UsernameRegex.MatchString(ms.Username) {
+		return false
+	}
+
+	return true
+}
+
+// FindMassActionTargets returns the users matched by the filter that author is allowed to punish, sorted by ID.
+// If membersOnly is set, user ID's of users not in the server are left out (you can't kick someone who isn't there)
+func FindMassActionTargets(gs *dstate.GuildState, author *dstate.MemberState, filter *MassActionFilter, membersOnly bool) (targets []*discordgo.User, skipped int) {
+	canTarget := func(ms *dstate.MemberState) bool {
+		if ms.ID == author.ID || ms.ID == common.BotUser.ID {
+			return false
+		}
+
+		return bot.IsMemberAbove(gs, author, ms)
+	}
+
+	if len(filter.UserIDs) > 0 {
+		// members missing from state are fetched, otherwise they would skip the rank check
+		lookups := lookupMassActionMembers(gs.ID, filter.UserIDs)
+
+		gs.RLock()
+		for _, v := range lookups {
+			if v.ms == nil {
+				// only users the api says are not in the server are punished without a rank check
+				if membersOnly || !common.IsDiscordErr(v.err, discordgo.ErrCodeUnknownMember) {
+					skipped++
+					continue
+				}
+
+				targets = append(targets, &discordgo.User{
+					ID:            v.id,
+					Username:      "unknown",
+					Discriminator: "????",
+				})
+				continue
+			}
+
+			if !canTarget(v.ms) {
+				skipped++
+				continue
+			}
+
+			targets = append(targets, v.ms.DGoUser())
+		}
+		gs.RUnlock()
+	} else if filter.HasMemberFilters() {
+		gs.RLock()
+		now := time.Now()
+		for _, ms := range gs.Members {
+			if !filter.matchMember(ms, now) {
+				continue
+			}
+
+			if !canTarget(ms) {
+				skipped++
+				continue
+			}
+
+			targets = append(targets, ms.DGoUser())
+		}
+		gs.RUnlock()
+	}
+
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].ID < targets[j].ID
+	})
+
+	return targets, skipped
+}
+
+type massActionLookup struct {
+	id  int64
+	ms  *dstate.MemberState
+	err error
+}
+
+// lookupMassActionMembers looks up the members through the member fetcher, keeping the errors
+// so users that are not in the server can be told apart from failed lookups
+func lookupMassActionMembers(guildID int64, userIDs []int64) []*massActionLookup {
+	resultChan := make(chan *massActionLookup)
+	for _, v := range userIDs {
+		go func(id int64) {
+			ms, err := bot.GetMember(guildID, id)
+			resultChan <- &massActionLookup{id: id, ms: ms, err: err}
+		}(v)
+	}
+
+	result := make([]*massActionLookup, 0, len(userIDs))
+	for i := 0; i < len(userIDs); i++ {
+		result = append(result, <-resultChan)
+	}
+
+	return result
+}
+
+// MassActionPreview creates a description of who would be affected by the mass action
+func MassActionPreview(action ModlogAction, targets []*discordgo.User, skipped int) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "**Dry run:** this would have %s %s **%d** user(s)", action.Emoji, strings.ToLower(action.Prefix), len(targets))
+	if skipped > 0 {
+		fmt.Fprintf(&builder, " (skipped %d you can't target)", skipped)
+	}
+	builder.WriteString(":\n")
+	builder.WriteString(massActionUserList(targets))
+	builder.WriteString("\nRun the command again with `-confirm` to go through with it.")
+
+	return builder.String()
+}
+
+func massActionUserList(targets []*discordgo.User) string {
+	var builder strings.Builder
+	for i, v := range targets {
+		if i >= massActionListLimit {
+			fmt.Fprintf(&builder, "...and %d more\n", len(targets)-i)
+			break
+		}
+
+		if v.Discriminator == "????" {
+			fmt.Fprintf(&builder, "`%d`\n", v.ID)
+		} else {
+			fmt.Fprintf(&builder, "`%s#%s` (%d)\n", v.Username, v.Discriminator, v.ID)
+		}
+	}
+
+	return builder.String()
+}
+
+var (
+	// guilds with a mass action running, only one can run at a time per guild
+	runningMassActions   = make(map[int64]bool)
+	runningMassActionsMU sync.Mutex
+)
+
+// lockMassAction returns false if there's already a mass action running on the guild
+func lockMassAction(guildID int64) bool {
+	runningMassActionsMU.Lock()
+	defer runningMassActionsMU.Unlock()
+
+	if runningMassActions[guildID] {
+		return false
+	}
+
+	runningMassActions[guildID] = true
+	return true
+}
+
+func unlockMassAction(guildID int64) {
+	runningMassActionsMU.Lock()
+	delete(runningMassActions, guildID)
+	runningMassActionsMU.Unlock()
+}
+
+// massActionProgress keeps a message in the channel up to date with how far along a mass action is
+type massActionProgress struct {
+	channelID  int64
+	messageID  int64
+	action     ModlogAction
+	total      int
+	lastUpdate time.Time
+}
+
+func newMassActionProgress(channelID int64, action ModlogAction, total int) *massActionProgress {
+	p := &massActionProgress{
+		channelID:  channelID,
+		action:     action,
+		total:      total,
+		lastUpdate: time.Now(),
+	}
+
+	m, err := common.BotSession.ChannelMessageSend(channelID, fmt.Sprintf("%s %s %d user(s), this will take a while...", action.Emoji, action.Prefix, total))
+	if err == nil {
+		p.messageID = m.ID
+	}
+
+	return p
+}
+
+func (p *massActionProgress) update(done, failed int) {
+	if p.messageID == 0 || time.Since(p.lastUpdate) < time.Second*5 {
+		return
+	}
+	p.lastUpdate = time.Now()
+
+	content := fmt.Sprintf("%s %s... %d/%d user(s) done", p.action.Emoji, p.action.Prefix, done, p.total)
+	if failed > 0 {
+		content += fmt.Sprintf(", failed on %d", failed)
+	}
+
+	common.BotSession.ChannelMessageEdit(p.channelID, p.messageID, content)
+}
+
+func (p *massActionProgress) finish(punished, failed int, err error) {
+	content := fmt.Sprintf("%s %s **%d** user(s)", p.action.Emoji, p.action.Prefix, punished)
+	if failed > 0 {
+		content += fmt.Sprintf(", failed on %d", failed)
+	}
+	if err != nil {
+		content += ", but something went wrong creating the modlog entry"
+	}
+
+	if p.messageID != 0 {
+		_, editErr := common.BotSession.ChannelMessageEdit(p.channelID, p.messageID, content)
+		if editErr == nil {
+			return
+		}
+	}
+
+	common.BotSession.ChannelMessageSend(p.channelID, content)
+}
+
+// MassPunish kicks or bans all the targets, rate limited, creating a case for each and a single aggregated modlog entry.
+// This can take a long time, progress is called after each target with how many have been done so far.
+func MassPunish(config *Config, p Punishment, guildID int64, author *discordgo.User, reason string, targets []*discordgo.User, duration time.Duration, deleteMessageDays int, progress func(done, failed int)) (punished []*discordgo.User, failed int, err error) {
+	config, err = getConfigIfNotSet(guildID, config)
+	if err != nil {
+		return nil, 0, common.ErrWithCaller(err)
+	}
+
+	if deleteMessageDays > 7 {
+		deleteMessageDays = 7
+	}
+	if deleteMessageDays < 0 {
+		deleteMessageDays = 0
+	}
+
+	var action ModlogAction
+	cases := make([]*CaseModel, 0, len(targets))
+	for i, target := range targets {
+		if i != 0 {
+			time.Sleep(massActionDelay)
+			if progress != nil {
+				progress(i, failed)
+			}
+		}
+
+		if p == PunishmentBan {
+			// Mark the user as already logged so the ban event handler doesn't create a modlog entry for each
+			common.RedisPool.Do(radix.Cmd(nil, "SETEX", RedisKeyBannedUser(guildID, target.ID), "60", "1"))
+		}
+
+		var resolved *discordgo.User
+		var punishErr error
+		action, resolved, _, punishErr = executePunishment(config, p, guildID, nil, nil, author, reason, target, duration, deleteMessageDays)
+		if punishErr != nil {
+			logger.WithError(punishErr).WithField("guild", guildID).WithField("user", target.ID).Error("failed mass punishing user")
+			failed++
+			continue
+		}
+
+		if p == PunishmentBan {
+			schedErr := scheduleUnban(guildID, resolved.ID, duration)
+			common.LogIgnoreError(schedErr, "[moderation] failed scheduling mass unban", nil)
+		}
+
+		punished = append(punished, resolved)
+
+		modCase, caseErr := CreateCase(guildID, author, action, resolved, reason, "")
+		if caseErr != nil {
+			logger.WithError(caseErr).WithField("guild", guildID).Error("failed creating case")
+			continue
+		}
+		cases = append(cases, modCase)
+	}
+
+	if len(punished) > 0 {
+		err = createMassModlogEmbed(config, author, action, punished, cases, reason)
+	}
+
+	return punished, failed, err
+}
+
+// createMassModlogEmbed posts a single modlog entry covering all the users punished in a mass action
+func createMassModlogEmbed(config *Config, author *discordgo.User, action ModlogAction, targets []*discordgo.User, cases []*CaseModel, reason string) error {
+	if reason == "" {
+		reason = "(no reason specified)"
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Author: &discordgo.MessageEmbedAuthor{
+			Name:    fmt.Sprintf("%s#%s (ID %d)", author.Username, author.Discriminator, author.ID),
+			IconURL: discordgo.EndpointUserAvatar(author.ID, author.Avatar),
+		},
+		Color: action.Color,
+		Description: fmt.Sprintf("**%sMass %s %d users**\n%s📄**Reason:** %s",
+			action.Emoji, strings.ToLower(action.Prefix), len(targets), massActionUserList(targets), reason),
+	}
+
+	footer := action.Footer
+	if len(cases) > 0 {
+		footer = fmt.Sprintf("Cases #%d-#%d", cases[0].CaseNumber, cases[len(cases)-1].CaseNumber)
+		if action.Footer != "" {
+			footer += " | " + action.Footer
+		}
+	}
+
+	if footer != "" {
+		embed.Footer = &discordgo.MessageEmbedFooter{
+			Text: footer,
+		}
+	}
+
+	m, err := sendModlogEmbed(config, action, embed)
+	if err != nil || m == nil {
+		return err
+	}
+
+	if len(cases) < 1 {
+		return nil
+	}
+
+	caseIDs := make([]uint, 0, len(cases))
+	for _, v := range cases {
+		caseIDs = append(caseIDs, v.ID)
+	}
+
+	err = common.GORM.Model(&CaseModel{}).Where("id IN (?)", caseIDs).Updates(map[string]interface{}{
+		"modlog_channel_id": m.ChannelID,
+		"modlog_message_id": m.ID,
+	}).Error
+	if err != nil {
+		logger.WithError(err).WithField("guild", config.GetGuildID()).Error("failed updating mass action cases modlog message")
+	}
+
+	return nil
+}
@@ -29,6 +29,8 @@ type Config struct {
 	BanReasonOptional 	bool
 	BanMessage        	string `valid:"template,5000"`
 	DefaultBanDeleteDays    sql.NullInt64 `gorm:"default:1" valid:"0,7"`
+	BanAppealsEnabled       bool
+	BanAppealChannel        string `valid:"channel,true"`
 
 	// Mute/unmute
 	MuteEnabled             bool
@@ -44,6 +46,15 @@ type Config struct {
 	UnmuteMessage           string        `valid:"template,5000"`
 	DefaultMuteDuration     sql.NullInt64 `gorm:"default:10"`
 
+	// Timeout
+	TimeoutEnabled              bool
+	TimeoutCmdRoles             pq.Int64Array `gorm:"type:bigint[]" valid:"role,true"`
+	TimeoutReasonOptional       bool
+	TimeoutRemoveReasonOptional bool
+	TimeoutMessage              string        `valid:"template,5000"`
+	DefaultTimeoutDuration      sql.NullInt64 `gorm:"default:10" valid:"1,40320"`
+	MaxTimeoutDuration          sql.NullInt64 `gorm:"default:40320" valid:"1,40320"`
+
 	// Warn
 	WarnCommandsEnabled    bool
 	WarnCmdRoles           pq.Int64Array `gorm:"type:bigint[]" valid:"role,true"`
@@ -51,14 +62,45 @@ type Config struct {
 	WarnSendToModlog       bool
 	WarnMessage            string `valid:"template,5000"`
 
+	// Warn escalation, the i'th threshold is made up of the i'th element of each array
+	WarnEscalationEnabled   bool
+	WarnEscalationPeriod    sql.NullInt64  `gorm:"default:30" valid:"1,365"`
+	WarnEscalationCounts    pq.Int64Array  `gorm:"type:bigint[]"`
+	WarnEscalationActions   pq.StringArray `gorm:"type:text[]"`
+	WarnEscalationDurations pq.Int64Array  `gorm:"type:bigint[]"`
+
 	// Misc
 	CleanEnabled  bool
 	ReportEnabled bool
 	ActionChannel string `valid:"channel,true"`
 	ReportChannel string `valid:"channel,true"`
+
+	// Per action type modlog channels, ActionChannel is used for the ones not set
+	ModlogBanChannel      string `valid:"channel,true"`
+	ModlogKickChannel     string `valid:"channel,true"`
+	ModlogMuteChannel     string `valid:"channel,true"`
+	ModlogTimeoutChannel  string `valid:"channel,true"`
+	ModlogWarnChannel     string `valid:"channel,true"`
+	ModlogRoleChannel     string `valid:"channel,true"`
+	ModlogLockdownChannel string `valid:"channel,true"`
+
+	// Per action type modlog message templates, the default embed is used for the ones not set
+	ModlogBanTemplate     string `valid:"template,2000"`
+	ModlogKickTemplate    string `valid:"template,2000"`
+	ModlogMuteTemplate    string `valid:"template,2000"`
+	ModlogTimeoutTemplate string `valid:"template,2000"`
+	ModlogWarnTemplate    string `valid:"template,2000"`
+	ModlogRoleTemplate    string `valid:"template,2000"`
+
+	ReportAnonymousEnabled bool
+	ReportStaffRoles       pq.Int64Array `gorm:"type:bigint[]" valid:"role,true"`
+
 	LogUnbans     bool
 	LogBans       bool
 
+	LockdownEnabled  bool
+	LockdownCmdRoles pq.Int64Array `gorm:"type:bigint[]" valid:"role,true"`
+
 	GiveRoleCmdEnabled bool
 	GiveRoleCmdModlog  bool
 	GiveRoleCmdRoles   pq.Int64Array `gorm:"type:bigint[]" valid:"role,true"`
@@ -79,6 +121,11 @@ func (c *Config) IntReportChannel() (r int64) {
 	return
 }
 
+func (c *Config) IntBanAppealChannel() (r int64) {
+	r, _ = strconv.ParseInt(c.BanAppealChannel, 10, 64)
+	return
+}
+
 func (c *Config) GetName() string {
 	return "moderation"
 }
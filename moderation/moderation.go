@@ -16,6 +16,7 @@ const (
 	ActionBanned   = "Banned"
 	ActionUnbanned = "Unbanned"
 	ActionWarned   = "Warned"
+	ActionTimedOut = "Timed out"
 )
 
 var logger = common.GetPluginLogger(&Plugin{})
@@ -52,7 +53,7 @@ func RegisterPlugin() {
 	common.RegisterPlugin(plugin)
 
 	configstore.RegisterConfig(configstore.SQL, &Config{})
-	common.GORM.AutoMigrate(&Config{}, &WarningModel{}, &MuteModel{})
+	common.GORM.AutoMigrate(&Config{}, &WarningModel{}, &MuteModel{}, &CaseModel{}, &ReportModel{}, &ReportReasonModel{}, &LockdownModel{}, &BanAppealModel{})
 }
 
 func getConfigIfNotSet(guildID int64, config *Config) (*Config, error) {
@@ -72,6 +73,7 @@ func GetConfig(guildID int64) (*Config, error) {
 	err := configstore.Cached.GetGuildConfig(context.Background(), guildID, &config)
 	if err == configstore.ErrNotFound {
 		err = nil
+		config.GuildID = guildID
 	}
 	return &config, err
 }
@@ -3,10 +3,25 @@ package moderation
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jonas747/discordgo"
+	"github.com/jonas747/yagpdb/bot"
 	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/common/templates"
+)
+
+// Modlog categories, each can be routed to its own channel and have its own message template
+const (
+	ModlogCategoryBan      = "ban"
+	ModlogCategoryKick     = "kick"
+	ModlogCategoryMute     = "mute"
+	ModlogCategoryTimeout  = "timeout"
+	ModlogCategoryWarn     = "warn"
+	ModlogCategoryRole     = "role"
+	ModlogCategoryLockdown = "lockdown"
 )
 
 type ModlogAction struct {
@@ -14,7 +29,12 @@ type ModlogAction struct {
 	Emoji  string
 	Color  int
 
+	Category string
+
 	Footer string
+
+	// Duration of the punishment, stored in the case
+	Duration time.Duration
 }
 
 func (m ModlogAction) String() string {
@@ -27,19 +47,30 @@ func (m ModlogAction) String() string {
 }
 
 var (
-	MAMute       = ModlogAction{Prefix: "Muted", Emoji: "🔇", Color: 0x57728e}
-	MAUnmute     = ModlogAction{Prefix: "Unmuted", Emoji: "🔊", Color: 0x62c65f}
-	MAKick       = ModlogAction{Prefix: "Kicked", Emoji: "👢", Color: 0xf2a013}
-	MABanned     = ModlogAction{Prefix: "Banned", Emoji: "🔨", Color: 0xd64848}
-	MAUnbanned   = ModlogAction{Prefix: "Unbanned", Emoji: "🔓", Color: 0x62c65f}
-	MAWarned     = ModlogAction{Prefix: "Warned", Emoji: "⚠", Color: 0xfca253}
-	MAGiveRole   = ModlogAction{Prefix: "", Emoji: "➕", Color: 0x53fcf9}
-	MARemoveRole = ModlogAction{Prefix: "", Emoji: "➖", Color: 0x53fcf9}
+	MAMute       = ModlogAction{Prefix: "Muted", Emoji: "🔇", Color: 0x57728e, Category: ModlogCategoryMute}
+	MAUnmute     = ModlogAction{Prefix: "Unmuted", Emoji: "🔊", Color: 0x62c65f, Category: ModlogCategoryMute}
+	MAKick       = ModlogAction{Prefix: "Kicked", Emoji: "👢", Color: 0xf2a013, Category: ModlogCategoryKick}
+	MABanned     = ModlogAction{Prefix: "Banned", Emoji: "🔨", Color: 0xd64848, Category: ModlogCategoryBan}
+	MAUnbanned   = ModlogAction{Prefix: "Unbanned", Emoji: "🔓", Color: 0x62c65f, Category: ModlogCategoryBan}
+	MAWarned     = ModlogAction{Prefix: "Warned", Emoji: "⚠", Color: 0xfca253, Category: ModlogCategoryWarn}
+	MAGiveRole   = ModlogAction{Prefix: "", Emoji: "➕", Color: 0x53fcf9, Category: ModlogCategoryRole}
+	MARemoveRole = ModlogAction{Prefix: "", Emoji: "➖", Color: 0x53fcf9, Category: ModlogCategoryRole}
+
+	MATimeoutAdded   = ModlogAction{Prefix: "Timed out", Emoji: "⏱", Color: 0x9b59b6, Category: ModlogCategoryTimeout}
+	MATimeoutRemoved = ModlogAction{Prefix: "Removed timeout from", Emoji: "⏱", Color: 0x62c65f, Category: ModlogCategoryTimeout}
+
+	MALockdown = ModlogAction{Prefix: "Locked down", Emoji: "🔒", Color: 0xd64848, Category: ModlogCategoryLockdown}
+	MAUnlock   = ModlogAction{Prefix: "Unlocked", Emoji: "🔓", Color: 0x62c65f, Category: ModlogCategoryLockdown}
 )
 
 func CreateModlogEmbed(config *Config, author *discordgo.User, action ModlogAction, target *discordgo.User, reason, logLink string) error {
-	channelID := config.IntActionChannel()
-	config.GetGuildID()
+	channelID := config.ModlogChannel(action)
+
+	modCase, err := CreateCase(config.GetGuildID(), author, action, target, reason, logLink)
+	if err != nil {
+		logger.WithError(err).WithField("guild", config.GetGuildID()).Error("failed creating case")
+	}
+
 	if channelID == 0 {
 		return nil
 	}
@@ -58,45 +89,235 @@ func CreateModlogEmbed(config *Config, author *discordgo.User, action ModlogActi
 		reason = "(no reason specified)"
 	}
 
-	embed := &discordgo.MessageEmbed{
-		Author: &discordgo.MessageEmbedAuthor{
-			Name:    fmt.Sprintf("%s#%s (ID %d)", author.Username, author.Discriminator, author.ID),
-			IconURL: discordgo.EndpointUserAvatar(author.ID, author.Avatar),
-		},
-		Thumbnail: &discordgo.MessageEmbedThumbnail{
-			URL: discordgo.EndpointUserAvatar(target.ID, target.Avatar),
-		},
-		Color: action.Color,
-		Description: fmt.Sprintf("**%s%s %s**#%s *(ID %d)*\n📄**Reason:** %s",
-			action.Emoji, action.Prefix, target.Username, target.Discriminator, target.ID, reason),
+	var m *discordgo.Message
+	if tmpl := config.ModlogTemplate(action); tmpl != "" {
+		var sent bool
+		m, sent, err = sendModlogTemplate(config, channelID, tmpl, author, action, target, reason, logLink, modCase)
+		if err != nil || !sent {
+			return err
+		}
+
+		// templated entries can't be edited by the reason command, the case itself is still updated
+		emptyAuthor = false
+	} else {
+		embed := &discordgo.MessageEmbed{
+			Author: &discordgo.MessageEmbedAuthor{
+				Name:    fmt.Sprintf("%s#%s (ID %d)", author.Username, author.Discriminator, author.ID),
+				IconURL: discordgo.EndpointUserAvatar(author.ID, author.Avatar),
+			},
+			Thumbnail: &discordgo.MessageEmbedThumbnail{
+				URL: discordgo.EndpointUserAvatar(target.ID, target.Avatar),
+			},
+			Color: action.Color,
+			Description: fmt.Sprintf("**%s%s %s**#%s *(ID %d)*\n📄**Reason:** %s",
+				action.Emoji, action.Prefix, target.Username, target.Discriminator, target.ID, reason),
+		}
+
+		if logLink != "" {
+			embed.Description += " ([Logs](" + logLink + "))"
+		}
+
+		footer := action.Footer
+		if modCase != nil {
+			footer = fmt.Sprintf("Case #%d", modCase.CaseNumber)
+			if action.Footer != "" {
+				footer += " | " + action.Footer
+			}
+		}
+
+		if footer != "" {
+			embed.Footer = &discordgo.MessageEmbedFooter{
+				Text: footer,
+			}
+		}
+
+		m, err = sendModlogMessage(config, channelID, &discordgo.MessageSend{Embed: embed})
 	}
 
-	if logLink != "" {
-		embed.Description += " ([Logs](" + logLink + "))"
+	if err != nil || m == nil {
+		return err
 	}
 
-	if action.Footer != "" {
-		embed.Footer = &discordgo.MessageEmbedFooter{
-			Text: action.Footer,
+	if modCase != nil {
+		modCase.ModlogChannelID = channelID
+		modCase.ModlogMessageID = m.ID
+		err := common.GORM.Model(modCase).Updates(map[string]interface{}{
+			"modlog_channel_id": channelID,
+			"modlog_message_id": m.ID,
+		}).Error
+		if err != nil {
+			logger.WithError(err).WithField("guild", config.GetGuildID()).Error("failed updating case modlog message")
+		}
+	}
+
+	if emptyAuthor && len(m.Embeds) > 0 {
+		embed := m.Embeds[0]
+		placeholder := fmt.Sprintf("Asssign an author and reason to this using **'reason %d your-reason-here`**", m.ID)
+		if modCase != nil {
+			placeholder = fmt.Sprintf("Asssign an author and reason to this using **'reason %d your-reason-here`**", modCase.CaseNumber)
 		}
+		updateEmbedReason(nil, placeholder, embed)
+		_, err = common.BotSession.ChannelMessageEditEmbed(channelID, m.ID, embed)
+	}
+	return err
+}
+
+// sendModlogTemplate executes the custom modlog template of the action and sends the output,
+// sent is false if the template produced no output, in which case nothing is logged in the channel
+func sendModlogTemplate(config *Config, channelID int64, tmpl string, author *discordgo.User, action ModlogAction, target *discordgo.User, reason, logLink string, modCase *CaseModel) (m *discordgo.Message, sent bool, err error) {
+	gs := bot.State.Guild(true, config.GetGuildID())
+	if gs == nil {
+		return nil, false, nil
+	}
+
+	ctx := templates.NewContext(gs, gs.Channel(true, channelID), nil)
+	ctx.Name = "modlog_" + action.Category
+	ctx.Data["User"] = target
+	ctx.Data["Author"] = author
+	ctx.Data["ModAction"] = action
+	ctx.Data["Reason"] = reason
+	ctx.Data["LogLink"] = logLink
+	ctx.Data["Duration"] = action.Duration
+	ctx.Data["HumanDuration"] = common.HumanizeDuration(common.DurationPrecisionMinutes, action.Duration)
+	if action.Duration < 1 {
+		ctx.Data["HumanDuration"] = "permanently"
+	}
+
+	ctx.Data["CaseNumber"] = 0
+	if modCase != nil {
+		ctx.Data["CaseNumber"] = modCase.CaseNumber
+	}
+
+	out, err := ctx.Execute(tmpl)
+	if err != nil {
+		logger.WithError(err).WithField("guild", config.GetGuildID()).Warn("Failed executing modlog template")
+		out = fmt.Sprintf("%s%s %s#%s (ID %d): %s\n(Failed executing the modlog template: `%s`)", action.Emoji, action.Prefix, target.Username, target.Discriminator, target.ID, reason, err.Error())
+	}
+
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return nil, false, nil
 	}
 
-	m, err := common.BotSession.ChannelMessageSendEmbed(channelID, embed)
+	m, err = sendModlogMessage(config, channelID, ctx.MessageSend(common.CutStringShort(out, 2000)))
+	return m, m != nil, err
+}
+
+// sendModlogMessage sends the message to the modlog channel, disabling the channel in the config if the bot can't send to it.
+// Returns a nil message if it was disabled
+func sendModlogMessage(config *Config, channelID int64, msg *discordgo.MessageSend) (*discordgo.Message, error) {
+	m, err := common.BotSession.ChannelMessageSendComplex(channelID, msg)
 	if err != nil {
 		if common.IsDiscordErr(err, discordgo.ErrCodeMissingAccess, discordgo.ErrCodeMissingPermissions, discordgo.ErrCodeUnknownChannel) {
-			// disable the modlog
-			config.ActionChannel = ""
-			config.Save(config.GetGuildID())
-			return nil
+			config.disableModlogChannel(channelID)
+			return nil, nil
 		}
-		return err
+		return nil, err
 	}
 
-	if emptyAuthor {
-		placeholder := fmt.Sprintf("Asssign an author and reason to this using **'reason %d your-reason-here`**", m.ID)
-		updateEmbedReason(nil, placeholder, embed)
-		_, err = common.BotSession.ChannelMessageEditEmbed(channelID, m.ID, embed)
+	return m, nil
+}
+
+// sendModlogEmbed sends the embed to the modlog channel of the action.
+// Returns a nil message if there's no modlog channel
+func sendModlogEmbed(config *Config, action ModlogAction, embed *discordgo.MessageEmbed) (*discordgo.Message, error) {
+	channelID := config.ModlogChannel(action)
+	if channelID == 0 {
+		return nil, nil
+	}
+
+	return sendModlogMessage(config, channelID, &discordgo.MessageSend{Embed: embed})
+}
+
+// ModlogChannel returns the channel entries for the action are sent to, falling back to the action channel
+func (c *Config) ModlogChannel(action ModlogAction) int64 {
+	if field := c.modlogChannelField(action.Category); field != nil && *field != "" {
+		r, _ := strconv.ParseInt(*field, 10, 64)
+		return r
+	}
+
+	return c.IntActionChannel()
+}
+
+// ModlogTemplate returns the custom modlog message template for the action, or an empty string to use the default embed
+func (c *Config) ModlogTemplate(action ModlogAction) string {
+	switch action.Category {
+	case ModlogCategoryBan:
+		return c.ModlogBanTemplate
+	case ModlogCategoryKick:
+		return c.ModlogKickTemplate
+	case ModlogCategoryMute:
+		return c.ModlogMuteTemplate
+	case ModlogCategoryTimeout:
+		return c.ModlogTimeoutTemplate
+	case ModlogCategoryWarn:
+		return c.ModlogWarnTemplate
+	case ModlogCategoryRole:
+		return c.ModlogRoleTemplate
+	}
+
+	return ""
+}
+
+func (c *Config) modlogChannelField(category string) *string {
+	switch category {
+	case ModlogCategoryBan:
+		return &c.ModlogBanChannel
+	case ModlogCategoryKick:
+		return &c.ModlogKickChannel
+	case ModlogCategoryMute:
+		return &c.ModlogMuteChannel
+	case ModlogCategoryTimeout:
+		return &c.ModlogTimeoutChannel
+	case ModlogCategoryWarn:
+		return &c.ModlogWarnChannel
+	case ModlogCategoryRole:
+		return &c.ModlogRoleChannel
+	case ModlogCategoryLockdown:
+		return &c.ModlogLockdownChannel
+	}
+
+	return nil
+}
+
+// disableModlogChannel removes the channel from the modlog config, used when the bot no longer has access to it
+func (c *Config) disableModlogChannel(channelID int64) {
+	strID := discordgo.StrID(channelID)
+	if c.ActionChannel == strID {
+		c.ActionChannel = ""
 	}
+
+	for _, category := range []string{ModlogCategoryBan, ModlogCategoryKick, ModlogCategoryMute, ModlogCategoryTimeout, ModlogCategoryWarn, ModlogCategoryRole, ModlogCategoryLockdown} {
+		if field := c.modlogChannelField(category); *field == strID {
+			*field = ""
+		}
+	}
+
+	c.Save(c.GetGuildID())
+}
+
+// createChannelModlogEmbed creates a modlog entry for actions on channels rather than users, such as lockdowns
+func createChannelModlogEmbed(config *Config, author *discordgo.User, action ModlogAction, target, reason string) error {
+	if reason == "" {
+		reason = "(no reason specified)"
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Author: &discordgo.MessageEmbedAuthor{
+			Name:    fmt.Sprintf("%s#%s (ID %d)", author.Username, author.Discriminator, author.ID),
+			IconURL: discordgo.EndpointUserAvatar(author.ID, author.Avatar),
+		},
+		Color:       action.Color,
+		Description: fmt.Sprintf("**%s%s %s**\n📄**Reason:** %s", action.Emoji, action.Prefix, target, reason),
+	}
+
+	if action.Footer != "" {
+		embed.Footer = &discordgo.MessageEmbedFooter{
+			Text: action.Footer,
+		}
+	}
+
+	_, err := sendModlogEmbed(config, action, embed)
 	return err
 }
 
@@ -108,6 +329,9 @@ func updateEmbedReason(author *discordgo.User, reason string, embed *discordgo.M
 	const checkStr = "📄**Reason:**"
 
 	index := strings.Index(embed.Description, checkStr)
+	if index == -1 {
+		return
+	}
 	withoutReason := embed.Description[:index+len(checkStr)]
 
 	logsLink := logsRegex.FindString(embed.Description)
@@ -47,6 +47,9 @@ func (p *Plugin) BotInit() {
 	// scheduledevents.RegisterEventHandler("mod_unban", handleUnbanLegacy)
 	scheduledevents2.RegisterHandler("moderation_unmute", ScheduledUnmuteData{}, handleScheduledUnmute)
 	scheduledevents2.RegisterHandler("moderation_unban", ScheduledUnbanData{}, handleScheduledUnban)
+	scheduledevents2.RegisterHandler("moderation_timeout_expired", ScheduledTimeoutExpiredData{}, handleScheduledTimeoutExpired)
+	scheduledevents2.RegisterHandler("moderation_lockdown_expired", ScheduledLockdownExpiredData{}, handleScheduledLockdownExpired)
+	scheduledevents2.RegisterHandler("moderation_ban_appeal_submitted", ScheduledBanAppealSubmittedData{}, handleScheduledBanAppealSubmitted)
 	scheduledevents2.RegisterLegacyMigrater("unmute", handleMigrateScheduledUnmute)
 	scheduledevents2.RegisterLegacyMigrater("mod_unban", handleMigrateScheduledUnban)
 
@@ -57,6 +60,8 @@ func (p *Plugin) BotInit() {
 
 	eventsystem.AddHandlerAsyncLastLegacy(p, bot.ConcurrentEventHandler(HandleGuildCreate), eventsystem.EventGuildCreate)
 	eventsystem.AddHandlerAsyncLast(p, HandleChannelCreateUpdate, eventsystem.EventChannelCreate, eventsystem.EventChannelUpdate)
+	eventsystem.AddHandlerAsyncLastLegacy(p, handleReportReactionAdd, eventsystem.EventMessageReactionAdd)
+	eventsystem.AddHandlerAsyncLastLegacy(p, handleBanAppealReactionAdd, eventsystem.EventMessageReactionAdd)
 
 	pubsub.AddHandler("mod_refresh_mute_override", HandleRefreshMuteOverrides, nil)
 }
@@ -69,6 +74,10 @@ type ScheduledUnbanData struct {
 	UserID int64 `json:"user_id"`
 }
 
+type ScheduledTimeoutExpiredData struct {
+	UserID int64 `json:"user_id"`
+}
+
 func (p *Plugin) ShardMigrationReceive(evt dshardorchestrator.EventType, data interface{}) {
 	if evt == bot.EvtGuildState {
 		gs := data.(*dstate.GuildState)
@@ -246,6 +255,10 @@ func HandleGuildBanAddRemove(evt *eventsystem.EventData) {
 		if i > 0 {
 			// The bot was the one that performed the unban
 			common.RedisPool.Do(radix.Cmd(nil, "DEL", RedisKeyUnbannedUser(guildID, user.ID)))
+			if i == unbanKeyLogged {
+				// e.g approved ban appeals, they create their own modlog entry
+				return
+			}
 			botPerformed = true
 		}
 
@@ -259,7 +272,7 @@ func HandleGuildBanAddRemove(evt *eventsystem.EventData) {
 		return
 	}
 
-	if config.IntActionChannel() == 0 {
+	if config.ModlogChannel(action) == 0 {
 		return
 	}
 
@@ -307,7 +320,7 @@ func HandleGuildMemberRemove(evt *eventsystem.EventData) (retry bool, err error)
 		return true, errors.WithStackIf(err)
 	}
 
-	if config.IntActionChannel() == 0 {
+	if config.ModlogChannel(MAKick) == 0 {
 		return false, nil
 	}
 
@@ -535,7 +548,7 @@ func handleScheduledUnban(evt *seventsmodels.ScheduledEvent, data interface{}) (
 		return false, nil
 	}
 
-	common.RedisPool.Do(radix.FlatCmd(nil, "SETEX", RedisKeyUnbannedUser(guildID, userID), 30, 1))
+	common.RedisPool.Do(radix.FlatCmd(nil, "SETEX", RedisKeyUnbannedUser(guildID, userID), 30, unbanKeyTimedBan))
 
 	err = common.BotSession.GuildBanDelete(guildID, userID)
 	if err != nil {
@@ -545,3 +558,26 @@ func handleScheduledUnban(evt *seventsmodels.ScheduledEvent, data interface{}) (
 
 	return false, nil
 }
+
+func handleScheduledTimeoutExpired(evt *seventsmodels.ScheduledEvent, data interface{}) (retry bool, err error) {
+	timeoutData := data.(*ScheduledTimeoutExpiredData)
+
+	config, err := GetConfig(evt.GuildID)
+	if err != nil {
+		return true, errors.WithStackIf(err)
+	}
+
+	member, err := bot.GetMember(evt.GuildID, timeoutData.UserID)
+	if err != nil {
+		if common.IsDiscordErr(err, discordgo.ErrCodeUnknownMember) {
+			// left the server, nothing to clean up
+			return false, nil
+		}
+
+		return scheduledevents2.CheckDiscordErrRetry(err), err
+	}
+
+	// discord lifts the timeout on its own, but clear it anyways incase the clocks are out of sync and to log it in the modlog
+	err = RemoveTimeout(config, evt.GuildID, common.BotUser, "Timeout Duration Expired", member.DGoUser())
+	return scheduledevents2.CheckDiscordErrRetry(err), err
+}
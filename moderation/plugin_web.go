@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"html/template"
 	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/jonas747/discordgo"
 	"github.com/jonas747/yagpdb/common"
@@ -16,10 +19,14 @@ import (
 var (
 	panelLogKeyUpdatedSettings = cplogs.RegisterActionFormat(&cplogs.ActionFormat{Key: "moderation_settings_updated", FormatString: "Updated moderation config"})
 	panelLogKeyClearWarnings   = cplogs.RegisterActionFormat(&cplogs.ActionFormat{Key: "moderation_warnings_cleared", FormatString: "Cleared %d moderation user warnings"})
+	panelLogKeyCancelTempRole  = cplogs.RegisterActionFormat(&cplogs.ActionFormat{Key: "moderation_temprole_cancelled", FormatString: "Removed a temporary role from %d"})
 )
 
 func (p *Plugin) InitWeb() {
 	web.LoadHTMLTemplate("../../moderation/assets/moderation.html", "templates/plugins/moderation.html")
+	web.LoadHTMLTemplate("../../moderation/assets/moderation_history.html", "templates/plugins/moderation_history.html")
+	web.LoadHTMLTemplate("../../moderation/assets/moderation_temproles.html", "templates/plugins/moderation_temproles.html")
+	web.LoadHTMLTemplate("../../moderation/assets/moderation_appeal.html", "templates/plugins/moderation_appeal.html")
 
 	web.AddSidebarItem(web.SidebarCategoryTools, &web.SidebarItem{
 		Name: "Moderation",
@@ -37,12 +44,23 @@ func (p *Plugin) InitWeb() {
 	getHandler := web.ControllerHandler(HandleModeration, "cp_moderation")
 	postHandler := web.ControllerPostHandler(HandlePostModeration, getHandler, Config{})
 	clearServerWarnings := web.ControllerPostHandler(HandleClearServerWarnings, getHandler, nil)
+	historyHandler := web.ControllerHandler(HandleUserHistory, "cp_moderation_history")
+	tempRolesHandler := web.ControllerHandler(HandleTempRoles, "cp_moderation_temproles")
+	cancelTempRoleHandler := web.ControllerPostHandler(HandleCancelTempRole, tempRolesHandler, nil)
 
 	subMux.Handle(pat.Get(""), getHandler)
 	subMux.Handle(pat.Get("/"), getHandler)
 	subMux.Handle(pat.Post(""), postHandler)
 	subMux.Handle(pat.Post("/"), postHandler)
 	subMux.Handle(pat.Post("/clear_server_warnings"), clearServerWarnings)
+	subMux.Handle(pat.Get("/history"), historyHandler)
+	subMux.Handle(pat.Get("/temproles"), tempRolesHandler)
+	subMux.Handle(pat.Post("/temproles/cancel"), cancelTempRoleHandler)
+
+	getAppealPageHandler := web.ControllerHandler(HandleGetBanAppealPage, "moderation_appeal_page")
+	postAppealPageHandler := web.ControllerPostHandler(HandlePostBanAppealPage, getAppealPageHandler, nil)
+	web.ServerPublicMux.Handle(pat.Get("/appeal/:user_id/:token"), getAppealPageHandler)
+	web.ServerPublicMux.Handle(pat.Post("/appeal/:user_id/:token"), postAppealPageHandler)
 }
 
 // HandleModeration servers the moderation page itself
@@ -71,6 +89,9 @@ func HandlePostModeration(w http.ResponseWriter, r *http.Request) (web.TemplateD
 	newConfig := ctx.Value(common.ContextKeyParsedForm).(*Config)
 	newConfig.DefaultMuteDuration.Valid = true
 	newConfig.DefaultBanDeleteDays.Valid = true
+	newConfig.DefaultTimeoutDuration.Valid = true
+	newConfig.MaxTimeoutDuration.Valid = true
+	newConfig.WarnEscalationPeriod.Valid = true
 	templateData["ModConfig"] = newConfig
 
 	err := newConfig.Save(activeGuild.ID)
@@ -101,6 +122,209 @@ func HandleClearServerWarnings(w http.ResponseWriter, r *http.Request) (web.Temp
 	return templateData, nil
 }
 
+var _ web.CustomValidator = (*Config)(nil)
+
+// Validate validates the warn escalation thresholds, which can't be done through tags since they're spread over multiple arrays
+func (c *Config) Validate(tmpl web.TemplateData) (ok bool) {
+	if len(c.WarnEscalationCounts) > MaxWarnEscalations {
+		tmpl.AddAlerts(web.ErrorAlert("Too many warn escalation thresholds, max ", MaxWarnEscalations))
+		return false
+	}
+
+	seenCounts := make(map[int64]bool)
+	for i, count := range c.WarnEscalationCounts {
+		if count < 0 || count > 1000 {
+			tmpl.AddAlerts(web.ErrorAlert("Warn escalation threshold counts has to be between 0 and 1000"))
+			return false
+		}
+
+		action := ""
+		if i < len(c.WarnEscalationActions) {
+			action = c.WarnEscalationActions[i]
+		}
+
+		switch action {
+		case "", WarnEscalationActionMute, WarnEscalationActionTimeout, WarnEscalationActionKick, WarnEscalationActionBan:
+		default:
+			tmpl.AddAlerts(web.ErrorAlert("Unknown warn escalation action: ", action))
+			return false
+		}
+
+		if i < len(c.WarnEscalationDurations) && c.WarnEscalationDurations[i] < 0 {
+			tmpl.AddAlerts(web.ErrorAlert("Warn escalation durations can't be negative"))
+			return false
+		}
+
+		// only the first threshold with a count would ever be used
+		if count > 0 && action != "" {
+			if seenCounts[count] {
+				tmpl.AddAlerts(web.ErrorAlert("There can only be one warn escalation threshold for ", count, " warnings"))
+				return false
+			}
+			seenCounts[count] = true
+		}
+	}
+
+	return true
+}
+
+// HandleUserHistory serves the user history page
+func HandleUserHistory(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
+	ctx := r.Context()
+	activeGuild, templateData := web.GetBaseCPContextData(ctx)
+
+	userStr := strings.TrimSpace(r.URL.Query().Get("user"))
+	if userStr == "" {
+		return templateData, nil
+	}
+
+	userID, err := strconv.ParseInt(userStr, 10, 64)
+	if err != nil {
+		templateData.AddAlerts(web.ErrorAlert("Invalid user ID"))
+		return templateData, nil
+	}
+
+	templateData["HistoryUserID"] = userID
+
+	history, err := GetUserHistory(ctx, activeGuild.ID, userID)
+	if err != nil {
+		return templateData, err
+	}
+
+	templateData["History"] = history
+	return templateData, nil
+}
+
+const tempRolesPerPage = 50
+
+// HandleTempRoles serves the list of active temporary roles
+func HandleTempRoles(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
+	ctx := r.Context()
+	activeGuild, templateData := web.GetBaseCPContextData(ctx)
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	userID, _ := strconv.ParseInt(r.URL.Query().Get("user"), 10, 64)
+
+	tempRoles, total, err := GetTempRoles(ctx, activeGuild.ID, userID, tempRolesPerPage, (page-1)*tempRolesPerPage)
+	if err != nil {
+		return templateData, err
+	}
+
+	roleNames := make(map[int64]string)
+	for _, v := range activeGuild.Roles {
+		roleNames[v.ID] = v.Name
+	}
+
+	templateData["TempRoles"] = tempRoles
+	templateData["TempRolesTotal"] = total
+	templateData["RoleNames"] = roleNames
+	templateData["TempRolesUser"] = userID
+	templateData["CurrentPage"] = page
+	if page > 1 {
+		templateData["PrevPage"] = page - 1
+	}
+	if int64(page*tempRolesPerPage) < total {
+		templateData["NextPage"] = page + 1
+	}
+
+	return templateData, nil
+}
+
+// HandleCancelTempRole removes a temporary role right away
+func HandleCancelTempRole(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
+	ctx := r.Context()
+	activeGuild, templateData := web.GetBaseCPContextData(ctx)
+
+	userID, _ := strconv.ParseInt(r.FormValue("user_id"), 10, 64)
+	roleID, _ := strconv.ParseInt(r.FormValue("role_id"), 10, 64)
+	if userID == 0 || roleID == 0 {
+		return templateData.AddAlerts(web.ErrorAlert("Invalid user or role")), nil
+	}
+
+	err := CancelTempRole(ctx, activeGuild.ID, userID, roleID, true)
+	if err != nil {
+		if err == ErrTempRoleNotFound {
+			return templateData.AddAlerts(web.ErrorAlert(err.Error())), nil
+		}
+		return templateData, err
+	}
+
+	go cplogs.RetryAddEntry(web.NewLogEntryFromContext(r.Context(), panelLogKeyCancelTempRole, &cplogs.Param{Type: cplogs.ParamTypeInt, Value: userID}))
+
+	return templateData.AddAlerts(web.SucessAlert("Removed the role")), nil
+}
+
+// banAppealFromRequest returns the appeal session from the page url, adding an alert if there is none or appeals are disabled
+func banAppealFromRequest(r *http.Request, templateData web.TemplateData) (*BanAppealModel, error) {
+	activeGuild, _ := web.GetBaseCPContextData(r.Context())
+
+	config, err := GetConfig(activeGuild.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !config.BanAppealsEnabled {
+		templateData.AddAlerts(web.ErrorAlert("Ban appeals are disabled on this server"))
+		return nil, nil
+	}
+
+	userID, _ := strconv.ParseInt(pat.Param(r, "user_id"), 10, 64)
+	appeal, err := GetBanAppealSession(activeGuild.ID, userID, pat.Param(r, "token"))
+	if err != nil {
+		if err == ErrBanAppealNotFound {
+			templateData.AddAlerts(web.ErrorAlert("No ban appeal found, the link may have been replaced by a newer one"))
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return appeal, nil
+}
+
+// HandleGetBanAppealPage serves the public page where banned users submit their appeal
+func HandleGetBanAppealPage(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
+	_, templateData := web.GetBaseCPContextData(r.Context())
+
+	appeal, err := banAppealFromRequest(r, templateData)
+	if appeal != nil {
+		templateData["Appeal"] = appeal
+	}
+	templateData["MaxAppealLength"] = MaxBanAppealLength
+
+	return templateData, err
+}
+
+// HandlePostBanAppealPage submits the appeal
+func HandlePostBanAppealPage(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
+	_, templateData := web.GetBaseCPContextData(r.Context())
+
+	appeal, err := banAppealFromRequest(r, templateData)
+	if appeal == nil || err != nil {
+		return templateData, err
+	}
+
+	content := strings.TrimSpace(r.FormValue("appeal"))
+	if content == "" || utf8.RuneCountInString(content) > MaxBanAppealLength {
+		return templateData.AddAlerts(web.ErrorAlert(fmt.Sprintf("The appeal has to be between 1 and %d characters long", MaxBanAppealLength))), nil
+	}
+
+	err = SubmitBanAppeal(appeal, content)
+	if err != nil {
+		if err == ErrBanAppealAlreadyHandled {
+			return templateData.AddAlerts(web.ErrorAlert("You have already submitted this appeal")), nil
+		}
+		return templateData, err
+	}
+
+	templateData["Appeal"] = appeal
+	return templateData.AddAlerts(web.SucessAlert("Your appeal was submitted, you will be DM'd once it has been reviewed")), nil
+}
+
 var _ web.PluginWithServerHomeWidget = (*Plugin)(nil)
 
 func (p *Plugin) LoadServerHomeWidget(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
@@ -121,11 +345,12 @@ func (p *Plugin) LoadServerHomeWidget(w http.ResponseWriter, r *http.Request) (w
 	<li>Kick command: %s</li>
 	<li>Ban command: %s</li>
 	<li>Mute/Unmute commands: %s</li>
+	<li>Timeout commands: %s</li>
 	<li>Warning commands: %s</li>
 </ul>`
 
 	if config.ReportEnabled || config.CleanEnabled || config.GiveRoleCmdEnabled || config.ActionChannel != "" ||
-		config.MuteEnabled || config.KickEnabled || config.BanEnabled || config.WarnCommandsEnabled {
+		config.MuteEnabled || config.TimeoutEnabled || config.KickEnabled || config.BanEnabled || config.WarnCommandsEnabled {
 		templateData["WidgetEnabled"] = true
 	} else {
 		templateData["WidgetDisabled"] = true
@@ -134,7 +359,8 @@ func (p *Plugin) LoadServerHomeWidget(w http.ResponseWriter, r *http.Request) (w
 	templateData["WidgetBody"] = template.HTML(fmt.Sprintf(format, web.EnabledDisabledSpanStatus(config.ReportEnabled),
 		web.EnabledDisabledSpanStatus(config.CleanEnabled), web.EnabledDisabledSpanStatus(config.GiveRoleCmdEnabled),
 		web.EnabledDisabledSpanStatus(config.KickEnabled), web.EnabledDisabledSpanStatus(config.BanEnabled),
-		web.EnabledDisabledSpanStatus(config.MuteEnabled), web.EnabledDisabledSpanStatus(config.WarnCommandsEnabled)))
+		web.EnabledDisabledSpanStatus(config.MuteEnabled), web.EnabledDisabledSpanStatus(config.TimeoutEnabled),
+		web.EnabledDisabledSpanStatus(config.WarnCommandsEnabled)))
 
 	return templateData, nil
 }
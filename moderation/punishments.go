@@ -62,6 +62,18 @@ func punish(config *Config, p Punishment, guildID int64, channel *dstate.Channel
 		return common.ErrWithCaller(err)
 	}
 
+	action, user, logLink, err := executePunishment(config, p, guildID, channel, message, author, reason, user, duration, variadicBanDeleteDays...)
+	if err != nil {
+		return err
+	}
+
+	err = CreateModlogEmbed(config, author, action, user, reason, logLink)
+	return err
+}
+
+// executePunishment kicks or bans someone without creating a modlog entry,
+// returning the action and the (possibly more detailed) user for the caller to log
+func executePunishment(config *Config, p Punishment, guildID int64, channel *dstate.ChannelState, message *discordgo.Message, author *discordgo.User, reason string, user *discordgo.User, duration time.Duration, variadicBanDeleteDays ...int) (ModlogAction, *discordgo.User, string, error) {
 	var action ModlogAction
 	if p == PunishmentKick {
 		action = MAKick
@@ -69,6 +81,7 @@ func punish(config *Config, p Punishment, guildID int64, channel *dstate.Channel
 		action = MABanned
 		if duration > 0 {
 			action.Footer = "Expires after: " + common.HumanizeDuration(common.DurationPrecisionMinutes, duration)
+			action.Duration = duration
 		}
 	}
 
@@ -79,13 +92,20 @@ func punish(config *Config, p Punishment, guildID int64, channel *dstate.Channel
 
 	gs := bot.State.Guild(true, guildID)
 
+	// the appeal link has to be in the DM which is sent before the ban, but the session is only stored once the ban went through
+	var appeal *BanAppealModel
+
 	member, memberNotFound := getMemberWithFallback(gs, user)
 	if !memberNotFound {
 		msg := config.BanMessage
+		appealLink := ""
 		if p == PunishmentKick {
 			msg = config.KickMessage
+		} else if config.BanAppealsEnabled {
+			appeal = NewBanAppealSession(guildID, member.DGoUser(), reason)
+			appealLink = appeal.Link()
 		}
-		sendPunishDM(config, msg, action, gs, channel, message, author, member, duration, reason)
+		sendPunishDM(config, msg, action, gs, channel, message, author, member, duration, reason, appealLink)
 	}
 
 	logLink := ""
@@ -98,6 +118,7 @@ func punish(config *Config, p Punishment, guildID int64, channel *dstate.Channel
 		fullReason = author.Username + "#" + author.Discriminator + ": " + reason
 	}
 
+	var err error
 	switch p {
 	case PunishmentKick:
 		err = common.BotSession.GuildMemberDeleteWithReason(guildID, user.ID, fullReason)
@@ -110,11 +131,18 @@ func punish(config *Config, p Punishment, guildID int64, channel *dstate.Channel
 	}
 
 	if err != nil {
-		return err
+		return action, user, logLink, err
 	}
 
 	logger.Infof("MODERATION: %s %s %s cause %q", author.Username, action.Prefix, user.Username, reason)
 
+	if appeal != nil {
+		err = StoreBanAppealSession(appeal)
+		if err != nil {
+			logger.WithError(err).WithField("guild", guildID).Error("failed storing ban appeal session")
+		}
+	}
+
 	if memberNotFound {
 		// Wait a tiny bit to make sure the audit log is updated
 		time.Sleep(time.Second * 3)
@@ -142,11 +170,11 @@ func punish(config *Config, p Punishment, guildID int64, channel *dstate.Channel
 		}
 	}
 
-	err = CreateModlogEmbed(config, author, action, user, reason, logLink)
-	return err
+	return action, user, logLink, nil
 }
 
-func sendPunishDM(config *Config, dmMsg string, action ModlogAction, gs *dstate.GuildState, channel *dstate.ChannelState, message *discordgo.Message, author *discordgo.User, member *dstate.MemberState, duration time.Duration, reason string) {
+// sendPunishDM sends the punishment DM, appealLink is the link to the ban appeal page for bans with appeals enabled
+func sendPunishDM(config *Config, dmMsg string, action ModlogAction, gs *dstate.GuildState, channel *dstate.ChannelState, message *discordgo.Message, author *discordgo.User, member *dstate.MemberState, duration time.Duration, reason string, appealLink string) {
 	if dmMsg == "" {
 		dmMsg = DefaultDMMessage
 	}
@@ -169,12 +197,19 @@ func sendPunishDM(config *Config, dmMsg string, action ModlogAction, gs *dstate.
 		ctx.Data["HumanDuration"] = "permanently"
 	}
 
+	ctx.Data["AppealLink"] = appealLink
+
 	executed, err := ctx.Execute(dmMsg)
 	if err != nil {
 		logger.WithError(err).WithField("guild", gs.ID).Warn("Failed executing pusnishment DM")
 		executed = "Failed executing template."
 	}
 
+	// make sure they get the link even if the custom message doesn't include it
+	if appealLink != "" && strings.TrimSpace(executed) != "" && !strings.Contains(dmMsg, "AppealLink") {
+		executed += "\n\nYou can appeal this ban here: " + appealLink
+	}
+
 	if strings.TrimSpace(executed) != "" {
 		err = bot.SendDM(member.ID, "**"+bot.GuildName(gs.ID)+":** "+executed)
 		if err != nil {
@@ -258,12 +293,17 @@ func BanUserWithDuration(config *Config, guildID int64, channel *dstate.ChannelS
 		return err
 	}
 
-	_, err = seventsmodels.ScheduledEvents(qm.Where("event_name='moderation_unban' AND  guild_id = ? AND (data->>'user_id')::bigint = ?", guildID, user.ID)).DeleteAll(context.Background(), common.PQ)
+	return scheduleUnban(guildID, user.ID, duration)
+}
+
+// scheduleUnban replaces any pending unban of the user with one after duration, or only clears it if duration is 0
+func scheduleUnban(guildID int64, userID int64, duration time.Duration) error {
+	_, err := seventsmodels.ScheduledEvents(qm.Where("event_name='moderation_unban' AND  guild_id = ? AND (data->>'user_id')::bigint = ?", guildID, userID)).DeleteAll(context.Background(), common.PQ)
 	common.LogIgnoreError(err, "[moderation] failed clearing unban events", nil)
 
 	if duration > 0 {
 		err = scheduledevents2.ScheduleEvent("moderation_unban", guildID, time.Now().Add(duration), &ScheduledUnbanData{
-			UserID: user.ID,
+			UserID: userID,
 		})
 		if err != nil {
 			return errors.WithMessage(err, "punish,sched_unban")
@@ -395,6 +435,7 @@ func MuteUnmuteUser(config *Config, mute bool, guildID int64, channel *dstate.Ch
 		action.Footer = "Duration: "
 		if duration > 0 {
 			action.Footer += common.HumanizeDuration(common.DurationPrecisionMinutes, time.Duration(duration)*time.Minute)
+			action.Duration = time.Duration(duration) * time.Minute
 		} else {
 			action.Footer += "permanent"
 		}
@@ -403,7 +444,7 @@ func MuteUnmuteUser(config *Config, mute bool, guildID int64, channel *dstate.Ch
 
 	gs := bot.State.Guild(true, guildID)
 	if gs != nil {
-		sendPunishDM(config, dmMsg, action, gs, channel, message, author, member, time.Duration(duration)*time.Minute, reason)
+		sendPunishDM(config, dmMsg, action, gs, channel, message, author, member, time.Duration(duration)*time.Minute, reason, "")
 	}
 
 	// Create the modlog entry
@@ -459,6 +500,107 @@ func RemoveMemberMuteRole(config *Config, id int64, currentRoles []int64, mute M
 	return
 }
 
+// MaxTimeoutDuration is the longest timeout discord allows
+const MaxTimeoutDuration = time.Hour * 24 * 28
+
+// memberTimeoutEdit is the payload for changing a member's native timeout, discordgo has no support for it yet
+type memberTimeoutEdit struct {
+	CommunicationDisabledUntil *time.Time `json:"communication_disabled_until"`
+}
+
+// setMemberTimeout applies a native discord timeout until the specified time, or removes it if until is nil
+func setMemberTimeout(guildID, userID int64, until *time.Time) error {
+	_, err := common.BotSession.RequestWithBucketID("PATCH", discordgo.EndpointGuildMember(guildID, userID), &memberTimeoutEdit{
+		CommunicationDisabledUntil: until,
+	}, discordgo.EndpointGuildMember(guildID, 0))
+	return err
+}
+
+// TimeoutDuration clamps the provided duration to the configured and discord limits, falling back to the default duration if 0
+func (c *Config) TimeoutDuration(d time.Duration) time.Duration {
+	if d <= 0 {
+		d = time.Duration(c.DefaultTimeoutDuration.Int64) * time.Minute
+	}
+
+	max := MaxTimeoutDuration
+	if c.MaxTimeoutDuration.Int64 > 0 && time.Duration(c.MaxTimeoutDuration.Int64)*time.Minute < max {
+		max = time.Duration(c.MaxTimeoutDuration.Int64) * time.Minute
+	}
+
+	if d > max {
+		d = max
+	}
+
+	if d < time.Minute {
+		d = time.Minute
+	}
+
+	return d
+}
+
+// TimeoutUser puts the member in a native discord timeout (communication disabled) for the provided duration
+func TimeoutUser(config *Config, guildID int64, channel *dstate.ChannelState, message *discordgo.Message, author *discordgo.User, reason string, member *dstate.MemberState, duration time.Duration) error {
+	config, err := getConfigIfNotSet(guildID, config)
+	if err != nil {
+		return common.ErrWithCaller(err)
+	}
+
+	duration = config.TimeoutDuration(duration)
+	until := time.Now().Add(duration)
+
+	err = setMemberTimeout(guildID, member.ID, &until)
+	if err != nil {
+		return err
+	}
+
+	// make sure we don't have duplicated timeout expiry events
+	_, err = seventsmodels.ScheduledEvents(qm.Where("event_name='moderation_timeout_expired' AND  guild_id = ? AND (data->>'user_id')::bigint = ?", guildID, member.ID)).DeleteAll(context.Background(), common.PQ)
+	common.LogIgnoreError(err, "[moderation] failed clearing timeout expiry events", nil)
+
+	err = scheduledevents2.ScheduleEvent("moderation_timeout_expired", guildID, until, &ScheduledTimeoutExpiredData{
+		UserID: member.ID,
+	})
+	if err != nil {
+		return errors.WithMessage(err, "failed scheduling timeout expiry")
+	}
+
+	logLink := ""
+	if channel != nil {
+		logLink = CreateLogs(guildID, channel.ID, author)
+	}
+
+	action := MATimeoutAdded
+	action.Footer = "Duration: " + common.HumanizeDuration(common.DurationPrecisionMinutes, duration)
+	action.Duration = duration
+
+	gs := bot.State.Guild(true, guildID)
+	if gs != nil {
+		sendPunishDM(config, config.TimeoutMessage, action, gs, channel, message, author, member, duration, reason, "")
+	}
+
+	logger.Infof("MODERATION: %s %s %s cause %q", author.Username, action.Prefix, member.Username, reason)
+
+	return CreateModlogEmbed(config, author, action, member.DGoUser(), reason, logLink)
+}
+
+// RemoveTimeout removes a native discord timeout from the user
+func RemoveTimeout(config *Config, guildID int64, author *discordgo.User, reason string, user *discordgo.User) error {
+	config, err := getConfigIfNotSet(guildID, config)
+	if err != nil {
+		return common.ErrWithCaller(err)
+	}
+
+	err = setMemberTimeout(guildID, user.ID, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = seventsmodels.ScheduledEvents(qm.Where("event_name='moderation_timeout_expired' AND  guild_id = ? AND (data->>'user_id')::bigint = ?", guildID, user.ID)).DeleteAll(context.Background(), common.PQ)
+	common.LogIgnoreError(err, "[moderation] failed clearing timeout expiry events", nil)
+
+	return CreateModlogEmbed(config, author, MATimeoutRemoved, user, reason, "")
+}
+
 func WarnUser(config *Config, guildID int64, channel *dstate.ChannelState, msg *discordgo.Message, author *discordgo.User, target *discordgo.User, message string) error {
 	warning := &WarningModel{
 		GuildID:               guildID,
@@ -492,16 +634,28 @@ func WarnUser(config *Config, guildID int64, channel *dstate.ChannelState, msg *
 	gs := bot.State.Guild(true, guildID)
 	ms, _ := bot.GetMember(guildID, target.ID)
 	if gs != nil && ms != nil {
-		sendPunishDM(config, config.WarnMessage, MAWarned, gs, channel, msg, author, ms, -1, message)
+		sendPunishDM(config, config.WarnMessage, MAWarned, gs, channel, msg, author, ms, -1, message, "")
 	}
 
 	// go bot.SendDM(target.ID, fmt.Sprintf("**%s**: You have been warned for: %s", bot.GuildName(guildID), message))
 
-	if config.WarnSendToModlog && config.ActionChannel != "" {
+	if config.WarnSendToModlog && config.ModlogChannel(MAWarned) != 0 {
 		err = CreateModlogEmbed(config, author, MAWarned, target, message, warning.LogsLink)
 		if err != nil {
 			return common.ErrWithCaller(err)
 		}
+	} else {
+		// not posted in the modlog, but still keep a record of it
+		_, err = CreateCase(guildID, author, MAWarned, target, message, warning.LogsLink)
+		if err != nil {
+			return common.ErrWithCaller(err)
+		}
+	}
+
+	err = checkWarnEscalation(config, guildID, channel, msg, target)
+	if err != nil {
+		// the warning itself went through fine
+		logger.WithError(err).WithField("guild", guildID).Error("failed applying warn escalation")
 	}
 
 	return nil
@@ -0,0 +1,435 @@
+package moderation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/jinzhu/gorm"
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/dstate/v2"
+	"github.com/jonas747/yagpdb/bot"
+	"github.com/jonas747/yagpdb/bot/eventsystem"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/mediocregopher/radix/v3"
+)
+
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusClaimed   = "claimed"
+	ReportStatusResolved  = "resolved"
+	ReportStatusDismissed = "dismissed"
+)
+
+const (
+	ReportEmojiClaim   = "✋"
+	ReportEmojiResolve = "✅"
+	ReportEmojiDismiss = "❌"
+)
+
+// cooldown between anonymous reports from the same user, in seconds
+const anonymousReportCooldown = 60
+
+// ReportModel is a report against a user, multiple reports against the same user are merged into the same active report
+type ReportModel struct {
+	common.SmallModel
+
+	GuildID  int64 `gorm:"index:idx_moderation_reports_guild_report"`
+	ReportID int64 `gorm:"index:idx_moderation_reports_guild_report"`
+
+	TargetID       int64 `gorm:"index"`
+	TargetUsername string
+
+	Status string
+
+	// The staff member that last changed the status
+	HandledByID       int64
+	HandledByUsername string
+	Note              string
+
+	ReportChannelID int64
+	ReportMessageID int64 `gorm:"index"`
+
+	Reasons []*ReportReasonModel `gorm:"foreignkey:ReportModelID"`
+}
+
+func (r *ReportModel) TableName() string {
+	return "moderation_reports"
+}
+
+// Active returns true if the report is still being dealt with
+func (r *ReportModel) Active() bool {
+	return r.Status == ReportStatusOpen || r.Status == ReportStatusClaimed
+}
+
+// ReportReasonModel is a single user's report, merged into a ReportModel
+type ReportReasonModel struct {
+	common.SmallModel
+
+	ReportModelID uint `gorm:"index"`
+
+	// The reporter is stored even for anonymous reports so abuse can be dealt with, but it's never displayed
+	ReporterID       int64 `gorm:"index"`
+	ReporterUsername string
+	Anonymous        bool
+
+	ChannelID int64
+	Reason    string
+	LogsLink  string
+}
+
+func (r *ReportReasonModel) TableName() string {
+	return "moderation_report_reasons"
+}
+
+var (
+	ErrReportNotFound        = errors.New("Report not found")
+	ErrReportAlreadyHandled  = errors.New("That report has already been resolved or dismissed")
+	ErrReportAlreadyClaimed  = errors.New("That report has already been claimed")
+	ErrNoReportChannel       = errors.New("No report channel set up")
+	ErrAnonymousReportsOff   = errors.New("Anonymous reports are not enabled on that server")
+	ErrReportCooldown        = errors.New("You're sending reports too fast, wait a minute and try again")
+	ErrReporterNotInGuild    = errors.New("Couldn't find you on that server")
+	ErrCantReportYourself    = errors.New("You can't report yourself")
+	ErrReportTargetNotFound  = errors.New("Unknown user")
+	ErrInvalidReportStatus   = errors.New("Invalid report status")
+	ErrNoReportHandlingPerms = errors.New("You don't have permissions to handle reports")
+)
+
+// CreateReport creates a new report, or merges it into the active report against the same target if there is one
+func CreateReport(config *Config, guildID int64, reporter *discordgo.User, target *discordgo.User, channelID int64, reason, logLink string, anonymous bool) (report *ReportModel, merged bool, err error) {
+	reportChannel := config.IntReportChannel()
+	if reportChannel == 0 {
+		return nil, false, ErrNoReportChannel
+	}
+
+	reportReason := &ReportReasonModel{
+		ReporterID:       reporter.ID,
+		ReporterUsername: reporter.Username + "#" + reporter.Discriminator,
+		Anonymous:        anonymous,
+		ChannelID:        channelID,
+		Reason:           reason,
+		LogsLink:         logLink,
+	}
+
+	var existing ReportModel
+	err = common.GORM.Where("guild_id = ? AND target_id = ? AND status IN (?)", guildID, target.ID, []string{ReportStatusOpen, ReportStatusClaimed}).
+		Order("report_id desc").Preload("Reasons").First(&existing).Error
+	if err == nil {
+		// merge it into the existing report
+		reportReason.ReportModelID = existing.ID
+		err = common.GORM.Create(reportReason).Error
+		if err != nil {
+			return nil, false, errors.WithStackIf(err)
+		}
+
+		existing.Reasons = append(existing.Reasons, reportReason)
+		err = postReport(config, &existing)
+		return &existing, true, err
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, false, errors.WithStackIf(err)
+	}
+
+	reportID, err := common.GenLocalIncrIDPQ(nil, guildID, "moderation_reports")
+	if err != nil {
+		return nil, false, err
+	}
+
+	report = &ReportModel{
+		GuildID:        guildID,
+		ReportID:       reportID,
+		TargetID:       target.ID,
+		TargetUsername: target.Username + "#" + target.Discriminator,
+		Status:         ReportStatusOpen,
+		Reasons:        []*ReportReasonModel{reportReason},
+	}
+
+	err = common.GORM.Create(report).Error
+	if err != nil {
+		return nil, false, errors.WithStackIf(err)
+	}
+
+	err = postReport(config, report)
+	return report, false, err
+}
+
+// postReport sends the report embed to the report channel, or updates the existing one
+func postReport(config *Config, report *ReportModel) error {
+	embed := ReportEmbed(report)
+
+	if report.ReportMessageID != 0 {
+		_, err := common.BotSession.ChannelMessageEditEmbed(report.ReportChannelID, report.ReportMessageID, embed)
+		if err == nil {
+			return nil
+		}
+
+		if !common.IsDiscordErr(err, discordgo.ErrCodeUnknownMessage, discordgo.ErrCodeUnknownChannel) {
+			return err
+		}
+
+		// the message or channel is gone, if the report is still active post it again
+		if !report.Active() {
+			return nil
+		}
+	}
+
+	channelID := config.IntReportChannel()
+	if channelID == 0 {
+		return ErrNoReportChannel
+	}
+
+	m, err := common.BotSession.ChannelMessageSendEmbed(channelID, embed)
+	if err != nil {
+		return err
+	}
+
+	report.ReportChannelID = channelID
+	report.ReportMessageID = m.ID
+	err = common.GORM.Model(report).Updates(map[string]interface{}{
+		"report_channel_id": channelID,
+		"report_message_id": m.ID,
+	}).Error
+	if err != nil {
+		return errors.WithStackIf(err)
+	}
+
+	if report.Active() {
+		for _, emoji := range []string{ReportEmojiClaim, ReportEmojiResolve, ReportEmojiDismiss} {
+			err = common.BotSession.MessageReactionAdd(channelID, m.ID, emoji)
+			if err != nil {
+				logger.WithError(err).WithField("guild", report.GuildID).Warn("failed adding report reactions")
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetReport returns the report with the specified report id
+func GetReport(guildID int64, reportID int64) (*ReportModel, error) {
+	var report ReportModel
+	err := common.GORM.Where("guild_id = ? AND report_id = ?", guildID, reportID).Preload("Reasons").First(&report).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrReportNotFound
+		}
+
+		return nil, errors.WithStackIf(err)
+	}
+
+	return &report, nil
+}
+
+// GetReportByMessage returns the report that was posted as the specified message
+func GetReportByMessage(guildID int64, messageID int64) (*ReportModel, error) {
+	var report ReportModel
+	err := common.GORM.Where("guild_id = ? AND report_message_id = ?", guildID, messageID).Preload("Reasons").First(&report).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrReportNotFound
+		}
+
+		return nil, errors.WithStackIf(err)
+	}
+
+	return &report, nil
+}
+
+// SetReportStatus claims, resolves or dismisses the report and updates the report embed
+func SetReportStatus(config *Config, report *ReportModel, status string, handler *discordgo.User, note string) error {
+	switch status {
+	case ReportStatusClaimed:
+		if report.Status == ReportStatusClaimed && report.HandledByID != handler.ID {
+			return ErrReportAlreadyClaimed
+		}
+		fallthrough
+	case ReportStatusResolved, ReportStatusDismissed:
+		if !report.Active() {
+			return ErrReportAlreadyHandled
+		}
+	default:
+		return ErrInvalidReportStatus
+	}
+
+	report.Status = status
+	report.HandledByID = handler.ID
+	report.HandledByUsername = handler.Username + "#" + handler.Discriminator
+	if note != "" {
+		report.Note = note
+	}
+
+	err := common.GORM.Model(report).Updates(map[string]interface{}{
+		"status":              report.Status,
+		"handled_by_id":       report.HandledByID,
+		"handled_by_username": report.HandledByUsername,
+		"note":                report.Note,
+	}).Error
+	if err != nil {
+		return errors.WithStackIf(err)
+	}
+
+	err = postReport(config, report)
+	if err != nil {
+		return err
+	}
+
+	if !report.Active() && report.ReportMessageID != 0 {
+		err = common.BotSession.MessageReactionsRemoveAll(report.ReportChannelID, report.ReportMessageID)
+		common.LogIgnoreError(err, "[moderation] failed removing report reactions", nil)
+	}
+
+	return nil
+}
+
+// CanHandleReports returns true if the member has one of the report staff roles or kick members permissions in the channel
+func CanHandleReports(config *Config, channelID int64, ms *dstate.MemberState) bool {
+	for _, r := range ms.Roles {
+		if common.ContainsInt64Slice(config.ReportStaffRoles, r) {
+			return true
+		}
+	}
+
+	hasPerms, err := bot.AdminOrPermMS(channelID, ms, discordgo.PermissionKickMembers)
+	return err == nil && hasPerms
+}
+
+func reportStatusColor(status string) int {
+	switch status {
+	case ReportStatusClaimed:
+		return 0xf2a013
+	case ReportStatusResolved:
+		return 0x62c65f
+	case ReportStatusDismissed:
+		return 0x57728e
+	}
+
+	return 0xd64848
+}
+
+// ReportEmbed creates the embed displaying the report
+func ReportEmbed(report *ReportModel) *discordgo.MessageEmbed {
+	var reasons strings.Builder
+	for i, v := range report.Reasons {
+		if i >= 10 {
+			fmt.Fprintf(&reasons, "...and %d more\n", len(report.Reasons)-i)
+			break
+		}
+
+		reporter := fmt.Sprintf("<@%d>", v.ReporterID)
+		if v.Anonymous {
+			reporter = "Anonymous"
+		}
+
+		fmt.Fprintf(&reasons, "**%s**", reporter)
+		if v.ChannelID != 0 {
+			fmt.Fprintf(&reasons, " in <#%d>", v.ChannelID)
+		}
+		fmt.Fprintf(&reasons, ": %s", common.CutStringShort(v.Reason, 200))
+		if v.LogsLink != "" {
+			reasons.WriteString(" ([Logs](" + v.LogsLink + "))")
+		}
+		reasons.WriteString("\n")
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("Report #%d - %s", report.ReportID, strings.Title(report.Status)),
+		Description: fmt.Sprintf("**Reported user:** %s <@%d> *(ID %d)*", report.TargetUsername, report.TargetID, report.TargetID),
+		Color:       reportStatusColor(report.Status),
+		Fields: []*discordgo.MessageEmbedField{
+			&discordgo.MessageEmbedField{
+				Name:  fmt.Sprintf("Reports (%d)", len(report.Reasons)),
+				Value: common.CutStringShort(reasons.String(), 1000),
+			},
+		},
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("%s claim, %s resolve, %s dismiss or use the claimreport/resolvereport/dismissreport commands", ReportEmojiClaim, ReportEmojiResolve, ReportEmojiDismiss),
+		},
+		Timestamp: report.CreatedAt.Format(time.RFC3339),
+	}
+
+	if report.HandledByID != 0 {
+		handled := fmt.Sprintf("%s by %s", strings.Title(report.Status), report.HandledByUsername)
+		if report.Note != "" {
+			handled += "\n" + common.CutStringShort(report.Note, 500)
+		}
+
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "Status",
+			Value: handled,
+		})
+	}
+
+	if !report.Active() {
+		embed.Footer = nil
+	}
+
+	return embed
+}
+
+// checkAnonymousReportCooldown returns true if the user is allowed to send another anonymous report
+func checkAnonymousReportCooldown(userID int64) (bool, error) {
+	var resp string
+	err := common.RedisPool.Do(radix.Cmd(&resp, "SET", "moderation_anon_report_cooldown:"+strconv.FormatInt(userID, 10), "1", "EX", strconv.Itoa(anonymousReportCooldown), "NX"))
+	if err != nil {
+		return false, err
+	}
+
+	return resp == "OK", nil
+}
+
+func handleReportReactionAdd(evt *eventsystem.EventData) {
+	ra := evt.MessageReactionAdd()
+	if ra.UserID == common.BotUser.ID || ra.GuildID == 0 {
+		return
+	}
+
+	var status string
+	switch ra.Emoji.Name {
+	case ReportEmojiClaim:
+		status = ReportStatusClaimed
+	case ReportEmojiResolve:
+		status = ReportStatusResolved
+	case ReportEmojiDismiss:
+		status = ReportStatusDismissed
+	default:
+		return
+	}
+
+	config, err := GetConfig(ra.GuildID)
+	if err != nil {
+		logger.WithError(err).WithField("guild", ra.GuildID).Error("failed retrieving config")
+		return
+	}
+
+	if config.IntReportChannel() != ra.ChannelID {
+		return
+	}
+
+	report, err := GetReportByMessage(ra.GuildID, ra.MessageID)
+	if err != nil {
+		if err != ErrReportNotFound {
+			logger.WithError(err).WithField("guild", ra.GuildID).Error("failed retrieving report")
+		}
+		return
+	}
+
+	ms, err := bot.GetMember(ra.GuildID, ra.UserID)
+	if err != nil || ms == nil {
+		return
+	}
+
+	// remove the reaction so it can be used again, e.g claiming then resolving
+	defer common.BotSession.MessageReactionRemove(ra.ChannelID, ra.MessageID, ra.Emoji.APIName(), ra.UserID)
+
+	if !CanHandleReports(config, ra.ChannelID, ms) {
+		return
+	}
+
+	err = SetReportStatus(config, report, status, ms.DGoUser(), "")
+	if err != nil && errors.Cause(err) != ErrReportAlreadyClaimed && errors.Cause(err) != ErrReportAlreadyHandled {
+		logger.WithError(err).WithField("guild", ra.GuildID).Error("failed updating report status")
+	}
+}
@@ -0,0 +1,196 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/common/scheduledevents2"
+	seventsmodels "github.com/jonas747/yagpdb/common/scheduledevents2/models"
+	"github.com/jonas747/yagpdb/rolecommands"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+const (
+	// scheduled by giverole and custom commands
+	tempRoleEventStd = "std_remove_member_role"
+	// scheduled by role commands and role menus with a temporary duration
+	tempRoleEventRoleCommands = "remove_member_role"
+)
+
+var ErrTempRoleNotFound = errors.New("That user doesn't have that role temporarily")
+
+// TempRole is a role given to a member that is scheduled to be removed
+type TempRole struct {
+	EventID   int64
+	EventName string
+
+	UserID    int64
+	RoleID    int64
+	ExpiresAt time.Time
+
+	// role command group the role was given through, 0 if not given through role commands
+	GroupID int64
+}
+
+// FromRoleCommands returns true if the role was given through role commands or a role menu
+func (t *TempRole) FromRoleCommands() bool {
+	return t.EventName == tempRoleEventRoleCommands
+}
+
+func tempRoleFromEvent(evt *seventsmodels.ScheduledEvent) (*TempRole, error) {
+	tempRole := &TempRole{
+		EventID:   evt.ID,
+		EventName: evt.EventName,
+		ExpiresAt: evt.TriggersAt,
+	}
+
+	if evt.EventName == tempRoleEventRoleCommands {
+		var data rolecommands.ScheduledMemberRoleRemoveData
+		err := json.Unmarshal(evt.Data, &data)
+		if err != nil {
+			return nil, errors.WithStackIf(err)
+		}
+
+		tempRole.UserID = data.UserID
+		tempRole.RoleID = data.RoleID
+		tempRole.GroupID = data.GroupID
+		return tempRole, nil
+	}
+
+	var data scheduledevents2.RmoveRoleData
+	err := json.Unmarshal(evt.Data, &data)
+	if err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+
+	tempRole.UserID = data.UserID
+	tempRole.RoleID = data.RoleID
+	return tempRole, nil
+}
+
+func tempRoleQuery(guildID, userID, roleID int64) []qm.QueryMod {
+	mods := []qm.QueryMod{
+		qm.Where("event_name IN (?, ?) AND guild_id = ? AND processed = false", tempRoleEventStd, tempRoleEventRoleCommands, guildID),
+	}
+
+	if userID != 0 {
+		mods = append(mods, qm.Where("(data->>'user_id')::bigint = ?", userID))
+	}
+
+	if roleID != 0 {
+		mods = append(mods, qm.Where("(data->>'role_id')::bigint = ?", roleID))
+	}
+
+	return mods
+}
+
+// GetTempRoles returns the active temporary roles in the guild, ordered by expiry, optionally only of the specified user
+func GetTempRoles(ctx context.Context, guildID, userID int64, limit, offset int) (result []*TempRole, total int64, err error) {
+	total, err = seventsmodels.ScheduledEvents(tempRoleQuery(guildID, userID, 0)...).CountG(ctx)
+	if err != nil {
+		return nil, 0, errors.WithStackIf(err)
+	}
+
+	mods := append(tempRoleQuery(guildID, userID, 0), qm.OrderBy("triggers_at asc"), qm.Limit(limit), qm.Offset(offset))
+	events, err := seventsmodels.ScheduledEvents(mods...).AllG(ctx)
+	if err != nil {
+		return nil, 0, errors.WithStackIf(err)
+	}
+
+	result = make([]*TempRole, 0, len(events))
+	for _, v := range events {
+		tempRole, err := tempRoleFromEvent(v)
+		if err != nil {
+			logger.WithError(err).WithField("guild", guildID).WithField("event", v.ID).Error("failed decoding temp role event")
+			continue
+		}
+
+		result = append(result, tempRole)
+	}
+
+	return result, total, nil
+}
+
+// findTempRoleEvents returns the pending removals of the role from the user
+func findTempRoleEvents(ctx context.Context, guildID, userID, roleID int64) (seventsmodels.ScheduledEventSlice, error) {
+	mods := append(tempRoleQuery(guildID, userID, roleID), qm.OrderBy("triggers_at desc"))
+	events, err := seventsmodels.ScheduledEvents(mods...).AllG(ctx)
+	if err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+
+	if len(events) < 1 {
+		return nil, ErrTempRoleNotFound
+	}
+
+	return events, nil
+}
+
+// ExtendTempRole pushes back the removal of the temporary role by extra,
+// the scheduled event is replaced rather than updated since it may already be queued up
+func ExtendTempRole(ctx context.Context, guildID, userID, roleID int64, extra time.Duration) (*TempRole, error) {
+	events, err := findTempRoleEvents(ctx, guildID, userID, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the latest one is the one that matters
+	latest := events[0]
+	newExpiry := latest.TriggersAt.Add(extra)
+	if newExpiry.Before(time.Now()) {
+		newExpiry = time.Now()
+	}
+
+	var data interface{}
+	if latest.EventName == tempRoleEventRoleCommands {
+		data = &rolecommands.ScheduledMemberRoleRemoveData{}
+	} else {
+		data = &scheduledevents2.RmoveRoleData{}
+	}
+
+	err = json.Unmarshal(latest.Data, data)
+	if err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+
+	_, err = events.DeleteAll(ctx, common.PQ)
+	if err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+
+	err = scheduledevents2.ScheduleEvent(latest.EventName, guildID, newExpiry, data)
+	if err != nil {
+		return nil, err
+	}
+
+	tempRole, err := tempRoleFromEvent(latest)
+	if err != nil {
+		return nil, err
+	}
+
+	tempRole.ExpiresAt = newExpiry
+	return tempRole, nil
+}
+
+// CancelTempRole cancels the scheduled removal of the role, if removeNow is set the role is removed right away,
+// otherwise the member keeps it permanently
+func CancelTempRole(ctx context.Context, guildID, userID, roleID int64, removeNow bool) error {
+	events, err := findTempRoleEvents(ctx, guildID, userID, roleID)
+	if err != nil {
+		return err
+	}
+
+	_, err = events.DeleteAll(ctx, common.PQ)
+	if err != nil {
+		return errors.WithStackIf(err)
+	}
+
+	if removeNow {
+		return common.BotSession.GuildMemberRoleRemove(guildID, userID, roleID)
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package moderation
+
+import (
+	"context"
+
+	"emperror.dev/errors"
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/yagpdb/common"
+)
+
+var _ common.PluginWithUserData = (*Plugin)(nil)
+
+type userDataExport struct {
+	// warnings, cases and reports the user received
+	Warnings   []*WarningModel   `json:"warnings"`
+	Cases      []*CaseModel      `json:"cases"`
+	Reports    []*ReportModel    `json:"reports_against"`
+	BanAppeals []*BanAppealModel `json:"ban_appeals"`
+
+	// things the user did as a member or staff
+	ReportsMade    []*ReportReasonModel `json:"reports_made"`
+	WarningsIssued []*WarningModel      `json:"warnings_issued"`
+	CasesAuthored  []*CaseModel         `json:"cases_authored"`
+	ReportsHandled []*ReportModel       `json:"reports_handled"`
+	AppealsHandled []*BanAppealModel    `json:"ban_appeals_handled"`
+}
+
+func (p *Plugin) ExportUserData(ctx context.Context, userID int64) (interface{}, error) {
+	strID := discordgo.StrID(userID)
+	export := &userDataExport{}
+
+	queries := []struct {
+		name  string
+		dst   interface{}
+		where string
+		arg   interface{}
+	}{
+		{"warnings", &export.Warnings, "user_id = ?", strID},
+		{"cases", &export.Cases, "user_id = ?", userID},
+		{"reports", &export.Reports, "target_id = ?", userID},
+		{"ban appeals", &export.BanAppeals, "user_id = ?", userID},
+		{"report reasons", &export.ReportsMade, "reporter_id = ?", userID},
+		{"issued warnings", &export.WarningsIssued, "author_id = ?", strID},
+		{"authored cases", &export.CasesAuthored, "author_id = ?", userID},
+		{"handled reports", &export.ReportsHandled, "handled_by_id = ?", userID},
+		{"handled ban appeals", &export.AppealsHandled, "handled_by_id = ?", userID},
+	}
+
+	for _, q := range queries {
+		err := common.GORM.Where(q.where, q.arg).Order("id asc").Find(q.dst).Error
+		if err != nil {
+			return nil, errors.WrapIf(err, q.name)
+		}
+	}
+
+	return export, nil
+}
+
+// EraseUserData deletes everything about the user,
+// records of actions the user took as staff are kept but with the user anonymized
+func (p *Plugin) EraseUserData(ctx context.Context, userID int64) error {
+	strID := discordgo.StrID(userID)
+
+	err := common.GORM.Where("user_id = ?", strID).Delete(WarningModel{}).Error
+	if err != nil {
+		return errors.WrapIf(err, "warnings")
+	}
+
+	err = common.GORM.Where("user_id = ?", userID).Delete(CaseModel{}).Error
+	if err != nil {
+		return errors.WrapIf(err, "cases")
+	}
+
+	err = common.GORM.Where("user_id = ?", userID).Delete(BanAppealModel{}).Error
+	if err != nil {
+		return errors.WrapIf(err, "ban appeals")
+	}
+
+	err = common.GORM.Where("reporter_id = ? OR report_model_id IN (SELECT id FROM moderation_reports WHERE target_id = ?)", userID, userID).Delete(ReportReasonModel{}).Error
+	if err != nil {
+		return errors.WrapIf(err, "report reasons")
+	}
+
+	err = common.GORM.Where("target_id = ?", userID).Delete(ReportModel{}).Error
+	if err != nil {
+		return errors.WrapIf(err, "reports")
+	}
+
+	anonymize := []struct {
+		name    string
+		model   interface{}
+		where   string
+		arg     interface{}
+		updates map[string]interface{}
+	}{
+		{"issued warnings", WarningModel{}, "author_id = ?", strID, map[string]interface{}{"author_id": "0", "author_username_discrim": "Deleted user"}},
+		{"authored cases", CaseModel{}, "author_id = ?", userID, map[string]interface{}{"author_id": 0, "author_username": "Deleted user"}},
+		{"handled reports", ReportModel{}, "handled_by_id = ?", userID, map[string]interface{}{"handled_by_id": 0, "handled_by_username": "Deleted user"}},
+		{"handled ban appeals", BanAppealModel{}, "handled_by_id = ?", userID, map[string]interface{}{"handled_by_id": 0, "handled_by_username": "Deleted user"}},
+	}
+
+	for _, v := range anonymize {
+		err = common.GORM.Model(v.model).Where(v.where, v.arg).Updates(v.updates).Error
+		if err != nil {
+			return errors.WrapIf(err, v.name)
+		}
+	}
+
+	return nil
+}
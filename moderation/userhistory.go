@@ -0,0 +1,262 @@
+package moderation
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/jinzhu/gorm"
+	"github.com/jonas747/discordgo"
+	automodmodels "github.com/jonas747/yagpdb/automod/models"
+	"github.com/jonas747/yagpdb/bot"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/logs"
+	logsmodels "github.com/jonas747/yagpdb/logs/models"
+	reputationmodels "github.com/jonas747/yagpdb/reputation/models"
+	ticketsmodels "github.com/jonas747/yagpdb/tickets/models"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+// max number of entries of each kind included in the user history
+const userHistoryLimit = 10
+
+// UserHistory is a summary of everything known about a user in a guild
+type UserHistory struct {
+	GuildID int64
+	UserID  int64
+
+	Warnings     []*WarningModel
+	WarningCount int
+
+	Cases     []*CaseModel
+	CaseCount int
+
+	// counts of cases by action, e.g "Muted": 3
+	CaseActionCounts map[string]int
+
+	CurrentMute *MuteModel
+
+	// number of reports against the user, merged reports count once
+	ReportCount int
+
+	// AuditLogBans holds the bans of the user found in the audit log, the audit log only goes back 90 days
+	AuditLogBans []*discordgo.AuditLogEntry
+
+	AutomodViolations     []*automodmodels.AutomodViolation
+	AutomodViolationCount int64
+
+	Reputation int64
+
+	Usernames []*logsmodels.UsernameListing
+	Nicknames []*logsmodels.NicknameListing
+
+	Tickets     []*ticketsmodels.Ticket
+	TicketCount int64
+}
+
+// GetUserHistory aggregates the history of a user in a guild across plugins
+func GetUserHistory(ctx context.Context, guildID, userID int64) (*UserHistory, error) {
+	history := &UserHistory{
+		GuildID:          guildID,
+		UserID:           userID,
+		CaseActionCounts: make(map[string]int),
+	}
+
+	// warnings
+	err := common.GORM.Model(&WarningModel{}).Where("guild_id = ? AND user_id = ?", guildID, discordgo.StrID(userID)).Count(&history.WarningCount).Error
+	if err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+
+	err = common.GORM.Where("guild_id = ? AND user_id = ?", guildID, discordgo.StrID(userID)).Order("id desc").Limit(userHistoryLimit).Find(&history.Warnings).Error
+	if err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+
+	// cases, this also covers the mute history
+	var actionCounts []struct {
+		Action string
+		Count  int
+	}
+	err = common.GORM.Model(&CaseModel{}).Select("action, count(*) as count").Where("guild_id = ? AND user_id = ?", guildID, userID).Group("action").Scan(&actionCounts).Error
+	if err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+
+	for _, v := range actionCounts {
+		history.CaseActionCounts[v.Action] = v.Count
+		history.CaseCount += v.Count
+	}
+
+	err = common.GORM.Where("guild_id = ? AND user_id = ?", guildID, userID).Order("case_number desc").Limit(userHistoryLimit).Find(&history.Cases).Error
+	if err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+
+	var currentMute MuteModel
+	err = common.GORM.Where(&MuteModel{UserID: userID, GuildID: guildID}).First(&currentMute).Error
+	if err == nil {
+		history.CurrentMute = &currentMute
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, errors.WithStackIf(err)
+	}
+
+	err = common.GORM.Model(&ReportModel{}).Where("guild_id = ? AND target_id = ?", guildID, userID).Count(&history.ReportCount).Error
+	if err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+
+	// automod
+	history.AutomodViolationCount, err = automodmodels.AutomodViolations(automodmodels.AutomodViolationWhere.GuildID.EQ(guildID), automodmodels.AutomodViolationWhere.UserID.EQ(userID)).CountG(ctx)
+	if err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+
+	history.AutomodViolations, err = automodmodels.AutomodViolations(automodmodels.AutomodViolationWhere.GuildID.EQ(guildID),
+		automodmodels.AutomodViolationWhere.UserID.EQ(userID), qm.OrderBy("id desc"), qm.Limit(userHistoryLimit)).AllG(ctx)
+	if err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+
+	// reputation
+	rep, err := reputationmodels.FindReputationUserG(ctx, guildID, userID)
+	if err == nil {
+		history.Reputation = rep.Points
+	} else if errors.Cause(err) != sql.ErrNoRows {
+		return nil, errors.WithStackIf(err)
+	}
+
+	// past usernames and nicknames
+	history.Usernames, err = logs.GetUsernames(ctx, userID, userHistoryLimit, 0)
+	if err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+
+	history.Nicknames, err = logs.GetNicknames(ctx, userID, guildID, userHistoryLimit, 0)
+	if err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+
+	// tickets they opened or took part in
+	ticketsWhere := qm.Where("guild_id = ? AND (author_id = ? OR local_id IN (SELECT ticket_local_id FROM ticket_participants WHERE ticket_guild_id = ? AND user_id = ?))",
+		guildID, userID, guildID, userID)
+
+	history.TicketCount, err = ticketsmodels.Tickets(ticketsWhere).CountG(ctx)
+	if err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+
+	history.Tickets, err = ticketsmodels.Tickets(ticketsWhere, qm.OrderBy("local_id desc"), qm.Limit(userHistoryLimit)).AllG(ctx)
+	if err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+
+	// bans from the audit log, not having the audit log permission is not fatal
+	auditLog, err := common.BotSession.GuildAuditLog(guildID, 0, 0, discordgo.AuditLogActionMemberBanAdd, 100)
+	if err == nil {
+		for _, v := range auditLog.AuditLogEntries {
+			if v.TargetID == userID {
+				history.AuditLogBans = append(history.AuditLogBans, v)
+			}
+		}
+	} else {
+		logger.WithError(err).WithField("guild", guildID).Debug("failed retrieving audit log for user history")
+	}
+
+	return history, nil
+}
+
+// Embed creates a summary embed of the history
+func (h *UserHistory) Embed() *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("History - User : %d", h.UserID),
+	}
+
+	if len(h.Usernames) > 0 && h.Usernames[0].Username.Valid {
+		embed.Title = fmt.Sprintf("History - %s (%d)", h.Usernames[0].Username.String, h.UserID)
+	}
+
+	addField := func(name string, lines []string, inline bool) {
+		value := strings.Join(lines, "\n")
+		if value == "" {
+			value = "None"
+		}
+
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   name,
+			Value:  common.CutStringShort(value, 1000),
+			Inline: inline,
+		})
+	}
+
+	// summary
+	summary := []string{
+		fmt.Sprintf("Warnings: **%d**", h.WarningCount),
+		fmt.Sprintf("Cases: **%d**", h.CaseCount),
+		fmt.Sprintf("Reports: **%d**", h.ReportCount),
+		fmt.Sprintf("Automod violations: **%d**", h.AutomodViolationCount),
+		fmt.Sprintf("Tickets: **%d**", h.TicketCount),
+		fmt.Sprintf("Reputation: **%d**", h.Reputation),
+	}
+	if h.CurrentMute != nil {
+		if h.CurrentMute.ExpiresAt.IsZero() {
+			summary = append(summary, "Currently **muted** permanently")
+		} else {
+			summary = append(summary, fmt.Sprintf("Currently **muted**, expires in %s", common.HumanizeDuration(common.DurationPrecisionMinutes, time.Until(h.CurrentMute.ExpiresAt))))
+		}
+	}
+	addField("Summary", summary, true)
+
+	actionCounts := make([]string, 0, len(h.CaseActionCounts))
+	for action, count := range h.CaseActionCounts {
+		actionCounts = append(actionCounts, fmt.Sprintf("%s: **%d**", action, count))
+	}
+	addField("Cases by action", actionCounts, true)
+
+	warnings := make([]string, 0, len(h.Warnings))
+	for _, v := range h.Warnings {
+		warnings = append(warnings, fmt.Sprintf("#%d `%s` %s", v.ID, v.CreatedAt.UTC().Format("02 Jan 06"), common.CutStringShort(v.Message, 80)))
+	}
+	addField("Recent warnings", warnings, false)
+
+	cases := make([]string, 0, len(h.Cases))
+	for _, v := range h.Cases {
+		cases = append(cases, fmt.Sprintf("#%d `%s` %s%s - %s", v.CaseNumber, v.CreatedAt.UTC().Format("02 Jan 06"), v.Emoji, v.Action, common.CutStringShort(v.Reason, 60)))
+	}
+	addField("Recent cases", cases, false)
+
+	bans := make([]string, 0, len(h.AuditLogBans))
+	for _, v := range h.AuditLogBans {
+		bans = append(bans, fmt.Sprintf("`%s` by <@%d>: %s", bot.SnowflakeToTime(v.ID).UTC().Format("02 Jan 06"), v.UserID, common.CutStringShort(v.Reason, 80)))
+	}
+	addField("Bans (audit log)", bans, false)
+
+	violations := make([]string, 0, len(h.AutomodViolations))
+	for _, v := range h.AutomodViolations {
+		violations = append(violations, fmt.Sprintf("`%s` %s", v.CreatedAt.UTC().Format("02 Jan 06"), v.Name))
+	}
+	addField("Recent automod violations", violations, true)
+
+	tickets := make([]string, 0, len(h.Tickets))
+	for _, v := range h.Tickets {
+		tickets = append(tickets, fmt.Sprintf("#%d `%s` %s", v.LocalID, v.CreatedAt.UTC().Format("02 Jan 06"), common.CutStringShort(v.Title, 60)))
+	}
+	addField("Recent tickets", tickets, true)
+
+	usernames := make([]string, 0, len(h.Usernames))
+	for _, v := range h.Usernames {
+		usernames = append(usernames, fmt.Sprintf("`%s` %s", v.CreatedAt.Time.UTC().Format("02 Jan 06"), v.Username.String))
+	}
+	addField("Past usernames", usernames, true)
+
+	nicknames := make([]string, 0, len(h.Nicknames))
+	for _, v := range h.Nicknames {
+		nicknames = append(nicknames, fmt.Sprintf("`%s` %s", v.CreatedAt.Time.UTC().Format("02 Jan 06"), v.Nickname.String))
+	}
+	addField("Past nicknames", nicknames, true)
+
+	return embed
+}
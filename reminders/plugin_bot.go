@@ -11,10 +11,12 @@ import (
 	"github.com/jonas747/dcmd"
 	"github.com/jonas747/discordgo"
 	"github.com/jonas747/yagpdb/bot"
+	"github.com/jonas747/yagpdb/bot/eventsystem"
 	"github.com/jonas747/yagpdb/commands"
 	"github.com/jonas747/yagpdb/common"
 	"github.com/jonas747/yagpdb/common/scheduledevents2"
 	seventsmodels "github.com/jonas747/yagpdb/common/scheduledevents2/models"
+	"github.com/jonas747/yagpdb/timezonecompanion"
 )
 
 var logger = common.GetPluginLogger(&Plugin{})
@@ -30,19 +32,21 @@ func (p *Plugin) BotInit() {
 	// scheduledevents.RegisterEventHandler("reminders_check_user", checkUserEvtHandlerLegacy)
 	scheduledevents2.RegisterHandler("reminders_check_user", int64(0), checkUserScheduledEvent)
 	scheduledevents2.RegisterLegacyMigrater("reminders_check_user", migrateLegacyScheduledEvents)
+
+	eventsystem.AddHandlerAsyncLastLegacy(p, handleReactionAddSnooze, eventsystem.EventMessageReactionAdd)
 }
 
 // Reminder management commands
 var cmds = []*commands.YAGCommand{
 	&commands.YAGCommand{
-		CmdCategory:  commands.CategoryTool,
-		Name:         "Remindme",
-		Description:  "Schedules a reminder, example: 'remindme 1h30min are you alive still?'",
-		Aliases:      []string{"remind", "reminder"},
-		RequiredArgs: 2,
+		CmdCategory:     commands.CategoryTool,
+		Name:            "Remindme",
+		Description:     "Schedules a reminder, example: 'remindme 1h30min are you alive still?' or 'remindme tomorrow at noon lunch'",
+		LongDescription: "The time can be a duration such as `1h30min` or `in 2 days`, or a absolute time such as `at 17:30`, `on 2026-12-24 18:00`, `tomorrow at noon` or `next monday`.\nAbsolute times are in your timezone set with the `settimezone` command, or UTC if you haven't set one.",
+		Aliases:         []string{"remind", "reminder"},
+		RequiredArgs:    1,
 		Arguments: []*dcmd.ArgDef{
-			&dcmd.ArgDef{Name: "Time", Type: &commands.DurationArg{}},
-			&dcmd.ArgDef{Name: "Message", Type: dcmd.String},
+			&dcmd.ArgDef{Name: "Time-and-message", Type: dcmd.String},
 		},
 		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
 			currentReminders, _ := GetUserReminders(parsed.Msg.Author.ID)
@@ -50,22 +54,73 @@ var cmds = []*commands.YAGCommand{
 				return "You can have a maximum of 25 active reminders, list your reminders with the `reminders` command", nil
 			}
 
-			fromNow := parsed.Args[0].Value.(time.Duration)
+			loc, tzNote := userLocation(parsed.Msg.Author.ID)
+			when, message, absolute, err := ParseReminderTime(parsed.Args[0].Str(), time.Now().In(loc))
+			if err != nil {
+				return err.Error(), nil
+			}
 
-			durString := common.HumanizeDuration(common.DurationPrecisionSeconds, fromNow)
-			when := time.Now().Add(fromNow)
-			tStr := when.UTC().Format(time.RFC822)
+			if message == "" {
+				return "No message provided for the reminder", nil
+			}
 
 			if when.After(time.Now().Add(time.Hour * 24 * 366)) {
 				return "Can be max 365 days from now...", nil
 			}
 
-			_, err := NewReminder(parsed.Msg.Author.ID, parsed.GS.ID, parsed.CS.ID, parsed.Args[1].Str(), when)
+			_, err = NewReminder(parsed.Msg.Author.ID, parsed.GS.ID, parsed.CS.ID, message, when)
 			if err != nil {
 				return nil, err
 			}
 
-			return "Set a reminder in " + durString + " from now (" + tStr + ")\nView reminders with the reminders command", nil
+			durString := common.HumanizeDuration(common.DurationPrecisionSeconds, time.Until(when))
+			tStr := when.Format(time.RFC822)
+			if !absolute {
+				// durations doesn't depend on the timezone
+				tStr = when.UTC().Format(time.RFC822)
+				tzNote = ""
+			} else {
+				durString = common.HumanizeDuration(common.DurationPrecisionMinutes, time.Until(when).Round(time.Minute))
+			}
+
+			return "Set a reminder in " + durString + " from now (" + tStr + ")" + tzNote + "\nView reminders with the reminders command", nil
+		},
+	},
+	&commands.YAGCommand{
+		CmdCategory:     commands.CategoryTool,
+		Name:            "RemindEvery",
+		Description:     "Schedules a recurring reminder, example: 'remindevery day at 9:00 take your meds'",
+		LongDescription: "The schedule can be a number of hours, days, weeks or months, or a day of the week, optionally followed by `at` and a time of day, e.g `2 weeks`, `monday at 18:30` or `month at noon`.\nTimes are in your timezone set with the `settimezone` command, or UTC if you haven't set one.",
+		Aliases:         []string{"remindrepeat"},
+		RequiredArgs:    1,
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "Schedule-and-message", Type: dcmd.String},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			currentReminders, _ := GetUserReminders(parsed.Msg.Author.ID)
+			if len(currentReminders) >= 25 {
+				return "You can have a maximum of 25 active reminders, list your reminders with the `reminders` command", nil
+			}
+
+			rec, message, err := ParseRecurrence(parsed.Args[0].Str())
+			if err != nil {
+				return err.Error(), nil
+			}
+
+			if message == "" {
+				return "No message provided for the reminder", nil
+			}
+
+			loc, tzNote := userLocation(parsed.Msg.Author.ID)
+			first := rec.First(time.Now().In(loc))
+
+			_, err = NewRecurringReminder(parsed.Msg.Author.ID, parsed.GS.ID, parsed.CS.ID, message, first, rec.Every, rec.Unit)
+			if err != nil {
+				return nil, err
+			}
+
+			return fmt.Sprintf("Set a reminder %s, the first one is in %s (%s)%s\nView reminders with the reminders command",
+				rec.String(), common.HumanizeTime(common.DurationPrecisionMinutes, first), first.Format(time.RFC822), tzNote), nil
 		},
 	},
 	&commands.YAGCommand{
@@ -176,7 +231,10 @@ func stringReminders(reminders []*Reminder, displayUsernames bool) string {
 
 		t := time.Unix(v.When, 0)
 		timeFromNow := common.HumanizeTime(common.DurationPrecisionMinutes, t)
-		tStr := t.Format(time.RFC822)
+		tStr := t.In(v.location()).Format(time.RFC822)
+		if v.IsRecurring() {
+			tStr += ", repeats " + repeatString(v.RepeatEvery, v.RepeatUnit)
+		}
 		if !displayUsernames {
 			channel := "<#" + discordgo.StrID(parsedCID) + ">"
 			out += fmt.Sprintf("**%d**: %s: '%s' - %s from now (%s)\n", v.ID, channel, limitString(v.Message), timeFromNow, tStr)
@@ -229,6 +287,17 @@ func migrateLegacyScheduledEvents(t time.Time, data string) error {
 	return scheduledevents2.ScheduleEvent("reminders_check_user", 1, t, parsed)
 }
 
+// userLocation returns the timezone the user has set with the timezonecompanion plugin,
+// falling back to UTC with a note on how to set it
+func userLocation(userID int64) (*time.Location, string) {
+	loc := timezonecompanion.GetUserTimezone(userID)
+	if loc == nil {
+		return time.UTC, "\nTimes are in UTC, set your timezone with the `settimezone` command"
+	}
+
+	return loc, ""
+}
+
 func limitString(s string) string {
 	if utf8.RuneCountInString(s) < 50 {
 		return s
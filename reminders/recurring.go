@@ -0,0 +1,215 @@
+package reminders
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	RepeatUnitHour  = "hour"
+	RepeatUnitDay   = "day"
+	RepeatUnitWeek  = "week"
+	RepeatUnitMonth = "month"
+)
+
+var (
+	ErrInvalidRepeat = errors.New("Couldn't understand how often to repeat the reminder, examples: `day at 9:00`, `2 weeks`, `monday at 18:30`, `12 hours`")
+	ErrInvalidClock  = errors.New("Couldn't understand the time of day, examples: `9:00`, `17:30`, `9am`, `noon`")
+)
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+// Recurrence is a parsed "every ..." schedule
+type Recurrence struct {
+	Every int
+	Unit  string
+
+	// set when repeating on a specific day of the week
+	Weekday    time.Weekday
+	HasWeekday bool
+
+	// set when the reminder should trigger at a specific time of day
+	Hour, Minute int
+	HasClock     bool
+}
+
+// ParseRecurrence parses the schedule at the start of the input, e.g "every 2 days at 9:00 water the plants",
+// returning the rest of the input which is the reminder message
+func ParseRecurrence(input string) (*Recurrence, string, error) {
+	fields := strings.Fields(input)
+	i := 0
+	next := func() string {
+		if i >= len(fields) {
+			return ""
+		}
+		return strings.ToLower(fields[i])
+	}
+
+	if next() == "every" {
+		i++
+	}
+
+	rec := &Recurrence{Every: 1}
+	if n, err := strconv.Atoi(next()); err == nil {
+		if n < 1 || n > 365 {
+			return nil, "", errors.New("The interval has to be between 1 and 365")
+		}
+		rec.Every = n
+		i++
+	}
+
+	unit := next()
+	switch strings.TrimSuffix(unit, "s") {
+	case "hour", "hr", "h":
+		rec.Unit = RepeatUnitHour
+	case "day", "d":
+		rec.Unit = RepeatUnitDay
+	case "week", "w":
+		rec.Unit = RepeatUnitWeek
+	case "month":
+		rec.Unit = RepeatUnitMonth
+	default:
+		wd, ok := weekdays[unit]
+		if !ok {
+			wd, ok = weekdays[strings.TrimSuffix(unit, "s")]
+		}
+		if !ok {
+			return nil, "", ErrInvalidRepeat
+		}
+
+		rec.Unit = RepeatUnitWeek
+		rec.Weekday = wd
+		rec.HasWeekday = true
+	}
+	i++
+
+	if next() == "at" {
+		i++
+		h, m, ok := ParseClock(next())
+		if !ok {
+			return nil, "", ErrInvalidClock
+		}
+
+		if rec.Unit == RepeatUnitHour {
+			return nil, "", errors.New("A time of day can't be used with reminders repeating every few hours")
+		}
+
+		rec.Hour, rec.Minute, rec.HasClock = h, m, true
+		i++
+	}
+
+	return rec, strings.Join(fields[i:], " "), nil
+}
+
+// First returns the first time the reminder should trigger after now
+func (r *Recurrence) First(now time.Time) time.Time {
+	if r.Unit == RepeatUnitHour {
+		return now.Add(time.Hour * time.Duration(r.Every))
+	}
+
+	if !r.HasClock && !r.HasWeekday {
+		return addRepeatInterval(now, r.Every, r.Unit)
+	}
+
+	hour, minute := now.Hour(), now.Minute()
+	if r.HasClock {
+		hour, minute = r.Hour, r.Minute
+	}
+
+	t := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	for !t.After(now) || (r.HasWeekday && t.Weekday() != r.Weekday) {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return t
+}
+
+// String returns a human readable form of the schedule
+func (r *Recurrence) String() string {
+	return repeatString(r.Every, r.Unit)
+}
+
+func repeatString(every int, unit string) string {
+	if every == 1 {
+		return "every " + unit
+	}
+
+	return fmt.Sprintf("every %d %ss", every, unit)
+}
+
+// addRepeatInterval adds the interval using the calendar of the time's location,
+// so a reminder every day at 9:00 stays at 9:00 across daylight saving changes
+func addRepeatInterval(t time.Time, every int, unit string) time.Time {
+	switch unit {
+	case RepeatUnitHour:
+		return t.Add(time.Hour * time.Duration(every))
+	case RepeatUnitDay:
+		return t.AddDate(0, 0, every)
+	case RepeatUnitWeek:
+		return t.AddDate(0, 0, every*7)
+	case RepeatUnitMonth:
+		return t.AddDate(0, every, 0)
+	}
+
+	return t.AddDate(0, 0, every)
+}
+
+// ParseClock parses a time of day such as "9:00", "17:30", "9am", "9:30pm", "noon" and "midnight"
+func ParseClock(s string) (hour, minute int, ok bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "noon", "midday":
+		return 12, 0, true
+	case "midnight":
+		return 0, 0, true
+	}
+
+	pm := strings.HasSuffix(s, "pm")
+	am := strings.HasSuffix(s, "am")
+	if pm || am {
+		s = strings.TrimSpace(s[:len(s)-2])
+	}
+
+	hourStr, minuteStr := s, "0"
+	if idx := strings.IndexAny(s, ":."); idx != -1 {
+		hourStr, minuteStr = s[:idx], s[idx+1:]
+	}
+
+	hour, err := strconv.Atoi(hourStr)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	minute, err = strconv.Atoi(minuteStr)
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, 0, false
+	}
+
+	if pm || am {
+		if hour < 1 || hour > 12 {
+			return 0, 0, false
+		}
+
+		hour %= 12
+		if pm {
+			hour += 12
+		}
+	}
+
+	if hour < 0 || hour > 23 {
+		return 0, 0, false
+	}
+
+	return hour, minute, true
+}
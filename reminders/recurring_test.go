@@ -0,0 +1,175 @@
+package reminders
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseClock(t *testing.T) {
+	cases := []struct {
+		Input          string
+		ExpectedHour   int
+		ExpectedMinute int
+		ExpectedOK     bool
+	}{
+		{"9:00", 9, 0, true},
+		{"17:30", 17, 30, true},
+		{"7.15", 7, 15, true},
+		{"9am", 9, 0, true},
+		{"9:30pm", 21, 30, true},
+		{"9 PM", 21, 0, true},
+		{"12am", 0, 0, true},
+		{"12pm", 12, 0, true},
+		{"noon", 12, 0, true},
+		{"midnight", 0, 0, true},
+		{"13pm", 0, 0, false},
+		{"0am", 0, 0, false},
+		{"24:00", 0, 0, false},
+		{"9:60", 0, 0, false},
+		{"lunch", 0, 0, false},
+		{"", 0, 0, false},
+	}
+
+	for _, v := range cases {
+		t.Run(fmt.Sprintf("Input %q", v.Input), func(t *testing.T) {
+			hour, minute, ok := ParseClock(v.Input)
+			if ok != v.ExpectedOK {
+				t.Fatalf("Mismatched ok, GOT %t EXPECTED %t", ok, v.ExpectedOK)
+			}
+
+			if hour != v.ExpectedHour || minute != v.ExpectedMinute {
+				t.Errorf("GOT %02d:%02d EXPECTED %02d:%02d", hour, minute, v.ExpectedHour, v.ExpectedMinute)
+			}
+		})
+	}
+}
+
+func TestParseRecurrence(t *testing.T) {
+	cases := []struct {
+		Input        string
+		Expected     *Recurrence
+		ExpectedRest string
+	}{
+		{"every day at 9:00 water the plants", &Recurrence{Every: 1, Unit: RepeatUnitDay, Hour: 9, HasClock: true}, "water the plants"},
+		{"every 2 weeks standup", &Recurrence{Every: 2, Unit: RepeatUnitWeek}, "standup"},
+		{"12 hours stretch", &Recurrence{Every: 12, Unit: RepeatUnitHour}, "stretch"},
+		{"every 3 months pay rent", &Recurrence{Every: 3, Unit: RepeatUnitMonth}, "pay rent"},
+		{"monday at 18:30 gym", &Recurrence{Every: 1, Unit: RepeatUnitWeek, Weekday: time.Monday, HasWeekday: true, Hour: 18, Minute: 30, HasClock: true}, "gym"},
+		{"every fridays pizza", &Recurrence{Every: 1, Unit: RepeatUnitWeek, Weekday: time.Friday, HasWeekday: true}, "pizza"},
+		{"every day", &Recurrence{Every: 1, Unit: RepeatUnitDay}, ""},
+
+		// invalid
+		{"every 0 days", nil, ""},
+		{"every 400 days", nil, ""},
+		{"every fortnight", nil, ""},
+		{"every day at lunch", nil, ""},
+		{"every 2 hours at 9:00", nil, ""},
+		{"", nil, ""},
+	}
+
+	for _, v := range cases {
+		t.Run(fmt.Sprintf("Input %q", v.Input), func(t *testing.T) {
+			rec, rest, err := ParseRecurrence(v.Input)
+			if v.Expected == nil {
+				if err == nil {
+					t.Errorf("Expected an error, GOT %#v", rec)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if *rec != *v.Expected {
+				t.Errorf("GOT %#v EXPECTED %#v", rec, v.Expected)
+			}
+			if rest != v.ExpectedRest {
+				t.Errorf("Mismatched rest, GOT %q EXPECTED %q", rest, v.ExpectedRest)
+			}
+		})
+	}
+}
+
+func TestRecurrenceFirst(t *testing.T) {
+	// a sunday
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		Recurrence *Recurrence
+		Expected   time.Time
+	}{
+		{&Recurrence{Every: 2, Unit: RepeatUnitHour}, now.Add(time.Hour * 2)},
+		{&Recurrence{Every: 1, Unit: RepeatUnitDay}, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)},
+		{&Recurrence{Every: 1, Unit: RepeatUnitMonth}, time.Date(2026, 11, 18, 12, 0, 0, 0, time.UTC)},
+		{&Recurrence{Every: 1, Unit: RepeatUnitDay, Hour: 18, HasClock: true}, time.Date(2026, 10, 18, 18, 0, 0, 0, time.UTC)},
+		{&Recurrence{Every: 1, Unit: RepeatUnitDay, Hour: 9, HasClock: true}, time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{&Recurrence{Every: 1, Unit: RepeatUnitDay, Hour: 12, HasClock: true}, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)},
+		{&Recurrence{Every: 1, Unit: RepeatUnitWeek, Weekday: time.Wednesday, HasWeekday: true}, time.Date(2026, 10, 21, 12, 0, 0, 0, time.UTC)},
+		{&Recurrence{Every: 1, Unit: RepeatUnitWeek, Weekday: time.Sunday, HasWeekday: true, Hour: 18, HasClock: true}, time.Date(2026, 10, 18, 18, 0, 0, 0, time.UTC)},
+		{&Recurrence{Every: 1, Unit: RepeatUnitWeek, Weekday: time.Sunday, HasWeekday: true, Hour: 9, HasClock: true}, time.Date(2026, 10, 25, 9, 0, 0, 0, time.UTC)},
+	}
+
+	for k, v := range cases {
+		t.Run(fmt.Sprintf("Case %d", k), func(t *testing.T) {
+			first := v.Recurrence.First(now)
+			if !first.Equal(v.Expected) {
+				t.Errorf("GOT %s EXPECTED %s", first, v.Expected)
+			}
+		})
+	}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	if err != nil {
+		t.Skip("timezone data not available: ", err)
+	}
+
+	cases := []struct {
+		Reminder *Reminder
+		Now      time.Time
+		Expected time.Time
+	}{
+		{ // 0, next day
+			&Reminder{When: time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC).Unix(), RepeatEvery: 1, RepeatUnit: RepeatUnitDay},
+			time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC),
+		},
+		{ // 1, missed occurrences are skipped
+			&Reminder{When: time.Date(2026, 10, 10, 9, 0, 0, 0, time.UTC).Unix(), RepeatEvery: 1, RepeatUnit: RepeatUnitDay},
+			time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC),
+		},
+		{ // 2, every few hours
+			&Reminder{When: time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC).Unix(), RepeatEvery: 4, RepeatUnit: RepeatUnitHour},
+			time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 18, 13, 0, 0, 0, time.UTC),
+		},
+		{ // 3, months
+			&Reminder{When: time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC).Unix(), RepeatEvery: 1, RepeatUnit: RepeatUnitMonth},
+			time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC),
+			time.Date(2026, 11, 18, 9, 0, 0, 0, time.UTC),
+		},
+		{ // 4, stays at the same time of day when daylight saving time ends
+			&Reminder{When: time.Date(2026, 10, 24, 9, 0, 0, 0, amsterdam).Unix(), RepeatEvery: 1, RepeatUnit: RepeatUnitDay, Timezone: "Europe/Amsterdam"},
+			time.Date(2026, 10, 24, 9, 0, 0, 0, amsterdam),
+			time.Date(2026, 10, 25, 9, 0, 0, 0, amsterdam),
+		},
+		{ // 5, weekly across daylight saving time
+			&Reminder{When: time.Date(2026, 10, 19, 18, 30, 0, 0, amsterdam).Unix(), RepeatEvery: 1, RepeatUnit: RepeatUnitWeek, Timezone: "Europe/Amsterdam"},
+			time.Date(2026, 10, 19, 18, 30, 0, 0, amsterdam),
+			time.Date(2026, 10, 26, 18, 30, 0, 0, amsterdam),
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(fmt.Sprintf("Case %d", k), func(t *testing.T) {
+			next := v.Reminder.nextOccurrence(v.Now)
+			if !next.Equal(v.Expected) {
+				t.Errorf("GOT %s EXPECTED %s", next, v.Expected)
+			}
+		})
+	}
+}
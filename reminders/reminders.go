@@ -23,6 +23,7 @@ func RegisterPlugin() {
 
 	p := &Plugin{}
 	common.RegisterPlugin(p)
+	mqueue.RegisterSource("reminder", p)
 }
 
 func (p *Plugin) PluginInfo() *common.PluginInfo {
@@ -40,6 +41,12 @@ type Reminder struct {
 	GuildID   int64
 	Message   string
 	When      int64
+
+	// RepeatEvery is 0 for reminders that only trigger once
+	RepeatEvery int
+	RepeatUnit  string
+	// Timezone is the name of the zone the repeat interval is calculated in
+	Timezone string
 }
 
 func (r *Reminder) UserIDInt() (i int64) {
@@ -52,32 +59,129 @@ func (r *Reminder) ChannelIDInt() (i int64) {
 	return
 }
 
+// IsRecurring returns true if the reminder is rescheduled after triggering instead of deleted
+func (r *Reminder) IsRecurring() bool {
+	return r.RepeatEvery > 0
+}
+
+func (r *Reminder) location() *time.Location {
+	if r.Timezone == "" {
+		return time.UTC
+	}
+
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
+
+// nextOccurrence returns the next time a recurring reminder should trigger after now,
+// skipping the ones that were missed
+func (r *Reminder) nextOccurrence(now time.Time) time.Time {
+	t := time.Unix(r.When, 0).In(r.location())
+	for !t.After(now) {
+		t = addRepeatInterval(t, r.RepeatEvery, r.RepeatUnit)
+	}
+
+	return t
+}
+
 func (r *Reminder) Trigger() error {
-	// remove the actual reminder
-	rows := common.GORM.Delete(r).RowsAffected
-	if rows < 1 {
-		logger.Info("Tried to execute multiple reminders at once")
+	// remove or reschedule the reminder before queueing it, making sure it's not triggered multiple times if this runs concurrently
+	if r.IsRecurring() {
+		next := r.nextOccurrence(time.Now())
+		rows := common.GORM.Model(&Reminder{}).Where("id = ? AND \"when\" = ?", r.ID, r.When).Update("when", next.Unix()).RowsAffected
+		if rows < 1 {
+			logger.Info("Tried to execute multiple reminders at once")
+			return nil
+		}
+
+		err := scheduledevents2.ScheduleEvent("reminders_check_user", r.GuildID, next, r.UserIDInt())
+		if err != nil {
+			r.undoTrigger()
+			return err
+		}
+	} else {
+		// remove the actual reminder
+		rows := common.GORM.Delete(r).RowsAffected
+		if rows < 1 {
+			logger.Info("Tried to execute multiple reminders at once")
+			return nil
+		}
 	}
 
 	logger.WithFields(logrus.Fields{"channel": r.ChannelID, "user": r.UserID, "message": r.Message, "id": r.ID}).Info("Triggered reminder")
 
-	mqueue.QueueMessage(&mqueue.QueuedElement{
+	// the queue retries sending on anything but discord errors, the snooze reactions are added once it's sent
+	err := mqueue.QueueMessage(&mqueue.QueuedElement{
 		Source:   "reminder",
-		SourceID: "",
+		SourceID: strconv.FormatUint(uint64(r.ID), 10),
 
 		Guild:   r.GuildID,
 		Channel: r.ChannelIDInt(),
 
-		MessageStr: "**Reminder** <@" + r.UserID + ">: " + r.Message,
+		MessageStr: "**Reminder** <@" + r.UserID + ">: " + r.Message + "\n" + snoozeHelp,
 		AllowedMentions: discordgo.AllowedMentions{
 			Users: []int64{r.UserIDInt()},
 		},
 
 		Priority: 10, // above all feeds
 	})
+	if err != nil {
+		// put it back so it's triggered again when the scheduled event is retried
+		r.undoTrigger()
+		return err
+	}
+
 	return nil
 }
 
+// undoTrigger restores the reminder to how it was before it was triggered
+func (r *Reminder) undoTrigger() {
+	var err error
+	if r.IsRecurring() {
+		err = common.GORM.Model(&Reminder{}).Where("id = ?", r.ID).Update("when", r.When).Error
+	} else {
+		err = common.GORM.Unscoped().Model(&Reminder{}).Where("id = ?", r.ID).Update("deleted_at", nil).Error
+	}
+
+	if err != nil {
+		logger.WithError(err).WithField("id", r.ID).Error("failed restoring reminder after failing to trigger it")
+	}
+}
+
+var _ mqueue.PluginWithSourceDisabler = (*Plugin)(nil)
+var _ mqueue.PluginWithMessageSent = (*Plugin)(nil)
+
+// DisableFeed implements mqueue.PluginWithSourceDisabler, recurring reminders in channels that no longer exist are removed
+func (p *Plugin) DisableFeed(elem *mqueue.QueuedElement, err error) {
+	if code, _ := common.DiscordError(err); code != discordgo.ErrCodeUnknownChannel {
+		return
+	}
+
+	err = common.GORM.Where("id = ?", elem.SourceID).Delete(&Reminder{}).Error
+	if err != nil {
+		logger.WithError(err).Error("failed removing reminder in nonexistant channel")
+	}
+}
+
+// MessageSent implements mqueue.PluginWithMessageSent, adding the snooze reactions to the delivered reminder
+func (p *Plugin) MessageSent(elem *mqueue.QueuedElement, msg *discordgo.Message) {
+	// one time reminders are soft deleted by the time they're sent
+	var r Reminder
+	err := common.GORM.Unscoped().Where("id = ?", elem.SourceID).First(&r).Error
+	if err != nil {
+		if err != gorm.ErrRecordNotFound {
+			logger.WithError(err).WithField("id", elem.SourceID).Error("failed retrieving sent reminder")
+		}
+		return
+	}
+
+	addSnoozeReactions(&r, msg)
+}
+
 func GetUserReminders(userID int64) (results []*Reminder, err error) {
 	err = common.GORM.Where(&Reminder{UserID: discordgo.StrID(userID)}).Find(&results).Error
 	if err == gorm.ErrRecordNotFound {
@@ -114,6 +218,29 @@ func NewReminder(userID int64, guildID int64, channelID int64, message string, w
 	return reminder, err
 }
 
+// NewRecurringReminder creates a reminder that triggers first at the given time, and then repeats with the interval
+// calculated in the location of the first time
+func NewRecurringReminder(userID int64, guildID int64, channelID int64, message string, first time.Time, every int, unit string) (*Reminder, error) {
+	reminder := &Reminder{
+		UserID:      discordgo.StrID(userID),
+		ChannelID:   discordgo.StrID(channelID),
+		Message:     message,
+		When:        first.Unix(),
+		GuildID:     guildID,
+		RepeatEvery: every,
+		RepeatUnit:  unit,
+		Timezone:    first.Location().String(),
+	}
+
+	err := common.GORM.Create(reminder).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = scheduledevents2.ScheduleEvent("reminders_check_user", guildID, first, userID)
+	return reminder, err
+}
+
 func checkUserEvtHandlerLegacy(evt string) error {
 	split := strings.Split(evt, ":")
 	if len(split) < 2 {
@@ -0,0 +1,130 @@
+package reminders
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/yagpdb/bot/eventsystem"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/mediocregopher/radix/v3"
+)
+
+type snoozeOption struct {
+	Emoji    string
+	Duration time.Duration
+}
+
+var snoozeOptions = []*snoozeOption{
+	{Emoji: "⏰", Duration: time.Minute * 10},
+	{Emoji: "🕐", Duration: time.Hour},
+	{Emoji: "📅", Duration: time.Hour * 24},
+}
+
+const snoozeHelp = "*React to snooze: ⏰ 10 minutes, 🕐 1 hour, 📅 1 day*"
+
+// how long a delivered reminder can be snoozed for, in seconds
+const snoozeExpiry = 60 * 60 * 24
+
+func KeySnooze(messageID int64) string {
+	return "reminders_snooze:" + strconv.FormatInt(messageID, 10)
+}
+
+type snoozeData struct {
+	UserID    int64
+	GuildID   int64
+	ChannelID int64
+	Message   string
+}
+
+// addSnoozeReactions adds the snooze reactions to a delivered reminder and remembers what reminder it was
+func addSnoozeReactions(r *Reminder, msg *discordgo.Message) {
+	serialized, err := json.Marshal(&snoozeData{
+		UserID:    r.UserIDInt(),
+		GuildID:   r.GuildID,
+		ChannelID: r.ChannelIDInt(),
+		Message:   r.Message,
+	})
+	if err != nil {
+		logger.WithError(err).Error("failed serializing snooze data")
+		return
+	}
+
+	err = common.RedisPool.Do(radix.FlatCmd(nil, "SET", KeySnooze(msg.ID), serialized, "EX", snoozeExpiry))
+	if err != nil {
+		logger.WithError(err).Error("failed storing snooze data")
+		return
+	}
+
+	for _, v := range snoozeOptions {
+		err = common.BotSession.MessageReactionAdd(msg.ChannelID, msg.ID, v.Emoji)
+		if err != nil {
+			// most likely missing perms, no point in trying the rest
+			return
+		}
+	}
+}
+
+func handleReactionAddSnooze(evt *eventsystem.EventData) {
+	ra := evt.MessageReactionAdd()
+	if ra.UserID == common.BotUser.ID {
+		return
+	}
+
+	var option *snoozeOption
+	for _, v := range snoozeOptions {
+		if v.Emoji == ra.Emoji.Name {
+			option = v
+			break
+		}
+	}
+
+	if option == nil {
+		return
+	}
+
+	var serialized []byte
+	err := common.RedisPool.Do(radix.Cmd(&serialized, "GET", KeySnooze(ra.MessageID)))
+	if err != nil || len(serialized) < 1 {
+		return
+	}
+
+	var data snoozeData
+	err = json.Unmarshal(serialized, &data)
+	if err != nil {
+		logger.WithError(err).Error("failed decoding snooze data")
+		return
+	}
+
+	if data.UserID != ra.UserID {
+		return
+	}
+
+	// only allow snoozing a delivered reminder once
+	var deleted int
+	err = common.RedisPool.Do(radix.Cmd(&deleted, "DEL", KeySnooze(ra.MessageID)))
+	if err != nil || deleted < 1 {
+		return
+	}
+
+	currentReminders, _ := GetUserReminders(data.UserID)
+	if len(currentReminders) >= 25 {
+		common.BotSession.ChannelMessageSend(ra.ChannelID, "You can have a maximum of 25 active reminders, list your reminders with the `reminders` command")
+		return
+	}
+
+	when := time.Now().Add(option.Duration)
+	_, err = NewReminder(data.UserID, data.GuildID, data.ChannelID, data.Message, when)
+	if err != nil {
+		logger.WithError(err).WithField("user", data.UserID).Error("failed snoozing reminder")
+		return
+	}
+
+	common.BotSession.MessageReactionsRemoveAll(ra.ChannelID, ra.MessageID)
+	common.BotSession.ChannelMessageSendComplex(ra.ChannelID, &discordgo.MessageSend{
+		Content:         fmt.Sprintf("Snoozed, I'll remind you again in %s", common.HumanizeDuration(common.DurationPrecisionMinutes, option.Duration)),
+		AllowedMentions: discordgo.AllowedMentions{},
+	})
+}
@@ -0,0 +1,198 @@
+package reminders
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jonas747/yagpdb/commands"
+)
+
+var (
+	ErrInvalidReminderTime = errors.New("Couldn't understand when to remind you, examples: `1h30m`, `in 2 days`, `at 17:30`, `on 2026-12-24 18:00`, `tomorrow at noon`, `next monday`")
+	ErrReminderTimeInPast  = errors.New("That time is in the past")
+)
+
+// ParseReminderTime parses the time at the start of the input, returning the rest of the input which is the reminder message.
+// The time can either be a duration from now, such as "1h30m" or "in 2 days", or a absolute time such as "at 17:30",
+// "on 2026-12-24 18:00", "tomorrow at noon" or "next monday", which is interpreted in the location of now.
+// Absolute times without a time of day use the current time of day.
+func ParseReminderTime(input string, now time.Time) (when time.Time, rest string, absolute bool, err error) {
+	fields := strings.Fields(input)
+	if len(fields) < 1 {
+		return time.Time{}, "", false, ErrInvalidReminderTime
+	}
+
+	// relative durations, "1h30m" or "in 1h30m"
+	durIndex := 0
+	if strings.EqualFold(fields[0], "in") && len(fields) > 1 {
+		durIndex = 1
+	}
+
+	// "2 days" or "in 2 days", with the number and unit as separate words
+	if len(fields) > durIndex+1 && isDurationUnit(fields[durIndex+1]) {
+		if dur, ok := parseReminderDuration(fields[durIndex] + fields[durIndex+1]); ok {
+			return now.Add(dur), strings.Join(fields[durIndex+2:], " "), false, nil
+		}
+	}
+
+	if dur, ok := parseReminderDuration(fields[durIndex]); ok {
+		return now.Add(dur), strings.Join(fields[durIndex+1:], " "), false, nil
+	}
+
+	var (
+		date     time.Time
+		hasDate  bool
+		hour     = now.Hour()
+		minute   = now.Minute()
+		hasClock bool
+	)
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	i := 0
+OUTER:
+	for i < len(fields) {
+		word := strings.ToLower(fields[i])
+
+		switch word {
+		case "on":
+			// only a part of the time if followed by a date, "on 2026-12-24" or "on monday"
+			if i+1 >= len(fields) || !isReminderDate(fields[i+1]) {
+				break OUTER
+			}
+
+			i++
+			continue
+		case "today":
+			date, hasDate = today, true
+		case "tomorrow":
+			date, hasDate = today.AddDate(0, 0, 1), true
+		case "next":
+			if i+1 >= len(fields) {
+				break OUTER
+			}
+
+			wd, ok := weekdays[strings.ToLower(fields[i+1])]
+			if !ok {
+				break OUTER
+			}
+
+			date, hasDate = nextWeekday(today, wd), true
+			i++
+		case "at":
+			if i+1 >= len(fields) {
+				break OUTER
+			}
+
+			h, m, ok := ParseClock(fields[i+1])
+			if !ok {
+				if hasDate {
+					// "tomorrow at the office", the rest is part of the message
+					break OUTER
+				}
+
+				return time.Time{}, "", true, ErrInvalidClock
+			}
+
+			hour, minute, hasClock = h, m, true
+			i++
+		default:
+			if wd, ok := weekdays[word]; ok && !hasDate {
+				date, hasDate = nextWeekday(today, wd), true
+				break
+			}
+
+			if t, err := time.ParseInLocation("2006-01-02", word, now.Location()); err == nil && !hasDate {
+				date, hasDate = t, true
+				break
+			}
+
+			// only things that can't be mistaken for the start of the message are accepted as a time of day without "at"
+			if h, m, ok := ParseClock(word); ok && !hasClock && looksLikeClock(word) {
+				hour, minute, hasClock = h, m, true
+				break
+			}
+
+			break OUTER
+		}
+
+		i++
+	}
+
+	if !hasDate && !hasClock {
+		return time.Time{}, "", false, ErrInvalidReminderTime
+	}
+
+	if !hasDate {
+		date = today
+	}
+
+	when = time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, now.Location())
+	if !when.After(now) {
+		if hasDate {
+			return time.Time{}, "", true, ErrReminderTimeInPast
+		}
+
+		// "at 9:00" when it's already past 9:00 means tomorrow
+		when = when.AddDate(0, 0, 1)
+	}
+
+	return when, strings.Join(fields[i:], " "), true, nil
+}
+
+func parseReminderDuration(s string) (time.Duration, bool) {
+	// the first character has to be a number, same as the duration arg
+	r, _ := utf8.DecodeRuneInString(s)
+	if !unicode.IsNumber(r) || strings.ContainsAny(s, ":.-/") {
+		return 0, false
+	}
+
+	dur, err := commands.ParseDuration(s)
+	if err != nil || dur <= 0 {
+		return 0, false
+	}
+
+	return dur, true
+}
+
+var durationUnits = map[string]bool{
+	"s": true, "sec": true, "secs": true, "second": true, "seconds": true,
+	"m": true, "min": true, "mins": true, "minute": true, "minutes": true,
+	"h": true, "hr": true, "hrs": true, "hour": true, "hours": true,
+	"d": true, "day": true, "days": true,
+	"w": true, "week": true, "weeks": true,
+	"mo": true, "month": true, "months": true,
+	"y": true, "year": true, "years": true,
+}
+
+func isDurationUnit(s string) bool {
+	return durationUnits[strings.ToLower(s)]
+}
+
+// nextWeekday returns the next day with the weekday after today
+func nextWeekday(today time.Time, wd time.Weekday) time.Time {
+	days := (int(wd) - int(today.Weekday()) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+
+	return today.AddDate(0, 0, days)
+}
+
+func isReminderDate(s string) bool {
+	s = strings.ToLower(s)
+	if _, ok := weekdays[s]; ok {
+		return true
+	}
+
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
+
+func looksLikeClock(s string) bool {
+	s = strings.ToLower(s)
+	return strings.Contains(s, ":") || strings.HasSuffix(s, "am") || strings.HasSuffix(s, "pm") || s == "noon" || s == "midnight"
+}
@@ -0,0 +1,72 @@
+package reminders
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseReminderTime(t *testing.T) {
+	// a sunday
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		Input            string
+		ExpectedWhen     time.Time
+		ExpectedRest     string
+		ExpectedAbsolute bool
+		ExpectedErr      error
+	}{
+		// durations
+		{"1h30m take out the trash", now.Add(time.Minute * 90), "take out the trash", false, nil},
+		{"in 2 days call mom", now.Add(time.Hour * 48), "call mom", false, nil},
+		{"2 days call mom", now.Add(time.Hour * 48), "call mom", false, nil},
+		{"in 10 minutes", now.Add(time.Minute * 10), "", false, nil},
+		{"IN 1w check the logs", now.Add(time.Hour * 24 * 7), "check the logs", false, nil},
+
+		// times of day
+		{"at 17:30 dinner", time.Date(2026, 10, 18, 17, 30, 0, 0, time.UTC), "dinner", true, nil},
+		{"17:30 dinner", time.Date(2026, 10, 18, 17, 30, 0, 0, time.UTC), "dinner", true, nil},
+		{"at 9:00 standup", time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC), "standup", true, nil},
+		{"at 12:00 lunch", time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC), "lunch", true, nil},
+		{"9pm go to bed", time.Date(2026, 10, 18, 21, 0, 0, 0, time.UTC), "go to bed", true, nil},
+		{"at midnight", time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), "", true, nil},
+
+		// dates
+		{"tomorrow at noon lunch", time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC), "lunch", true, nil},
+		{"tomorrow at the office", time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC), "at the office", true, nil},
+		{"today at 18:00 call", time.Date(2026, 10, 18, 18, 0, 0, 0, time.UTC), "call", true, nil},
+		{"on 2026-12-24 18:00 presents", time.Date(2026, 12, 24, 18, 0, 0, 0, time.UTC), "presents", true, nil},
+		{"2026-12-24 at 6pm presents", time.Date(2026, 12, 24, 18, 0, 0, 0, time.UTC), "presents", true, nil},
+		{"next monday review", time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC), "review", true, nil},
+		{"on sunday at 9:00 brunch", time.Date(2026, 10, 25, 9, 0, 0, 0, time.UTC), "brunch", true, nil},
+		{"friday 17:00 drinks", time.Date(2026, 10, 23, 17, 0, 0, 0, time.UTC), "drinks", true, nil},
+
+		// errors
+		{"today at 9:00", time.Time{}, "", true, ErrReminderTimeInPast},
+		{"on 2020-01-01 party", time.Time{}, "", true, ErrReminderTimeInPast},
+		{"at lunch", time.Time{}, "", true, ErrInvalidClock},
+		{"on the way home", time.Time{}, "", false, ErrInvalidReminderTime},
+		{"hello world", time.Time{}, "", false, ErrInvalidReminderTime},
+		{"", time.Time{}, "", false, ErrInvalidReminderTime},
+	}
+
+	for _, v := range cases {
+		t.Run(fmt.Sprintf("Input %q", v.Input), func(t *testing.T) {
+			when, rest, absolute, err := ParseReminderTime(v.Input, now)
+			if err != v.ExpectedErr {
+				t.Fatalf("Mismatched error, GOT %v EXPECTED %v", err, v.ExpectedErr)
+			}
+
+			if !when.Equal(v.ExpectedWhen) {
+				t.Errorf("Mismatched time, GOT %s EXPECTED %s", when, v.ExpectedWhen)
+			}
+			if rest != v.ExpectedRest {
+				t.Errorf("Mismatched rest, GOT %q EXPECTED %q", rest, v.ExpectedRest)
+			}
+			if absolute != v.ExpectedAbsolute {
+				t.Errorf("Mismatched absolute, GOT %t EXPECTED %t", absolute, v.ExpectedAbsolute)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package reminders
+
+import (
+	"context"
+
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/yagpdb/common"
+)
+
+var _ common.PluginWithUserData = (*Plugin)(nil)
+
+// ExportUserData exports all the users reminders, including the already triggered ones that are only soft deleted
+func (p *Plugin) ExportUserData(ctx context.Context, userID int64) (interface{}, error) {
+	var reminders []*Reminder
+	err := common.GORM.Unscoped().Where(&Reminder{UserID: discordgo.StrID(userID)}).Order("id asc").Find(&reminders).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{"reminders": reminders}, nil
+}
+
+// EraseUserData permanently deletes the users reminders, soft deleting them would leave the messages in the database.
+// The scheduled events left behind won't find anything to trigger
+func (p *Plugin) EraseUserData(ctx context.Context, userID int64) error {
+	return common.GORM.Unscoped().Where(&Reminder{UserID: discordgo.StrID(userID)}).Delete(&Reminder{}).Error
+}
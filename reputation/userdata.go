@@ -0,0 +1,50 @@
+package reputation
+
+import (
+	"context"
+
+	"emperror.dev/errors"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/reputation/models"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+var _ common.PluginWithUserData = (*Plugin)(nil)
+
+type userDataExport struct {
+	Users models.ReputationUserSlice `json:"reputation"`
+	Log   models.ReputationLogSlice  `json:"reputation_log"`
+}
+
+func (p *Plugin) ExportUserData(ctx context.Context, userID int64) (interface{}, error) {
+	export := &userDataExport{}
+
+	var err error
+	export.Users, err = models.ReputationUsers(models.ReputationUserWhere.UserID.EQ(userID)).AllG(ctx)
+	if err != nil {
+		return nil, errors.WrapIf(err, "reputation_users")
+	}
+
+	export.Log, err = models.ReputationLogs(qm.Where("sender_id = ? OR receiver_id = ?", userID, userID), qm.OrderBy("id asc")).AllG(ctx)
+	if err != nil {
+		return nil, errors.WrapIf(err, "reputation_log")
+	}
+
+	return export, nil
+}
+
+// EraseUserData deletes the users reputation, the log entries are kept for the other user involved but with this user anonymized
+func (p *Plugin) EraseUserData(ctx context.Context, userID int64) error {
+	_, err := models.ReputationUsers(models.ReputationUserWhere.UserID.EQ(userID)).DeleteAll(ctx, common.PQ)
+	if err != nil {
+		return errors.WrapIf(err, "reputation_users")
+	}
+
+	_, err = models.ReputationLogs(models.ReputationLogWhere.SenderID.EQ(userID)).UpdateAllG(ctx, models.M{"sender_id": 0, "sender_username": "Deleted user"})
+	if err != nil {
+		return errors.WrapIf(err, "reputation_log")
+	}
+
+	_, err = models.ReputationLogs(models.ReputationLogWhere.ReceiverID.EQ(userID)).UpdateAllG(ctx, models.M{"receiver_id": 0, "receiver_username": "Deleted user"})
+	return errors.WrapIf(err, "reputation_log")
+}
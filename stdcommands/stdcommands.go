@@ -44,6 +44,7 @@ import (
 	"github.com/jonas747/yagpdb/stdcommands/topservers"
 	"github.com/jonas747/yagpdb/stdcommands/unbanserver"
 	"github.com/jonas747/yagpdb/stdcommands/undelete"
+	"github.com/jonas747/yagpdb/stdcommands/userdata"
 	"github.com/jonas747/yagpdb/stdcommands/viewperms"
 	"github.com/jonas747/yagpdb/stdcommands/weather"
 	"github.com/jonas747/yagpdb/stdcommands/wouldyourather"
@@ -117,6 +118,7 @@ func (p *Plugin) AddCommands() {
 		sleep.Command,
 		toggledbg.Command,
 		globalrl.Command,
+		userdata.Command,
 	)
 
 }
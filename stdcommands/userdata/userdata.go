@@ -0,0 +1,80 @@
+package userdata
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/jonas747/dcmd"
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/yagpdb/commands"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/stdcommands/util"
+	"github.com/jonas747/yagpdb/web"
+)
+
+// maxDMFileSize is discord's upload limit for the bot, bigger exports have to be downloaded from the admin panel
+const maxDMFileSize = 8 * 1024 * 1024
+
+var Command = &commands.YAGCommand{
+	Cooldown:             5,
+	CmdCategory:          commands.CategoryDebug,
+	HideFromCommandsPage: true,
+	Name:                 "userdata",
+	Description:          "Exports or erases all the data stored about a user, for handling data requests. The export is sent in DM's, it can also be downloaded from the admin panel",
+	LongDescription:      "Use `-erase -confirm` to delete or anonymize the data instead, this can't be undone.",
+	HideFromHelp:         true,
+	RequiredArgs:         1,
+	Arguments: []*dcmd.ArgDef{
+		{Name: "user", Type: dcmd.UserID},
+	},
+	ArgSwitches: []*dcmd.ArgDef{
+		{Switch: "erase", Name: "Erase the data instead of exporting it"},
+		{Switch: "confirm", Name: "Confirm erasing"},
+	},
+	RunFunc: util.RequireBotAdmin(func(data *dcmd.Data) (interface{}, error) {
+		userID := data.Args[0].Int64()
+
+		if data.Switch("erase").Bool() {
+			if !data.Switch("confirm").Bool() {
+				return fmt.Sprintf("This will delete or anonymize everything stored about `%d` across all plugins, run it again with `-confirm` to continue.", userID), nil
+			}
+
+			erased, err := common.EraseUserData(data.Context(), userID)
+			if err != nil {
+				return fmt.Sprintf("Erased data in: %s\n\nFailed in some plugins, check the logs: `%s`", strings.Join(erased, ", "), err.Error()), err
+			}
+
+			return fmt.Sprintf("Erased the data of `%d` in: %s", userID, strings.Join(erased, ", ")), nil
+		}
+
+		archive, err := common.ExportUserDataArchive(data.Context(), userID)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(archive) > maxDMFileSize {
+			return fmt.Sprintf("The export is %.1fMB which is too big to send in DM's, download it from the admin panel instead: <%s/admin/userdata?user=%d>",
+				float64(len(archive))/1024/1024, web.BaseURL(), userID), nil
+		}
+
+		channel, err := common.BotSession.UserChannelCreate(data.Msg.Author.ID)
+		if err != nil {
+			return "Failed creating a DM channel, are your DM's open?", err
+		}
+
+		_, err = common.BotSession.ChannelMessageSendComplex(channel.ID, &discordgo.MessageSend{
+			Content: fmt.Sprintf("Data stored about `%d`", userID),
+			File: &discordgo.File{
+				ContentType: "application/zip",
+				Name:        fmt.Sprintf("userdata-%d.zip", userID),
+				Reader:      bytes.NewReader(archive),
+			},
+		})
+		if err != nil {
+			return "Failed sending the export in DM's, are your DM's open?", err
+		}
+
+		return "Sent the export in DM's", nil
+	}),
+}
@@ -0,0 +1,236 @@
+package tickets
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/dstate/v2"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/tickets/models"
+	"github.com/volatiletech/null"
+	"github.com/volatiletech/sqlboiler/boil"
+)
+
+// setTicketClaim records the staff member responsible for the ticket, or clears it if claimedBy is nil.
+// conf should have the settings of the ticket type applied
+func setTicketClaim(ctx context.Context, gs *dstate.GuildState, cs *dstate.ChannelState, conf *models.TicketConfig, ticket *models.Ticket, claimedBy *discordgo.User) error {
+	previous := ticket.ClaimedByID
+
+	if claimedBy != nil {
+		ticket.ClaimedByID = claimedBy.ID
+		ticket.ClaimedByUsername = claimedBy.Username + "#" + claimedBy.Discriminator
+		ticket.ClaimedAt = null.TimeFrom(time.Now())
+	} else {
+		ticket.ClaimedByID = 0
+		ticket.ClaimedByUsername = ""
+		ticket.ClaimedAt = null.Time{}
+	}
+
+	_, err := ticket.UpdateG(ctx, boil.Whitelist("claimed_by_id", "claimed_by_username", "claimed_at"))
+	if err != nil {
+		return err
+	}
+
+	topic := fmt.Sprintf("Ticket #%d - %s", ticket.LocalID, ticket.Title)
+	if claimedBy != nil {
+		topic += " | Claimed by " + ticket.ClaimedByUsername
+	}
+
+	_, err = common.BotSession.ChannelEditComplex(cs.ID, &discordgo.ChannelEdit{Topic: topic})
+	if err != nil {
+		logger.WithError(err).WithField("guild", gs.ID).Error("[tickets] failed updating ticket topic")
+	}
+
+	if conf.ClaimRestrictsTalking {
+		updateClaimOverwrites(gs, cs, conf, ticket, previous)
+	}
+
+	return nil
+}
+
+// updateClaimOverwrites makes the mod roles read only while the ticket is claimed, and gives the staff member that claimed it perms to talk
+func updateClaimOverwrites(gs *dstate.GuildState, cs *dstate.ChannelState, conf *models.TicketConfig, ticket *models.Ticket, previousClaim int64) {
+	modOverwrites := make([]discordgo.PermissionOverwrite, 0)
+
+	gs.RLock()
+	for _, ow := range cs.PermissionOverwrites {
+		if ow.Type == "role" && common.ContainsInt64Slice(conf.ModRoles, ow.ID) && !common.ContainsInt64Slice(conf.AdminRoles, ow.ID) {
+			modOverwrites = append(modOverwrites, *ow)
+		}
+	}
+	gs.RUnlock()
+
+	for _, v := range modOverwrites {
+		if (v.Allow & discordgo.PermissionReadMessages) != discordgo.PermissionReadMessages {
+			// the mods was removed from the ticket through admins only mode
+			continue
+		}
+
+		var err error
+		if ticket.ClaimedByID != 0 {
+			err = common.BotSession.ChannelPermissionSet(cs.ID, v.ID, "role", v.Allow&^discordgo.PermissionSendMessages, v.Deny|discordgo.PermissionSendMessages)
+		} else {
+			err = common.BotSession.ChannelPermissionSet(cs.ID, v.ID, "role", v.Allow|InTicketPerms, v.Deny&^InTicketPerms)
+		}
+
+		if err != nil {
+			logger.WithError(err).WithField("guild", gs.ID).Error("[tickets] failed to update channel overwrite")
+		}
+	}
+
+	if previousClaim != 0 && previousClaim != ticket.AuthorID && previousClaim != ticket.ClaimedByID {
+		err := common.BotSession.ChannelPermissionDelete(cs.ID, previousClaim)
+		if err != nil {
+			logger.WithError(err).WithField("guild", gs.ID).Error("[tickets] failed to remove channel overwrite")
+		}
+	}
+
+	if ticket.ClaimedByID != 0 {
+		err := common.BotSession.ChannelPermissionSet(cs.ID, ticket.ClaimedByID, "member", InTicketPerms, 0)
+		if err != nil {
+			logger.WithError(err).WithField("guild", gs.ID).Error("[tickets] failed to create channel overwrite")
+		}
+	}
+}
+
+type TicketStaffStats struct {
+	UserID          int64
+	Username        string
+	Closed          int
+	MedianTimeClose time.Duration
+
+	// number of tickets opened in the period the staff member responded to, and the median time it took them
+	Responded           int
+	MedianFirstResponse time.Duration
+}
+
+type TicketStats struct {
+	Opened int
+	Closed int
+
+	// zero if there are no tickets with a response from staff in the period
+	MedianFirstResponse time.Duration
+	MedianTimeClose     time.Duration
+
+	Staff []*TicketStaffStats
+}
+
+// GetTicketStats returns stats for the tickets opened or closed after since,
+// first response times are based on the participants which are recorded when tickets are closed
+func GetTicketStats(ctx context.Context, guildID int64, since time.Time) (*TicketStats, error) {
+	stats := &TicketStats{}
+
+	const qCounts = `SELECT
+	COUNT(*) FILTER (WHERE created_at > $2),
+	COUNT(*) FILTER (WHERE closed_at > $2),
+	COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM closed_at - created_at)) FILTER (WHERE closed_at > $2), 0)
+FROM tickets WHERE guild_id = $1`
+
+	var medianClose float64
+	err := common.PQ.QueryRowContext(ctx, qCounts, guildID, since).Scan(&stats.Opened, &stats.Closed, &medianClose)
+	if err != nil {
+		return nil, err
+	}
+	stats.MedianTimeClose = secondsDuration(medianClose)
+
+	const qFirstResponse = `SELECT COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM first_response - created_at)), 0)
+FROM (
+	SELECT t.created_at, MIN(p.first_message_at) AS first_response
+	FROM tickets t
+	JOIN ticket_participants p ON p.ticket_guild_id = t.guild_id AND p.ticket_local_id = t.local_id
+	WHERE t.guild_id = $1 AND t.created_at > $2 AND p.is_staff AND p.user_id != t.author_id AND p.first_message_at IS NOT NULL
+	GROUP BY t.guild_id, t.local_id, t.created_at
+) AS responses`
+
+	var medianResponse float64
+	err = common.PQ.QueryRowContext(ctx, qFirstResponse, guildID, since).Scan(&medianResponse)
+	if err != nil {
+		return nil, err
+	}
+	stats.MedianFirstResponse = secondsDuration(medianResponse)
+
+	const qStaff = `SELECT claimed_by_id, MAX(claimed_by_username), COUNT(*), percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM closed_at - created_at))
+FROM tickets
+WHERE guild_id = $1 AND closed_at > $2 AND claimed_by_id != 0
+GROUP BY claimed_by_id
+ORDER BY COUNT(*) DESC
+LIMIT 10`
+
+	rows, err := common.PQ.QueryContext(ctx, qStaff, guildID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var seconds float64
+		staff := &TicketStaffStats{}
+		err = rows.Scan(&staff.UserID, &staff.Username, &staff.Closed, &seconds)
+		if err != nil {
+			return nil, err
+		}
+
+		staff.MedianTimeClose = secondsDuration(seconds)
+		stats.Staff = append(stats.Staff, staff)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = addStaffFirstResponses(ctx, stats, guildID, since)
+	return stats, err
+}
+
+// addStaffFirstResponses adds the median time each staff member took to first respond to the tickets they took part in,
+// staff that responded to tickets without claiming any are added after the ones that did
+func addStaffFirstResponses(ctx context.Context, stats *TicketStats, guildID int64, since time.Time) error {
+	const q = `SELECT p.user_id, MAX(p.username || '#' || p.discrim), COUNT(*), percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM p.first_message_at - t.created_at))
+FROM tickets t
+JOIN ticket_participants p ON p.ticket_guild_id = t.guild_id AND p.ticket_local_id = t.local_id
+WHERE t.guild_id = $1 AND t.created_at > $2 AND p.is_staff AND p.user_id != t.author_id AND p.first_message_at IS NOT NULL
+GROUP BY p.user_id
+ORDER BY COUNT(*) DESC
+LIMIT 10`
+
+	rows, err := common.PQ.QueryContext(ctx, q, guildID, since)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID int64
+		var username string
+		var responded int
+		var seconds float64
+		err = rows.Scan(&userID, &username, &responded, &seconds)
+		if err != nil {
+			return err
+		}
+
+		var staff *TicketStaffStats
+		for _, v := range stats.Staff {
+			if v.UserID == userID {
+				staff = v
+				break
+			}
+		}
+
+		if staff == nil {
+			staff = &TicketStaffStats{UserID: userID, Username: username}
+			stats.Staff = append(stats.Staff, staff)
+		}
+
+		staff.Responded = responded
+		staff.MedianFirstResponse = secondsDuration(seconds)
+	}
+
+	return rows.Err()
+}
+
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,250 @@
+package tickets
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/yagpdb/bot"
+	"github.com/jonas747/yagpdb/bot/eventsystem"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/common/scheduledevents2"
+	seventsmodels "github.com/jonas747/yagpdb/common/scheduledevents2/models"
+	"github.com/jonas747/yagpdb/tickets/models"
+	"github.com/volatiletech/null"
+	"github.com/volatiletech/sqlboiler/boil"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+type ScheduledInactivityCheckData struct {
+	TicketLocalID int64
+}
+
+func scheduleInactivityCheck(guildID, localID int64, at time.Time) error {
+	return scheduledevents2.ScheduleEvent("tickets_inactivity_check", guildID, at, &ScheduledInactivityCheckData{
+		TicketLocalID: localID,
+	})
+}
+
+// ScheduleOpenTicketsInactivityChecks schedules inactivity checks for all the open tickets in the guild, replacing the pending ones.
+// Checks are otherwise only scheduled when a ticket is opened, so this is called when the inactivity settings change
+func ScheduleOpenTicketsInactivityChecks(ctx context.Context, guildID int64) error {
+	conf, err := models.FindTicketConfigG(ctx, guildID)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil
+		}
+
+		return errors.WithStackIf(err)
+	}
+
+	types, err := GetTicketTypes(ctx, guildID)
+	if err != nil {
+		return err
+	}
+
+	tickets, err := models.Tickets(models.TicketWhere.GuildID.EQ(guildID), models.TicketWhere.ClosedAt.IsNull()).AllG(ctx)
+	if err != nil {
+		return errors.WithStackIf(err)
+	}
+
+	// so tickets that already have a check scheduled don't end up with several
+	_, err = seventsmodels.ScheduledEvents(qm.Where("event_name='tickets_inactivity_check' AND guild_id = ? AND processed = false", guildID)).DeleteAll(ctx, common.PQ)
+	if err != nil {
+		return errors.WithStackIf(err)
+	}
+
+	for _, ticket := range tickets {
+		ticketConf := ApplyTicketType(conf, findTicketType(types, ticket.TicketTypeID))
+		if !ticketConf.Enabled || ticketConf.InactivityWarnHours <= 0 {
+			continue
+		}
+
+		lastActivity := ticket.CreatedAt
+		if ticket.LastAuthorActivityAt.Valid {
+			lastActivity = ticket.LastAuthorActivityAt.Time
+		}
+
+		// the check figures out if it should warn or close, and schedules itself again if it's too early
+		at := lastActivity.Add(time.Hour * time.Duration(ticketConf.InactivityWarnHours))
+		if at.Before(time.Now()) {
+			at = time.Now()
+		}
+
+		err = scheduleInactivityCheck(guildID, ticket.LocalID, at)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// handleInactivityCheck warns the ticket author when the ticket has been inactive for the configured amount of hours,
+// and closes the ticket if there's still no activity some hours after that
+func handleInactivityCheck(evt *seventsmodels.ScheduledEvent, data interface{}) (retry bool, err error) {
+	checkData := data.(*ScheduledInactivityCheckData)
+	ctx := context.Background()
+
+	ticket, err := models.FindTicketG(ctx, evt.GuildID, checkData.TicketLocalID)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return false, nil
+		}
+
+		return true, errors.WithStackIf(err)
+	}
+
+	if ticket.ClosedAt.Valid {
+		return false, nil
+	}
+
+	gs := bot.State.Guild(true, evt.GuildID)
+	if gs == nil || gs.Channel(true, ticket.ChannelID) == nil {
+		return false, nil
+	}
+
+	conf, err := models.FindTicketConfigG(ctx, evt.GuildID)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return false, nil
+		}
+
+		return true, errors.WithStackIf(err)
+	}
+
+	var tt *models.TicketType
+	if ticket.TicketTypeID != 0 {
+		tt, err = models.FindTicketTypeG(ctx, ticket.TicketTypeID)
+		if err != nil && errors.Cause(err) != sql.ErrNoRows {
+			return true, errors.WithStackIf(err)
+		}
+	}
+
+	conf = ApplyTicketType(conf, tt)
+	if !conf.Enabled || conf.InactivityWarnHours <= 0 {
+		return false, nil
+	}
+
+	lastActivity := ticket.CreatedAt
+	if ticket.LastAuthorActivityAt.Valid {
+		lastActivity = ticket.LastAuthorActivityAt.Time
+	}
+
+	// the author said something since this check was scheduled, check again later
+	warnAt := lastActivity.Add(time.Hour * time.Duration(conf.InactivityWarnHours))
+	if time.Now().Before(warnAt) {
+		return false, scheduleInactivityCheck(ticket.GuildID, ticket.LocalID, warnAt)
+	}
+
+	if !ticket.InactivityWarnedAt.Valid {
+		return warnInactiveTicket(ctx, conf, ticket)
+	}
+
+	if conf.InactivityCloseHours <= 0 {
+		return false, nil
+	}
+
+	closeAt := ticket.InactivityWarnedAt.Time.Add(time.Hour * time.Duration(conf.InactivityCloseHours))
+	if time.Now().Before(closeAt) {
+		return false, scheduleInactivityCheck(ticket.GuildID, ticket.LocalID, closeAt)
+	}
+
+	err = closeTicket(ctx, gs, conf, ticket, common.BotUser, "Inactivity")
+	if err == ErrTicketAlreadyClosing {
+		return false, nil
+	}
+
+	return scheduledevents2.CheckDiscordErrRetry(err), err
+}
+
+func warnInactiveTicket(ctx context.Context, conf *models.TicketConfig, ticket *models.Ticket) (retry bool, err error) {
+	msg := fmt.Sprintf("<@%d> This ticket has been inactive for %d hours.", ticket.AuthorID, conf.InactivityWarnHours)
+	if conf.InactivityCloseHours > 0 {
+		msg += fmt.Sprintf(" It will be closed automatically if there's no reply within %d hours.", conf.InactivityCloseHours)
+	}
+
+	_, err = common.BotSession.ChannelMessageSendComplex(ticket.ChannelID, &discordgo.MessageSend{
+		Content: msg,
+		AllowedMentions: discordgo.AllowedMentions{
+			Users: []int64{ticket.AuthorID},
+		},
+	})
+	if err != nil {
+		return scheduledevents2.CheckDiscordErrRetry(err), err
+	}
+
+	if ticket.IsModmail {
+		// the author isn't in the channel, they talk with the staff through DM's
+		bot.SendDM(ticket.AuthorID, fmt.Sprintf("Your ticket #%d with the staff of %s has been inactive for %d hours, reply here to keep it open.", ticket.LocalID, bot.GuildName(ticket.GuildID), conf.InactivityWarnHours))
+	}
+
+	ticket.InactivityWarnedAt = null.TimeFrom(time.Now())
+	_, err = ticket.UpdateG(ctx, boil.Whitelist("inactivity_warned_at"))
+	if err != nil {
+		return false, errors.WithStackIf(err)
+	}
+
+	if conf.InactivityCloseHours <= 0 {
+		return false, nil
+	}
+
+	return false, scheduleInactivityCheck(ticket.GuildID, ticket.LocalID, ticket.InactivityWarnedAt.Time.Add(time.Hour*time.Duration(conf.InactivityCloseHours)))
+}
+
+// handleMessageCreateInactivity keeps track of when the author of a ticket last sent a message in it
+func handleMessageCreateInactivity(evt *eventsystem.EventData) (retry bool, err error) {
+	msg := evt.MessageCreate()
+	if evt.GS == nil || msg.GuildID == 0 || msg.Author == nil || msg.Author.Bot {
+		return false, nil
+	}
+
+	cs := evt.GS.Channel(true, msg.ChannelID)
+	if cs == nil || cs.ParentID == 0 {
+		return false, nil
+	}
+
+	cached, err := getCachedConfig(evt.GS)
+	if err != nil {
+		return true, errors.WithStackIf(err)
+	}
+
+	if !cached.Config.Enabled || !inactivityTrackedCategory(cached, cs.ParentID) {
+		return false, nil
+	}
+
+	_, err = models.Tickets(
+		models.TicketWhere.GuildID.EQ(msg.GuildID),
+		models.TicketWhere.ChannelID.EQ(msg.ChannelID),
+		models.TicketWhere.AuthorID.EQ(msg.Author.ID),
+		models.TicketWhere.ClosedAt.IsNull(),
+	).UpdateAll(evt.Context(), common.PQ, models.M{
+		"last_author_activity_at": time.Now(),
+		"inactivity_warned_at":    nil,
+	})
+
+	if err != nil {
+		return true, errors.WithStackIf(err)
+	}
+
+	return false, nil
+}
+
+// inactivityTrackedCategory returns true if tickets in the category have inactivity warnings enabled
+func inactivityTrackedCategory(cached *cachedConfig, categoryID int64) bool {
+	if cached.Config.TicketsChannelCategory == categoryID && cached.Config.InactivityWarnHours > 0 {
+		return true
+	}
+
+	for _, tt := range cached.Types {
+		conf := ApplyTicketType(cached.Config, tt)
+		if conf.TicketsChannelCategory == categoryID && conf.InactivityWarnHours > 0 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -6,9 +6,11 @@ package models
 var TableNames = struct {
 	TicketConfigs      string
 	TicketParticipants string
+	TicketTypes        string
 	Tickets            string
 }{
 	TicketConfigs:      "ticket_configs",
 	TicketParticipants: "ticket_participants",
+	TicketTypes:        "ticket_types",
 	Tickets:            "tickets",
 }
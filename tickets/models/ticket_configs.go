@@ -35,6 +35,14 @@ type TicketConfig struct {
 	ModRoles                           types.Int64Array `boil:"mod_roles" json:"mod_roles,omitempty" toml:"mod_roles" yaml:"mod_roles,omitempty"`
 	AdminRoles                         types.Int64Array `boil:"admin_roles" json:"admin_roles,omitempty" toml:"admin_roles" yaml:"admin_roles,omitempty"`
 	TicketsTranscriptsChannelAdminOnly int64            `boil:"tickets_transcripts_channel_admin_only" json:"tickets_transcripts_channel_admin_only" toml:"tickets_transcripts_channel_admin_only" yaml:"tickets_transcripts_channel_admin_only"`
+	TicketsUseHTMLTranscripts          bool             `boil:"tickets_use_html_transcripts" json:"tickets_use_html_transcripts" toml:"tickets_use_html_transcripts" yaml:"tickets_use_html_transcripts"`
+	PanelChannelID                     int64            `boil:"panel_channel_id" json:"panel_channel_id" toml:"panel_channel_id" yaml:"panel_channel_id"`
+	PanelMessageID                     int64            `boil:"panel_message_id" json:"panel_message_id" toml:"panel_message_id" yaml:"panel_message_id"`
+	InactivityWarnHours                int              `boil:"inactivity_warn_hours" json:"inactivity_warn_hours" toml:"inactivity_warn_hours" yaml:"inactivity_warn_hours"`
+	InactivityCloseHours               int              `boil:"inactivity_close_hours" json:"inactivity_close_hours" toml:"inactivity_close_hours" yaml:"inactivity_close_hours"`
+	ClaimRestrictsTalking              bool             `boil:"claim_restricts_talking" json:"claim_restricts_talking" toml:"claim_restricts_talking" yaml:"claim_restricts_talking"`
+	ModmailEnabled                     bool             `boil:"modmail_enabled" json:"modmail_enabled" toml:"modmail_enabled" yaml:"modmail_enabled"`
+	ModmailAnonymousReplies            bool             `boil:"modmail_anonymous_replies" json:"modmail_anonymous_replies" toml:"modmail_anonymous_replies" yaml:"modmail_anonymous_replies"`
 
 	R *ticketConfigR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L ticketConfigL  `boil:"-" json:"-" toml:"-" yaml:"-"`
@@ -52,6 +60,14 @@ var TicketConfigColumns = struct {
 	ModRoles                           string
 	AdminRoles                         string
 	TicketsTranscriptsChannelAdminOnly string
+	TicketsUseHTMLTranscripts          string
+	PanelChannelID                     string
+	PanelMessageID                     string
+	InactivityWarnHours                string
+	InactivityCloseHours               string
+	ClaimRestrictsTalking              string
+	ModmailEnabled                     string
+	ModmailAnonymousReplies            string
 }{
 	GuildID:                            "guild_id",
 	Enabled:                            "enabled",
@@ -64,6 +80,14 @@ var TicketConfigColumns = struct {
 	ModRoles:                           "mod_roles",
 	AdminRoles:                         "admin_roles",
 	TicketsTranscriptsChannelAdminOnly: "tickets_transcripts_channel_admin_only",
+	TicketsUseHTMLTranscripts:          "tickets_use_html_transcripts",
+	PanelChannelID:                     "panel_channel_id",
+	PanelMessageID:                     "panel_message_id",
+	InactivityWarnHours:                "inactivity_warn_hours",
+	InactivityCloseHours:               "inactivity_close_hours",
+	ClaimRestrictsTalking:              "claim_restricts_talking",
+	ModmailEnabled:                     "modmail_enabled",
+	ModmailAnonymousReplies:            "modmail_anonymous_replies",
 }
 
 // Generated where
@@ -77,6 +101,15 @@ func (w whereHelperint64) LTE(x int64) qm.QueryMod { return qmhelper.Where(w.fie
 func (w whereHelperint64) GT(x int64) qm.QueryMod  { return qmhelper.Where(w.field, qmhelper.GT, x) }
 func (w whereHelperint64) GTE(x int64) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.GTE, x) }
 
+type whereHelperint struct{ field string }
+
+func (w whereHelperint) EQ(x int) qm.QueryMod  { return qmhelper.Where(w.field, qmhelper.EQ, x) }
+func (w whereHelperint) NEQ(x int) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.NEQ, x) }
+func (w whereHelperint) LT(x int) qm.QueryMod  { return qmhelper.Where(w.field, qmhelper.LT, x) }
+func (w whereHelperint) LTE(x int) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.LTE, x) }
+func (w whereHelperint) GT(x int) qm.QueryMod  { return qmhelper.Where(w.field, qmhelper.GT, x) }
+func (w whereHelperint) GTE(x int) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.GTE, x) }
+
 type whereHelperbool struct{ field string }
 
 func (w whereHelperbool) EQ(x bool) qm.QueryMod  { return qmhelper.Where(w.field, qmhelper.EQ, x) }
@@ -130,6 +163,14 @@ var TicketConfigWhere = struct {
 	ModRoles                           whereHelpertypes_Int64Array
 	AdminRoles                         whereHelpertypes_Int64Array
 	TicketsTranscriptsChannelAdminOnly whereHelperint64
+	TicketsUseHTMLTranscripts          whereHelperbool
+	PanelChannelID                     whereHelperint64
+	PanelMessageID                     whereHelperint64
+	InactivityWarnHours                whereHelperint
+	InactivityCloseHours               whereHelperint
+	ClaimRestrictsTalking              whereHelperbool
+	ModmailEnabled                     whereHelperbool
+	ModmailAnonymousReplies            whereHelperbool
 }{
 	GuildID:                            whereHelperint64{field: "\"ticket_configs\".\"guild_id\""},
 	Enabled:                            whereHelperbool{field: "\"ticket_configs\".\"enabled\""},
@@ -142,6 +183,14 @@ var TicketConfigWhere = struct {
 	ModRoles:                           whereHelpertypes_Int64Array{field: "\"ticket_configs\".\"mod_roles\""},
 	AdminRoles:                         whereHelpertypes_Int64Array{field: "\"ticket_configs\".\"admin_roles\""},
 	TicketsTranscriptsChannelAdminOnly: whereHelperint64{field: "\"ticket_configs\".\"tickets_transcripts_channel_admin_only\""},
+	TicketsUseHTMLTranscripts:          whereHelperbool{field: "\"ticket_configs\".\"tickets_use_html_transcripts\""},
+	PanelChannelID:                     whereHelperint64{field: "\"ticket_configs\".\"panel_channel_id\""},
+	PanelMessageID:                     whereHelperint64{field: "\"ticket_configs\".\"panel_message_id\""},
+	InactivityWarnHours:                whereHelperint{field: "\"ticket_configs\".\"inactivity_warn_hours\""},
+	InactivityCloseHours:               whereHelperint{field: "\"ticket_configs\".\"inactivity_close_hours\""},
+	ClaimRestrictsTalking:              whereHelperbool{field: "\"ticket_configs\".\"claim_restricts_talking\""},
+	ModmailEnabled:                     whereHelperbool{field: "\"ticket_configs\".\"modmail_enabled\""},
+	ModmailAnonymousReplies:            whereHelperbool{field: "\"ticket_configs\".\"modmail_anonymous_replies\""},
 }
 
 // TicketConfigRels is where relationship names are stored.
@@ -161,9 +210,9 @@ func (*ticketConfigR) NewStruct() *ticketConfigR {
 type ticketConfigL struct{}
 
 var (
-	ticketConfigAllColumns            = []string{"guild_id", "enabled", "ticket_open_msg", "tickets_channel_category", "status_channel", "tickets_transcripts_channel", "download_attachments", "tickets_use_txt_transcripts", "mod_roles", "admin_roles", "tickets_transcripts_channel_admin_only"}
+	ticketConfigAllColumns            = []string{"guild_id", "enabled", "ticket_open_msg", "tickets_channel_category", "status_channel", "tickets_transcripts_channel", "download_attachments", "tickets_use_txt_transcripts", "mod_roles", "admin_roles", "tickets_transcripts_channel_admin_only", "tickets_use_html_transcripts", "panel_channel_id", "panel_message_id", "inactivity_warn_hours", "inactivity_close_hours", "claim_restricts_talking", "modmail_enabled", "modmail_anonymous_replies"}
 	ticketConfigColumnsWithoutDefault = []string{"guild_id", "enabled", "ticket_open_msg", "tickets_channel_category", "status_channel", "tickets_transcripts_channel", "download_attachments", "tickets_use_txt_transcripts", "mod_roles", "admin_roles"}
-	ticketConfigColumnsWithDefault    = []string{"tickets_transcripts_channel_admin_only"}
+	ticketConfigColumnsWithDefault    = []string{"tickets_transcripts_channel_admin_only", "tickets_use_html_transcripts", "panel_channel_id", "panel_message_id", "inactivity_warn_hours", "inactivity_close_hours", "claim_restricts_talking", "modmail_enabled", "modmail_anonymous_replies"}
 	ticketConfigPrimaryKeyColumns     = []string{"guild_id"}
 )
 
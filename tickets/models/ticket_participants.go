@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"emperror.dev/errors"
+	"github.com/volatiletech/null"
 	"github.com/volatiletech/sqlboiler/boil"
 	"github.com/volatiletech/sqlboiler/queries"
 	"github.com/volatiletech/sqlboiler/queries/qm"
@@ -23,49 +24,54 @@ import (
 
 // TicketParticipant is an object representing the database table.
 type TicketParticipant struct {
-	TicketGuildID int64  `boil:"ticket_guild_id" json:"ticket_guild_id" toml:"ticket_guild_id" yaml:"ticket_guild_id"`
-	TicketLocalID int64  `boil:"ticket_local_id" json:"ticket_local_id" toml:"ticket_local_id" yaml:"ticket_local_id"`
-	UserID        int64  `boil:"user_id" json:"user_id" toml:"user_id" yaml:"user_id"`
-	Username      string `boil:"username" json:"username" toml:"username" yaml:"username"`
-	Discrim       string `boil:"discrim" json:"discrim" toml:"discrim" yaml:"discrim"`
-	IsStaff       bool   `boil:"is_staff" json:"is_staff" toml:"is_staff" yaml:"is_staff"`
+	TicketGuildID  int64     `boil:"ticket_guild_id" json:"ticket_guild_id" toml:"ticket_guild_id" yaml:"ticket_guild_id"`
+	TicketLocalID  int64     `boil:"ticket_local_id" json:"ticket_local_id" toml:"ticket_local_id" yaml:"ticket_local_id"`
+	UserID         int64     `boil:"user_id" json:"user_id" toml:"user_id" yaml:"user_id"`
+	Username       string    `boil:"username" json:"username" toml:"username" yaml:"username"`
+	Discrim        string    `boil:"discrim" json:"discrim" toml:"discrim" yaml:"discrim"`
+	IsStaff        bool      `boil:"is_staff" json:"is_staff" toml:"is_staff" yaml:"is_staff"`
+	FirstMessageAt null.Time `boil:"first_message_at" json:"first_message_at,omitempty" toml:"first_message_at" yaml:"first_message_at,omitempty"`
 
 	R *ticketParticipantR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L ticketParticipantL  `boil:"-" json:"-" toml:"-" yaml:"-"`
 }
 
 var TicketParticipantColumns = struct {
-	TicketGuildID string
-	TicketLocalID string
-	UserID        string
-	Username      string
-	Discrim       string
-	IsStaff       string
+	TicketGuildID  string
+	TicketLocalID  string
+	UserID         string
+	Username       string
+	Discrim        string
+	IsStaff        string
+	FirstMessageAt string
 }{
-	TicketGuildID: "ticket_guild_id",
-	TicketLocalID: "ticket_local_id",
-	UserID:        "user_id",
-	Username:      "username",
-	Discrim:       "discrim",
-	IsStaff:       "is_staff",
+	TicketGuildID:  "ticket_guild_id",
+	TicketLocalID:  "ticket_local_id",
+	UserID:         "user_id",
+	Username:       "username",
+	Discrim:        "discrim",
+	IsStaff:        "is_staff",
+	FirstMessageAt: "first_message_at",
 }
 
 // Generated where
 
 var TicketParticipantWhere = struct {
-	TicketGuildID whereHelperint64
-	TicketLocalID whereHelperint64
-	UserID        whereHelperint64
-	Username      whereHelperstring
-	Discrim       whereHelperstring
-	IsStaff       whereHelperbool
+	TicketGuildID  whereHelperint64
+	TicketLocalID  whereHelperint64
+	UserID         whereHelperint64
+	Username       whereHelperstring
+	Discrim        whereHelperstring
+	IsStaff        whereHelperbool
+	FirstMessageAt whereHelpernull_Time
 }{
-	TicketGuildID: whereHelperint64{field: "\"ticket_participants\".\"ticket_guild_id\""},
-	TicketLocalID: whereHelperint64{field: "\"ticket_participants\".\"ticket_local_id\""},
-	UserID:        whereHelperint64{field: "\"ticket_participants\".\"user_id\""},
-	Username:      whereHelperstring{field: "\"ticket_participants\".\"username\""},
-	Discrim:       whereHelperstring{field: "\"ticket_participants\".\"discrim\""},
-	IsStaff:       whereHelperbool{field: "\"ticket_participants\".\"is_staff\""},
+	TicketGuildID:  whereHelperint64{field: "\"ticket_participants\".\"ticket_guild_id\""},
+	TicketLocalID:  whereHelperint64{field: "\"ticket_participants\".\"ticket_local_id\""},
+	UserID:         whereHelperint64{field: "\"ticket_participants\".\"user_id\""},
+	Username:       whereHelperstring{field: "\"ticket_participants\".\"username\""},
+	Discrim:        whereHelperstring{field: "\"ticket_participants\".\"discrim\""},
+	IsStaff:        whereHelperbool{field: "\"ticket_participants\".\"is_staff\""},
+	FirstMessageAt: whereHelpernull_Time{field: "\"ticket_participants\".\"first_message_at\""},
 }
 
 // TicketParticipantRels is where relationship names are stored.
@@ -85,8 +91,8 @@ func (*ticketParticipantR) NewStruct() *ticketParticipantR {
 type ticketParticipantL struct{}
 
 var (
-	ticketParticipantAllColumns            = []string{"ticket_guild_id", "ticket_local_id", "user_id", "username", "discrim", "is_staff"}
-	ticketParticipantColumnsWithoutDefault = []string{"ticket_guild_id", "ticket_local_id", "user_id", "username", "discrim", "is_staff"}
+	ticketParticipantAllColumns            = []string{"ticket_guild_id", "ticket_local_id", "user_id", "username", "discrim", "is_staff", "first_message_at"}
+	ticketParticipantColumnsWithoutDefault = []string{"ticket_guild_id", "ticket_local_id", "user_id", "username", "discrim", "is_staff", "first_message_at"}
 	ticketParticipantColumnsWithDefault    = []string{}
 	ticketParticipantPrimaryKeyColumns     = []string{"ticket_guild_id", "ticket_local_id", "user_id"}
 )
@@ -33,6 +33,13 @@ type Ticket struct {
 	LogsID                int64     `boil:"logs_id" json:"logs_id" toml:"logs_id" yaml:"logs_id"`
 	AuthorID              int64     `boil:"author_id" json:"author_id" toml:"author_id" yaml:"author_id"`
 	AuthorUsernameDiscrim string    `boil:"author_username_discrim" json:"author_username_discrim" toml:"author_username_discrim" yaml:"author_username_discrim"`
+	TicketTypeID          int64     `boil:"ticket_type_id" json:"ticket_type_id" toml:"ticket_type_id" yaml:"ticket_type_id"`
+	LastAuthorActivityAt  null.Time `boil:"last_author_activity_at" json:"last_author_activity_at,omitempty" toml:"last_author_activity_at" yaml:"last_author_activity_at,omitempty"`
+	InactivityWarnedAt    null.Time `boil:"inactivity_warned_at" json:"inactivity_warned_at,omitempty" toml:"inactivity_warned_at" yaml:"inactivity_warned_at,omitempty"`
+	ClaimedByID           int64     `boil:"claimed_by_id" json:"claimed_by_id" toml:"claimed_by_id" yaml:"claimed_by_id"`
+	ClaimedByUsername     string    `boil:"claimed_by_username" json:"claimed_by_username" toml:"claimed_by_username" yaml:"claimed_by_username"`
+	ClaimedAt             null.Time `boil:"claimed_at" json:"claimed_at,omitempty" toml:"claimed_at" yaml:"claimed_at,omitempty"`
+	IsModmail             bool      `boil:"is_modmail" json:"is_modmail" toml:"is_modmail" yaml:"is_modmail"`
 
 	R *ticketR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L ticketL  `boil:"-" json:"-" toml:"-" yaml:"-"`
@@ -48,6 +55,13 @@ var TicketColumns = struct {
 	LogsID                string
 	AuthorID              string
 	AuthorUsernameDiscrim string
+	TicketTypeID          string
+	LastAuthorActivityAt  string
+	InactivityWarnedAt    string
+	ClaimedByID           string
+	ClaimedByUsername     string
+	ClaimedAt             string
+	IsModmail             string
 }{
 	GuildID:               "guild_id",
 	LocalID:               "local_id",
@@ -58,6 +72,13 @@ var TicketColumns = struct {
 	LogsID:                "logs_id",
 	AuthorID:              "author_id",
 	AuthorUsernameDiscrim: "author_username_discrim",
+	TicketTypeID:          "ticket_type_id",
+	LastAuthorActivityAt:  "last_author_activity_at",
+	InactivityWarnedAt:    "inactivity_warned_at",
+	ClaimedByID:           "claimed_by_id",
+	ClaimedByUsername:     "claimed_by_username",
+	ClaimedAt:             "claimed_at",
+	IsModmail:             "is_modmail",
 }
 
 // Generated where
@@ -116,6 +137,13 @@ var TicketWhere = struct {
 	LogsID                whereHelperint64
 	AuthorID              whereHelperint64
 	AuthorUsernameDiscrim whereHelperstring
+	TicketTypeID          whereHelperint64
+	LastAuthorActivityAt  whereHelpernull_Time
+	InactivityWarnedAt    whereHelpernull_Time
+	ClaimedByID           whereHelperint64
+	ClaimedByUsername     whereHelperstring
+	ClaimedAt             whereHelpernull_Time
+	IsModmail             whereHelperbool
 }{
 	GuildID:               whereHelperint64{field: "\"tickets\".\"guild_id\""},
 	LocalID:               whereHelperint64{field: "\"tickets\".\"local_id\""},
@@ -126,6 +154,13 @@ var TicketWhere = struct {
 	LogsID:                whereHelperint64{field: "\"tickets\".\"logs_id\""},
 	AuthorID:              whereHelperint64{field: "\"tickets\".\"author_id\""},
 	AuthorUsernameDiscrim: whereHelperstring{field: "\"tickets\".\"author_username_discrim\""},
+	TicketTypeID:          whereHelperint64{field: "\"tickets\".\"ticket_type_id\""},
+	LastAuthorActivityAt:  whereHelpernull_Time{field: "\"tickets\".\"last_author_activity_at\""},
+	InactivityWarnedAt:    whereHelpernull_Time{field: "\"tickets\".\"inactivity_warned_at\""},
+	ClaimedByID:           whereHelperint64{field: "\"tickets\".\"claimed_by_id\""},
+	ClaimedByUsername:     whereHelperstring{field: "\"tickets\".\"claimed_by_username\""},
+	ClaimedAt:             whereHelpernull_Time{field: "\"tickets\".\"claimed_at\""},
+	IsModmail:             whereHelperbool{field: "\"tickets\".\"is_modmail\""},
 }
 
 // TicketRels is where relationship names are stored.
@@ -145,9 +180,9 @@ func (*ticketR) NewStruct() *ticketR {
 type ticketL struct{}
 
 var (
-	ticketAllColumns            = []string{"guild_id", "local_id", "channel_id", "title", "created_at", "closed_at", "logs_id", "author_id", "author_username_discrim"}
-	ticketColumnsWithoutDefault = []string{"guild_id", "local_id", "channel_id", "title", "created_at", "closed_at", "logs_id", "author_id", "author_username_discrim"}
-	ticketColumnsWithDefault    = []string{}
+	ticketAllColumns            = []string{"guild_id", "local_id", "channel_id", "title", "created_at", "closed_at", "logs_id", "author_id", "author_username_discrim", "ticket_type_id", "last_author_activity_at", "inactivity_warned_at", "claimed_by_id", "claimed_by_username", "claimed_at", "is_modmail"}
+	ticketColumnsWithoutDefault = []string{"guild_id", "local_id", "channel_id", "title", "created_at", "closed_at", "logs_id", "author_id", "author_username_discrim", "last_author_activity_at", "inactivity_warned_at", "claimed_at"}
+	ticketColumnsWithDefault    = []string{"ticket_type_id", "claimed_by_id", "claimed_by_username", "is_modmail"}
 	ticketPrimaryKeyColumns     = []string{"guild_id", "local_id"}
 )
 
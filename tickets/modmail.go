@@ -0,0 +1,484 @@
+package tickets
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"emperror.dev/errors"
+	"github.com/jonas747/dcmd"
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/yagpdb/bot"
+	"github.com/jonas747/yagpdb/bot/eventsystem"
+	"github.com/jonas747/yagpdb/commands"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/common/pubsub"
+	"github.com/jonas747/yagpdb/tickets/models"
+	"github.com/mediocregopher/radix/v3"
+	"github.com/volatiletech/null"
+	"github.com/volatiletech/sqlboiler/boil"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+// ModmailOpenData is sent to the process handling the guild when a user wants to contact its staff through modmail
+type ModmailOpenData struct {
+	GuildID     int64
+	Author      *discordgo.User
+	Message     string
+	Attachments []string
+}
+
+var cmdModmail = &commands.YAGCommand{
+	CmdCategory:     commands.CategoryTool,
+	Name:            "Modmail",
+	Description:     "Contacts the staff of a server through a ticket, use this in the bot's DM's",
+	LongDescription: "Further messages you send in DM's are sent to your most recently opened modmail ticket, and the staff's replies are sent back to you in DM's.\nWithout the server ID you get to pick the server from the ones you share with the bot that have modmail enabled.",
+	RunInDM:         true,
+	RequiredArgs:    1,
+	Arguments: []*dcmd.ArgDef{
+		&dcmd.ArgDef{Name: "Server-ID and Message", Type: dcmd.String},
+	},
+	RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+		if parsed.Source != dcmd.DMSource {
+			return "Modmail can only be used in the bot's DM's", nil
+		}
+
+		guildID, message := splitModmailServerID(parsed.Args[0].Str())
+		if message == "" {
+			return "No message specified", nil
+		}
+
+		attachments := attachmentURLs(parsed.Msg.Attachments)
+		if guildID == 0 {
+			return startModmailPick(parsed.Context(), parsed.Msg.Author.ID, message, attachments)
+		}
+
+		conf, err := models.FindTicketConfigG(parsed.Context(), guildID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return "Modmail is not enabled on that server", nil
+			}
+
+			return nil, err
+		}
+
+		if !conf.Enabled || !conf.ModmailEnabled {
+			return "Modmail is not enabled on that server", nil
+		}
+
+		if ms, err := bot.GetMember(guildID, parsed.Msg.Author.ID); err != nil || ms == nil {
+			return "You're not a member of that server", nil
+		}
+
+		err = publishModmailOpen(guildID, parsed.Msg.Author, message, attachments)
+		if err != nil {
+			return nil, err
+		}
+
+		return fmt.Sprintf("Sending your message to the staff of %s...", bot.GuildName(guildID)), nil
+	},
+}
+
+// splitModmailServerID splits off the server ID at the start of the modmail command input, if there is one
+func splitModmailServerID(input string) (guildID int64, message string) {
+	input = strings.TrimSpace(input)
+
+	first := input
+	if idx := strings.IndexFunc(input, unicode.IsSpace); idx != -1 {
+		first = input[:idx]
+	}
+
+	// snowflakes are at least 17 digits, shorter numbers are part of the message
+	if len(first) < 17 {
+		return 0, input
+	}
+
+	parsed, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, input
+	}
+
+	return parsed, strings.TrimSpace(input[len(first):])
+}
+
+// publishModmailOpen opens a modmail ticket with the message, the guild could be on another process,
+// that process opens the ticket and lets the user know how it went
+func publishModmailOpen(guildID int64, author *discordgo.User, message string, attachments []string) error {
+	return pubsub.Publish("tickets_modmail_open", guildID, &ModmailOpenData{
+		GuildID:     guildID,
+		Author:      author,
+		Message:     message,
+		Attachments: attachments,
+	})
+}
+
+func handleModmailOpen(evt *pubsub.Event) {
+	data := evt.Data.(*ModmailOpenData)
+	ctx := context.Background()
+
+	err := openModmailTicket(ctx, data)
+	if err == nil {
+		return
+	}
+
+	if _, ok := errors.Cause(err).(commands.UserError); ok {
+		bot.SendDM(data.Author.ID, "Failed opening a ticket: "+err.Error())
+		return
+	}
+
+	logger.WithError(err).WithField("guild", data.GuildID).Error("failed opening modmail ticket")
+	bot.SendDM(data.Author.ID, "Something went wrong when opening the ticket, please try again later")
+}
+
+// openModmailTicket opens a modmail ticket, or sends the message to the user's open one if there is one already
+func openModmailTicket(ctx context.Context, data *ModmailOpenData) error {
+	gs := bot.State.Guild(true, data.GuildID)
+	if gs == nil {
+		return nil
+	}
+
+	conf, err := models.FindTicketConfigG(ctx, data.GuildID)
+	if err != nil {
+		return err
+	}
+
+	if !conf.Enabled || !conf.ModmailEnabled {
+		return commands.NewUserError("Modmail is not enabled on that server")
+	}
+
+	guildName := bot.GuildName(data.GuildID)
+
+	existing, err := models.Tickets(
+		models.TicketWhere.GuildID.EQ(data.GuildID),
+		models.TicketWhere.AuthorID.EQ(data.Author.ID),
+		models.TicketWhere.IsModmail.EQ(true),
+		models.TicketWhere.ClosedAt.IsNull(),
+		qm.OrderBy("created_at desc"),
+	).OneG(ctx)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	if existing != nil && gs.Channel(true, existing.ChannelID) != nil {
+		err = relayToModmailTicket(ctx, existing, data.Author, data.Message, data.Attachments)
+		if err != nil {
+			return err
+		}
+
+		return bot.SendDM(data.Author.ID, fmt.Sprintf("Sent your message to your open ticket #%d with the staff of %s", existing.LocalID, guildName))
+	}
+
+	ms, err := bot.GetMember(data.GuildID, data.Author.ID)
+	if err != nil || ms == nil {
+		return commands.NewUserError("You're not a member of that server")
+	}
+
+	ticket, _, err := openTicket(ctx, gs, conf, nil, ms, "modmail "+data.Author.Username, true)
+	if err != nil {
+		return err
+	}
+
+	err = relayToModmailTicket(ctx, ticket, data.Author, data.Message, data.Attachments)
+	if err != nil {
+		return err
+	}
+
+	return bot.SendDM(data.Author.ID, fmt.Sprintf("Opened ticket #%d with the staff of %s, their replies will be sent to you here.\nMessages you send here are sent to the ticket until it's closed.", ticket.LocalID, guildName))
+}
+
+// relayToModmailTicket sends a message from the user in DM's to the ticket channel
+func relayToModmailTicket(ctx context.Context, ticket *models.Ticket, author *discordgo.User, content string, attachments []string) error {
+	msg := fmt.Sprintf("**%s#%s:** %s", author.Username, author.Discriminator, content)
+	if len(attachments) > 0 {
+		msg += "\n" + strings.Join(attachments, "\n")
+	}
+
+	_, err := common.BotSession.ChannelMessageSendComplex(ticket.ChannelID, &discordgo.MessageSend{
+		Content: common.CutStringShort(msg, 2000),
+	})
+	if err != nil {
+		return err
+	}
+
+	// the author can't talk in the channel so the inactivity tracking is done here instead
+	ticket.LastAuthorActivityAt = null.TimeFrom(time.Now())
+	ticket.InactivityWarnedAt = null.Time{}
+	_, err = ticket.UpdateG(ctx, boil.Whitelist("last_author_activity_at", "inactivity_warned_at"))
+	return err
+}
+
+// handleModmailDM relays messages in DM's to the user's most recently opened modmail ticket,
+// all processes receive DM's so only the one handling the ticket's guild does it
+func handleModmailDM(evt *pubsub.Event) {
+	mc := evt.Data.(*discordgo.MessageCreate)
+	if mc.Author == nil || mc.Author.Bot || isDMCommand(mc.Content) {
+		return
+	}
+
+	ctx := context.Background()
+	ticket, err := models.Tickets(
+		models.TicketWhere.AuthorID.EQ(mc.Author.ID),
+		models.TicketWhere.IsModmail.EQ(true),
+		models.TicketWhere.ClosedAt.IsNull(),
+		qm.OrderBy("created_at desc"),
+	).OneG(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			handleModmailPickDM(mc)
+		} else {
+			logger.WithError(err).WithField("user", mc.Author.ID).Error("failed retrieving modmail ticket")
+		}
+		return
+	}
+
+	if !bot.ReadyTracker.IsGuildOnProcess(ticket.GuildID) {
+		return
+	}
+
+	err = relayToModmailTicket(ctx, ticket, mc.Author, mc.Content, attachmentURLs(mc.Attachments))
+	if err != nil {
+		logger.WithError(err).WithField("guild", ticket.GuildID).Error("failed relaying modmail message")
+		common.BotSession.MessageReactionAdd(mc.ChannelID, mc.ID, "❌")
+		return
+	}
+
+	common.BotSession.MessageReactionAdd(mc.ChannelID, mc.ID, "✅")
+}
+
+// how long the user has to pick the server to send their message to, in seconds
+const modmailPickExpiry = 60 * 10
+
+func KeyModmailPick(userID int64) string {
+	return "tickets_modmail_pick:" + strconv.FormatInt(userID, 10)
+}
+
+// modmailPick is a modmail message without a server, waiting for the user to pick which server to send it to
+type modmailPick struct {
+	Message     string
+	Attachments []string
+	Guilds      []int64
+}
+
+// startModmailPick lists the servers the user shares with the bot that have modmail enabled,
+// the user replies with the number of one of them to send the message there
+func startModmailPick(ctx context.Context, userID int64, message string, attachments []string) (interface{}, error) {
+	guilds, err := localModmailGuilds(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(guilds) < 1 {
+		return "Couldn't find any servers with modmail enabled that you're in, use `modmail <server-id> <message>` with the ID of the server", nil
+	}
+
+	pick := &modmailPick{
+		Message:     message,
+		Attachments: attachments,
+	}
+
+	var buf strings.Builder
+	buf.WriteString("Reply with the number of the server you want to send your message to:\n")
+	for _, g := range guilds {
+		pick.Guilds = append(pick.Guilds, g.ID)
+		buf.WriteString(fmt.Sprintf("`%d`: %s\n", len(pick.Guilds), g.Name))
+	}
+	buf.WriteString("Not listed? Use `modmail <server-id> <message>` with the ID of the server.")
+
+	serialized, err := json.Marshal(pick)
+	if err != nil {
+		return nil, err
+	}
+
+	err = common.RedisPool.Do(radix.FlatCmd(nil, "SET", KeyModmailPick(userID), serialized, "EX", modmailPickExpiry))
+	if err != nil {
+		return nil, err
+	}
+
+	return common.CutStringShort(buf.String(), 2000), nil
+}
+
+// handleModmailPickDM sends the message the user started with the modmail command to the server they picked,
+// DM's that aren't a valid pick are left alone
+func handleModmailPickDM(mc *discordgo.MessageCreate) {
+	var serialized []byte
+	err := common.RedisPool.Do(radix.Cmd(&serialized, "GET", KeyModmailPick(mc.Author.ID)))
+	if err != nil || len(serialized) < 1 {
+		return
+	}
+
+	var pick modmailPick
+	err = json.Unmarshal(serialized, &pick)
+	if err != nil {
+		logger.WithError(err).WithField("user", mc.Author.ID).Error("failed decoding modmail pick")
+		return
+	}
+
+	guildID, ok := pick.choice(mc.Content)
+	if !ok {
+		return
+	}
+
+	// all processes receive DM's, only one of them should send it
+	var deleted int
+	err = common.RedisPool.Do(radix.Cmd(&deleted, "DEL", KeyModmailPick(mc.Author.ID)))
+	if err != nil || deleted < 1 {
+		return
+	}
+
+	err = publishModmailOpen(guildID, mc.Author, pick.Message, pick.Attachments)
+	if err != nil {
+		logger.WithError(err).WithField("guild", guildID).Error("failed publishing modmail open")
+		common.BotSession.ChannelMessageSend(mc.ChannelID, "Something went wrong when sending your message, please try again later")
+	}
+}
+
+// choice returns the server picked by the reply, either by its number in the list or its ID
+func (p *modmailPick) choice(reply string) (int64, bool) {
+	n, err := strconv.ParseInt(strings.TrimSpace(reply), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	if n > 0 && n <= int64(len(p.Guilds)) {
+		return p.Guilds[n-1], true
+	}
+
+	for _, v := range p.Guilds {
+		if v == n {
+			return v, true
+		}
+	}
+
+	return 0, false
+}
+
+// localModmailGuilds returns the servers with modmail enabled the user is a member of,
+// only the servers on this process with the member in state are checked so this doesn't make any requests
+func localModmailGuilds(ctx context.Context, userID int64) ([]*discordgo.Guild, error) {
+	configs, err := models.TicketConfigs(
+		models.TicketConfigWhere.Enabled.EQ(true),
+		models.TicketConfigWhere.ModmailEnabled.EQ(true),
+		qm.Select("guild_id"),
+	).AllG(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var guilds []*discordgo.Guild
+	for _, v := range configs {
+		gs := bot.State.Guild(true, v.GuildID)
+		if gs == nil {
+			continue
+		}
+
+		ms := gs.MemberCopy(true, userID)
+		if ms == nil || !ms.MemberSet {
+			continue
+		}
+
+		gs.RLock()
+		guilds = append(guilds, &discordgo.Guild{ID: gs.ID, Name: gs.Guild.Name})
+		gs.RUnlock()
+
+		// keep the list readable
+		if len(guilds) >= 25 {
+			break
+		}
+	}
+
+	return guilds, nil
+}
+
+// handleMessageCreateModmail sends the replies in modmail tickets to the user
+func handleMessageCreateModmail(evt *eventsystem.EventData) (retry bool, err error) {
+	msg := evt.MessageCreate()
+	if evt.GS == nil || msg.GuildID == 0 || msg.Author == nil || msg.Author.Bot {
+		return false, nil
+	}
+
+	cs := evt.GS.Channel(true, msg.ChannelID)
+	if cs == nil || cs.ParentID == 0 {
+		return false, nil
+	}
+
+	cached, err := getCachedConfig(evt.GS)
+	if err != nil {
+		return true, errors.WithStackIf(err)
+	}
+
+	// modmail tickets don't have a type, so they're always in the default category
+	conf := cached.Config
+	if !conf.Enabled || !conf.ModmailEnabled || conf.TicketsChannelCategory != cs.ParentID {
+		return false, nil
+	}
+
+	prefix, err := commands.GetCommandPrefixBotEvt(evt)
+	if err != nil {
+		return true, errors.WithStackIf(err)
+	}
+
+	if strings.HasPrefix(msg.Content, prefix) {
+		return false, nil
+	}
+
+	ticket, err := models.Tickets(
+		models.TicketWhere.GuildID.EQ(msg.GuildID),
+		models.TicketWhere.ChannelID.EQ(msg.ChannelID),
+		models.TicketWhere.IsModmail.EQ(true),
+		models.TicketWhere.ClosedAt.IsNull(),
+	).OneG(evt.Context())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+
+		return true, errors.WithStackIf(err)
+	}
+
+	name := msg.Author.Username
+	if conf.ModmailAnonymousReplies {
+		name = "Staff"
+	}
+
+	reply := fmt.Sprintf("**%s (%s):** %s", name, bot.GuildName(msg.GuildID), msg.Content)
+	if urls := attachmentURLs(msg.Attachments); len(urls) > 0 {
+		reply += "\n" + strings.Join(urls, "\n")
+	}
+
+	err = bot.SendDM(ticket.AuthorID, common.CutStringShort(reply, 2000))
+	if err != nil {
+		common.BotSession.MessageReactionAdd(msg.ChannelID, msg.ID, "❌")
+		common.BotSession.ChannelMessageSend(msg.ChannelID, "Failed sending the reply to the user, they may have DM's disabled or left the server")
+		return false, nil
+	}
+
+	common.BotSession.MessageReactionAdd(msg.ChannelID, msg.ID, "✅")
+	return false, nil
+}
+
+// isDMCommand returns true if the message in DM's is a command, those are not relayed.
+// Only messages with the prefix count, so messages that happen to start with the name of a command are still relayed
+func isDMCommand(content string) bool {
+	content = strings.TrimSpace(content)
+	prefix := commands.DefaultCommandPrefix()
+	if !strings.HasPrefix(content, prefix) {
+		return false
+	}
+
+	cmd, _, _ := commands.CommandSystem.Root.AbsFindCommandWithRest(strings.TrimPrefix(content, prefix))
+	return cmd != nil
+}
+
+func attachmentURLs(attachments []*discordgo.MessageAttachment) []string {
+	urls := make([]string, 0, len(attachments))
+	for _, v := range attachments {
+		urls = append(urls, v.URL)
+	}
+
+	return urls
+}
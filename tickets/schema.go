@@ -63,4 +63,62 @@ CREATE TABLE IF NOT EXISTS ticket_participants (
 `, `
 
 CREATE INDEX IF NOT EXISTS ticket_participants_ticket_local_id_idx ON ticket_participants(ticket_guild_id, ticket_local_id);
+`, `
+ALTER TABLE ticket_configs ADD COLUMN IF NOT EXISTS tickets_use_html_transcripts BOOLEAN NOT NULL DEFAULT false;
+`, `
+CREATE TABLE IF NOT EXISTS ticket_types (
+	id BIGSERIAL PRIMARY KEY,
+	guild_id BIGINT NOT NULL,
+
+	name TEXT NOT NULL,
+	emoji TEXT NOT NULL DEFAULT '',
+
+	-- settings that override the ones in ticket_configs, 0/empty to use the guild's
+	channel_category BIGINT NOT NULL DEFAULT 0,
+	transcripts_channel BIGINT NOT NULL DEFAULT 0,
+	mod_roles BIGINT[],
+	admin_roles BIGINT[],
+	open_msg TEXT NOT NULL DEFAULT '',
+	naming_pattern TEXT NOT NULL DEFAULT ''
+);
+`, `
+CREATE INDEX IF NOT EXISTS ticket_types_guild_id_idx ON ticket_types(guild_id);
+`, `
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS ticket_type_id BIGINT NOT NULL DEFAULT 0;
+`, `
+ALTER TABLE ticket_configs ADD COLUMN IF NOT EXISTS panel_channel_id BIGINT NOT NULL DEFAULT 0;
+`, `
+ALTER TABLE ticket_configs ADD COLUMN IF NOT EXISTS panel_message_id BIGINT NOT NULL DEFAULT 0;
+`, `
+ALTER TABLE ticket_configs ADD COLUMN IF NOT EXISTS inactivity_warn_hours INT NOT NULL DEFAULT 0;
+`, `
+ALTER TABLE ticket_configs ADD COLUMN IF NOT EXISTS inactivity_close_hours INT NOT NULL DEFAULT 0;
+`, `
+ALTER TABLE ticket_types ADD COLUMN IF NOT EXISTS inactivity_warn_hours INT NOT NULL DEFAULT 0;
+`, `
+ALTER TABLE ticket_types ADD COLUMN IF NOT EXISTS inactivity_close_hours INT NOT NULL DEFAULT 0;
+`, `
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS last_author_activity_at TIMESTAMP WITH TIME ZONE;
+`, `
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS inactivity_warned_at TIMESTAMP WITH TIME ZONE;
+`, `
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS claimed_by_id BIGINT NOT NULL DEFAULT 0;
+`, `
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS claimed_by_username TEXT NOT NULL DEFAULT '';
+`, `
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS claimed_at TIMESTAMP WITH TIME ZONE;
+`, `
+ALTER TABLE ticket_participants ADD COLUMN IF NOT EXISTS first_message_at TIMESTAMP WITH TIME ZONE;
+`, `
+ALTER TABLE ticket_configs ADD COLUMN IF NOT EXISTS claim_restricts_talking BOOLEAN NOT NULL DEFAULT false;
+`, `
+CREATE INDEX IF NOT EXISTS tickets_guild_id_created_at_idx ON tickets(guild_id, created_at);
+`, `
+ALTER TABLE ticket_configs ADD COLUMN IF NOT EXISTS modmail_enabled BOOLEAN NOT NULL DEFAULT false;
+`, `
+ALTER TABLE ticket_configs ADD COLUMN IF NOT EXISTS modmail_anonymous_replies BOOLEAN NOT NULL DEFAULT false;
+`, `
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS is_modmail BOOLEAN NOT NULL DEFAULT false;
+`, `
+CREATE INDEX IF NOT EXISTS tickets_modmail_author_idx ON tickets(author_id) WHERE is_modmail AND closed_at IS NULL;
 `}
@@ -1,10 +1,18 @@
 package tickets
 
 import (
+	"context"
+	"database/sql"
+
 	"emperror.dev/errors"
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/dstate/v2"
 	"github.com/jonas747/yagpdb/bot"
 	"github.com/jonas747/yagpdb/bot/eventsystem"
+	"github.com/jonas747/yagpdb/commands"
 	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/common/pubsub"
+	"github.com/jonas747/yagpdb/common/scheduledevents2"
 	"github.com/jonas747/yagpdb/tickets/models"
 )
 
@@ -12,13 +20,35 @@ var _ bot.BotInitHandler = (*Plugin)(nil)
 
 func (p *Plugin) BotInit() {
 	eventsystem.AddHandlerAsyncLast(p, p.handleChannelRemoved, eventsystem.EventChannelDelete)
+	eventsystem.AddHandlerAsyncLastLegacy(p, handlePanelReactionAdd, eventsystem.EventMessageReactionAdd)
+	eventsystem.AddHandlerAsyncLast(p, handleMessageCreateInactivity, eventsystem.EventMessageCreate)
+
+	eventsystem.AddHandlerAsyncLast(p, handleMessageCreateModmail, eventsystem.EventMessageCreate)
+
+	scheduledevents2.RegisterHandler("tickets_inactivity_check", ScheduledInactivityCheckData{}, handleInactivityCheck)
+
+	pubsub.AddHandler("tickets_modmail_open", func(evt *pubsub.Event) {
+		go handleModmailOpen(evt)
+	}, ModmailOpenData{})
+	pubsub.AddHandler("dm_message", func(evt *pubsub.Event) {
+		go handleModmailDM(evt)
+	}, discordgo.MessageCreate{})
 }
 
 func (p *Plugin) handleChannelRemoved(evt *eventsystem.EventData) (retry bool, err error) {
 	del := evt.ChannelDelete()
 
+	// the channel of closed tickets is deleted as the last step of closing it, those are kept for the stats
+	closingTicketsLock.Lock()
+	closing := closingTickets[del.Channel.ID]
+	closingTicketsLock.Unlock()
+	if closing {
+		return false, nil
+	}
+
 	_, err = models.Tickets(
 		models.TicketWhere.ChannelID.EQ(del.Channel.ID),
+		models.TicketWhere.ClosedAt.IsNull(),
 	).DeleteAll(evt.Context(), common.PQ)
 
 	if err != nil {
@@ -27,3 +57,86 @@ func (p *Plugin) handleChannelRemoved(evt *eventsystem.EventData) (retry bool, e
 
 	return false, nil
 }
+
+// CacheKeyConfig is evicted when the config or ticket types change
+const CacheKeyConfig bot.GSCacheKey = "tickets_config"
+
+type cachedConfig struct {
+	Config *models.TicketConfig
+	Types  models.TicketTypeSlice
+}
+
+func getCachedConfig(gs *dstate.GuildState) (*cachedConfig, error) {
+	v, err := gs.UserCacheFetch(CacheKeyConfig, func() (interface{}, error) {
+		conf, err := models.FindTicketConfigG(context.Background(), gs.ID)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				return nil, err
+			}
+
+			conf = &models.TicketConfig{}
+		}
+
+		types, err := GetTicketTypes(context.Background(), gs.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &cachedConfig{Config: conf, Types: types}, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return v.(*cachedConfig), nil
+}
+
+// handlePanelReactionAdd opens a ticket of the type the user reacted with on the panel message
+func handlePanelReactionAdd(evt *eventsystem.EventData) {
+	ra := evt.MessageReactionAdd()
+	if ra.GuildID == 0 || ra.UserID == common.BotUser.ID {
+		return
+	}
+
+	panel, err := getCachedConfig(evt.GS)
+	if err != nil {
+		logger.WithError(err).WithField("guild", ra.GuildID).Error("failed retrieving ticket panel")
+		return
+	}
+
+	if panel.Config.PanelMessageID != ra.MessageID || !panel.Config.Enabled {
+		return
+	}
+
+	var tt *models.TicketType
+	emoji := ra.Emoji.APIName()
+	for _, v := range panel.Types {
+		if v.Emoji != "" && v.Emoji == emoji {
+			tt = v
+			break
+		}
+	}
+
+	if tt == nil {
+		return
+	}
+
+	// remove the reaction so it can be used to open another ticket later
+	common.BotSession.MessageReactionRemove(ra.ChannelID, ra.MessageID, emoji, ra.UserID)
+
+	ms, err := bot.GetMember(ra.GuildID, ra.UserID)
+	if err != nil || ms == nil || ms.Bot {
+		return
+	}
+
+	_, _, err = openTicket(evt.Context(), evt.GS, ApplyTicketType(panel.Config, tt), tt, ms, tt.Name, false)
+	if err != nil {
+		if _, ok := errors.Cause(err).(commands.UserError); ok {
+			bot.SendDM(ra.UserID, "Failed opening a ticket: "+err.Error())
+			return
+		}
+
+		logger.WithError(err).WithField("guild", ra.GuildID).Error("failed opening ticket from panel")
+	}
+}
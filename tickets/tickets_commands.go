@@ -9,17 +9,22 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"emperror.dev/errors"
 	"github.com/jonas747/dcmd"
 	"github.com/jonas747/discordgo"
 	"github.com/jonas747/dstate/v2"
 	"github.com/jonas747/yagpdb/analytics"
+	"github.com/jonas747/yagpdb/bot"
 	"github.com/jonas747/yagpdb/commands"
 	"github.com/jonas747/yagpdb/common"
 	"github.com/jonas747/yagpdb/common/templates"
 	"github.com/jonas747/yagpdb/tickets/models"
+	"github.com/volatiletech/null"
 	"github.com/volatiletech/sqlboiler/boil"
 	"github.com/volatiletech/sqlboiler/queries/qm"
 )
@@ -38,11 +43,12 @@ func (p *Plugin) AddCommands() {
 	}
 
 	cmdOpenTicket := &commands.YAGCommand{
-		CmdCategory:  categoryTickets,
-		Name:         "Open",
-		Aliases:      []string{"create", "new", "make"},
-		Description:  "Opens a new ticket",
-		RequiredArgs: 1,
+		CmdCategory:     categoryTickets,
+		Name:            "Open",
+		Aliases:         []string{"create", "new", "make"},
+		Description:     "Opens a new ticket",
+		LongDescription: "If the server has ticket types set up, start the subject with the name of one to open a ticket of that type, e.g `-ticket open appeals I was banned by mistake`",
+		RequiredArgs:    1,
 		Arguments: []*dcmd.ArgDef{
 			&dcmd.ArgDef{Name: "subject", Type: dcmd.String},
 		},
@@ -52,76 +58,19 @@ func (p *Plugin) AddCommands() {
 				return "Ticket system is disabled in this server, the server admins can enable it in the control panel.", nil
 			}
 
-			if parsed.GS.Channel(true, conf.TicketsChannelCategory) == nil {
-				return "No category for ticket channels set", nil
-			}
-
-			inCurrentTickets, err := models.Tickets(
-				qm.Where("closed_at IS NULL"),
-				qm.Where("guild_id = ?", parsed.GS.ID),
-				qm.Where("author_id = ?", parsed.Msg.Author.ID)).AllG(parsed.Context())
-
-			count := 0
-			for _, v := range inCurrentTickets {
-				if parsed.GS.Channel(true, v.ChannelID) != nil {
-					count++
-				}
-			}
-
-			if count >= 10 {
-				return "You're currently in over 10 open tickets on this server, please close some of the ones you're in.", nil
-			}
-
-			if len(parsed.Args[0].Str()) > 90 {
-				return "Title is too long (max 90 characters.) Please shorten it down, you can add more details in the ticket after it has been created", nil
-			}
-
-			subject := parsed.Args[0].Str()
-			id, channel, err := createTicketChannel(conf, parsed.GS, parsed.Msg.Author.ID, subject)
-			if err != nil {
-				return "Failed creating the channel, make sure the bot has proper perms and the channel limit hasn't been reached.", nil
-			}
-
-			// create the db model for it
-			dbModel := &models.Ticket{
-				GuildID:               parsed.GS.ID,
-				LocalID:               id,
-				ChannelID:             channel.ID,
-				Title:                 subject,
-				CreatedAt:             time.Now(),
-				AuthorID:              parsed.Msg.Author.ID,
-				AuthorUsernameDiscrim: parsed.Msg.Author.Username + "#" + parsed.Msg.Author.Discriminator,
-			}
-
-			err = dbModel.InsertG(parsed.Context(), boil.Infer())
+			types, err := GetTicketTypes(parsed.Context(), parsed.GS.ID)
 			if err != nil {
 				return nil, err
 			}
 
-			// send the first ticket message
-
-			tmplCTX := templates.NewContext(parsed.GS, dstate.NewChannelState(parsed.GS, parsed.GS, channel), parsed.MS)
-			tmplCTX.Name = "ticket open message"
-			tmplCTX.Data["Reason"] = parsed.Args[0].Str()
-			ticketOpenMsg := conf.TicketOpenMSG
-			if ticketOpenMsg == "" {
-				ticketOpenMsg = DefaultTicketMsg
-			}
-
-			err = tmplCTX.ExecuteAndSendWithErrors(ticketOpenMsg, channel.ID)
+			tt, subject := matchTicketType(types, parsed.Args[0].Str())
+			ticket, channel, err := openTicket(parsed.Context(), parsed.GS, ApplyTicketType(conf, tt), tt, parsed.MS, subject, false)
 			if err != nil {
-				logger.WithError(err).WithField("guild", parsed.GS.ID).Error("failed sending ticket open message")
+				return nil, err
 			}
 
-			// send the log message
-			TicketLog(conf, parsed.GS.ID, parsed.Msg.Author, &discordgo.MessageEmbed{
-				Title:       fmt.Sprintf("Ticket #%d opened", id),
-				Description: fmt.Sprintf("Subject: %s", subject),
-				Color:       0x5df948,
-			})
-
 			// Annn done setting up the ticket
-			return fmt.Sprintf("Ticket #%d opened in <#%d>", id, channel.ID), nil
+			return fmt.Sprintf("Ticket #%d opened in <#%d>", ticket.LocalID, channel.ID), nil
 		},
 	}
 
@@ -224,7 +173,8 @@ func (p *Plugin) AddCommands() {
 				return nil, err
 			}
 
-			_, err = common.BotSession.ChannelEdit(currentTicket.Ticket.ChannelID, fmt.Sprintf("#%d-%s", currentTicket.Ticket.LocalID, newName))
+			authorUsername := strings.SplitN(currentTicket.Ticket.AuthorUsernameDiscrim, "#", 2)[0]
+			_, err = common.BotSession.ChannelEdit(currentTicket.Ticket.ChannelID, TicketChannelName(currentTicket.Type, currentTicket.Ticket.LocalID, newName, authorUsername))
 			if err != nil {
 				return nil, err
 			}
@@ -240,9 +190,6 @@ func (p *Plugin) AddCommands() {
 		},
 	}
 
-	closingTickets := make(map[int64]bool)
-	var closingTicketsLock sync.Mutex
-
 	cmdCloseTicket := &commands.YAGCommand{
 		CmdCategory: categoryTickets,
 		Name:        "Close",
@@ -255,52 +202,12 @@ func (p *Plugin) AddCommands() {
 			conf := parsed.Context().Value(CtxKeyConfig).(*models.TicketConfig)
 			currentTicket := parsed.Context().Value(CtxKeyCurrentTicket).(*Ticket)
 
-			// protect again'st calling close multiple times at the sime time
-			closingTicketsLock.Lock()
-			if _, ok := closingTickets[currentTicket.Ticket.ChannelID]; ok {
-				closingTicketsLock.Unlock()
+			err := closeTicket(parsed.Context(), parsed.GS, conf, currentTicket.Ticket, parsed.Msg.Author, parsed.Args[0].Str())
+			if err == ErrTicketAlreadyClosing {
 				return "Already working on closing this ticket, please wait...", nil
 			}
-			closingTickets[currentTicket.Ticket.ChannelID] = true
-			closingTicketsLock.Unlock()
-			defer func() {
-				closingTicketsLock.Lock()
-				delete(closingTickets, currentTicket.Ticket.ChannelID)
-				closingTicketsLock.Unlock()
-			}()
-
-			// send a heads up that this can take a while
-			common.BotSession.ChannelMessageSend(parsed.CS.ID, "Closing ticket, creating logs, downloading attachments and so on.\nThis may take a while if the ticket is big.")
-
-			currentTicket.Ticket.ClosedAt.Time = time.Now()
-			currentTicket.Ticket.ClosedAt.Valid = true
-
-			isAdminsOnly := ticketIsAdminOnly(conf, parsed.CS)
-
-			// create the logs, download the attachments
-			err := createLogs(parsed.GS, conf, currentTicket.Ticket, isAdminsOnly)
-			if err != nil {
-				return nil, err
-			}
 
-			TicketLog(conf, parsed.GS.ID, parsed.Msg.Author, &discordgo.MessageEmbed{
-				Title:       fmt.Sprintf("Ticket #%d - '%s' closed", currentTicket.Ticket.LocalID, currentTicket.Ticket.Title),
-				Description: fmt.Sprintf("Reason: %s", parsed.Args[0].Str()),
-				Color:       0xf23c3c,
-			})
-
-			// if everything went well, delete the channel
-			_, err = common.BotSession.ChannelDelete(currentTicket.Ticket.ChannelID)
-			if err != nil {
-				return nil, err
-			}
-
-			_, err = currentTicket.Ticket.UpdateG(parsed.Context(), boil.Whitelist("closed_at"))
-			if err != nil {
-				return nil, err
-			}
-
-			return "", nil
+			return "", err
 		},
 	}
 
@@ -382,6 +289,222 @@ func (p *Plugin) AddCommands() {
 		},
 	}
 
+	cmdClaim := &commands.YAGCommand{
+		CmdCategory: categoryTickets,
+		Name:        "Claim",
+		Description: "Claims the ticket, making you the staff member responsible for it",
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			conf := parsed.Context().Value(CtxKeyConfig).(*models.TicketConfig)
+			currentTicket := parsed.Context().Value(CtxKeyCurrentTicket).(*Ticket)
+
+			if !isTicketStaff(parsed.GS, conf, parsed.Msg.Author.ID) {
+				return "Only staff can claim tickets", nil
+			}
+
+			if currentTicket.Ticket.ClaimedByID == parsed.Msg.Author.ID {
+				return "You've already claimed this ticket", nil
+			}
+
+			if currentTicket.Ticket.ClaimedByID != 0 {
+				return fmt.Sprintf("This ticket is already claimed by %s, use the assign command to take it over", currentTicket.Ticket.ClaimedByUsername), nil
+			}
+
+			err := setTicketClaim(parsed.Context(), parsed.GS, parsed.CS, conf, currentTicket.Ticket, parsed.Msg.Author)
+			if err != nil {
+				return nil, err
+			}
+
+			TicketLog(conf, parsed.GS.ID, parsed.Msg.Author, &discordgo.MessageEmbed{
+				Title: fmt.Sprintf("Ticket #%d claimed", currentTicket.Ticket.LocalID),
+				Color: 0x42b9f4,
+			})
+
+			return "Claimed the ticket", nil
+		},
+	}
+
+	cmdUnclaim := &commands.YAGCommand{
+		CmdCategory: categoryTickets,
+		Name:        "Unclaim",
+		Description: "Removes the claim on the ticket",
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			conf := parsed.Context().Value(CtxKeyConfig).(*models.TicketConfig)
+			currentTicket := parsed.Context().Value(CtxKeyCurrentTicket).(*Ticket)
+
+			if !isTicketStaff(parsed.GS, conf, parsed.Msg.Author.ID) {
+				return "Only staff can unclaim tickets", nil
+			}
+
+			if currentTicket.Ticket.ClaimedByID == 0 {
+				return "This ticket isn't claimed", nil
+			}
+
+			err := setTicketClaim(parsed.Context(), parsed.GS, parsed.CS, conf, currentTicket.Ticket, nil)
+			if err != nil {
+				return nil, err
+			}
+
+			TicketLog(conf, parsed.GS.ID, parsed.Msg.Author, &discordgo.MessageEmbed{
+				Title: fmt.Sprintf("Ticket #%d unclaimed", currentTicket.Ticket.LocalID),
+				Color: 0x42b9f4,
+			})
+
+			return "Unclaimed the ticket", nil
+		},
+	}
+
+	cmdAssign := &commands.YAGCommand{
+		CmdCategory:  categoryTickets,
+		Name:         "Assign",
+		Description:  "Assigns the ticket to a staff member",
+		RequiredArgs: 1,
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "member", Type: &commands.MemberArg{}},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			conf := parsed.Context().Value(CtxKeyConfig).(*models.TicketConfig)
+			currentTicket := parsed.Context().Value(CtxKeyCurrentTicket).(*Ticket)
+			target := parsed.Args[0].Value.(*dstate.MemberState)
+
+			if !isTicketStaff(parsed.GS, conf, parsed.Msg.Author.ID) {
+				return "Only staff can assign tickets", nil
+			}
+
+			if !isTicketStaff(parsed.GS, conf, target.ID) {
+				return "Tickets can only be assigned to staff", nil
+			}
+
+			if currentTicket.Ticket.ClaimedByID == target.ID {
+				return "The ticket is already assigned to them", nil
+			}
+
+			targetUser := target.DGoUser()
+			err := setTicketClaim(parsed.Context(), parsed.GS, parsed.CS, conf, currentTicket.Ticket, targetUser)
+			if err != nil {
+				return nil, err
+			}
+
+			TicketLog(conf, parsed.GS.ID, parsed.Msg.Author, &discordgo.MessageEmbed{
+				Title:       fmt.Sprintf("Ticket #%d assigned", currentTicket.Ticket.LocalID),
+				Description: fmt.Sprintf("Assigned to: %s#%s (%d)", targetUser.Username, targetUser.Discriminator, targetUser.ID),
+				Color:       0x42b9f4,
+			})
+
+			return fmt.Sprintf("Assigned the ticket to %s#%s", targetUser.Username, targetUser.Discriminator), nil
+		},
+	}
+
+	cmdStats := &commands.YAGCommand{
+		CmdCategory: categoryTickets,
+		Name:        "Stats",
+		Description: "Shows ticket stats for the last number of days (default 30)",
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "days", Type: &dcmd.IntArg{Min: 1, Max: 365}, Default: 30},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			conf := parsed.Context().Value(CtxKeyConfig).(*models.TicketConfig)
+			if !isTicketStaff(parsed.GS, conf, parsed.Msg.Author.ID) {
+				return "Only staff can view ticket stats", nil
+			}
+
+			days := parsed.Args[0].Int()
+			stats, err := GetTicketStats(parsed.Context(), parsed.GS.ID, time.Now().Add(-time.Hour*24*time.Duration(days)))
+			if err != nil {
+				return nil, err
+			}
+
+			embed := &discordgo.MessageEmbed{
+				Title: fmt.Sprintf("Ticket stats for the last %d days", days),
+				Color: 0x42b9f4,
+				Fields: []*discordgo.MessageEmbedField{
+					&discordgo.MessageEmbedField{Name: "Opened", Value: strconv.Itoa(stats.Opened), Inline: true},
+					&discordgo.MessageEmbedField{Name: "Closed", Value: strconv.Itoa(stats.Closed), Inline: true},
+					&discordgo.MessageEmbedField{Name: "Median first response", Value: humanizeTicketStat(stats.MedianFirstResponse), Inline: true},
+					&discordgo.MessageEmbedField{Name: "Median time to close", Value: humanizeTicketStat(stats.MedianTimeClose), Inline: true},
+				},
+			}
+
+			embed.Fields = append(embed.Fields, staffStatsFields(stats.Staff)...)
+
+			return embed, nil
+		},
+	}
+
+	cmdPanel := &commands.YAGCommand{
+		CmdCategory:         categoryTickets,
+		Name:                "Panel",
+		Description:         "Posts a message users can react to to open a ticket of one of the ticket types, replaces the previous one",
+		LongDescription:     "Only ticket types with a emoji set in the control panel are shown.",
+		RequireDiscordPerms: []int64{discordgo.PermissionManageServer},
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "channel", Type: dcmd.Channel},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			conf := parsed.Context().Value(CtxKeyConfig).(*models.TicketConfig)
+			if !conf.Enabled {
+				return "Ticket system is disabled in this server, the server admins can enable it in the control panel.", nil
+			}
+
+			channel := parsed.CS
+			if parsed.Args[0].Value != nil {
+				channel = parsed.Args[0].Value.(*dstate.ChannelState)
+			}
+
+			types, err := GetTicketTypes(parsed.Context(), parsed.GS.ID)
+			if err != nil {
+				return nil, err
+			}
+
+			var buf strings.Builder
+			emojis := make([]string, 0, len(types))
+			for _, v := range types {
+				if v.Emoji == "" {
+					continue
+				}
+
+				emojis = append(emojis, v.Emoji)
+				buf.WriteString(fmt.Sprintf("%s **%s**\n", ticketEmojiMention(v.Emoji), v.Name))
+			}
+
+			if len(emojis) < 1 {
+				return "No ticket types with a emoji set up, you can set them up in the control panel.", nil
+			}
+
+			msg, err := common.BotSession.ChannelMessageSendEmbed(channel.ID, &discordgo.MessageEmbed{
+				Title:       "Open a ticket",
+				Description: "React below to open a ticket\n\n" + buf.String(),
+				Color:       0x42b9f4,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			for _, v := range emojis {
+				err = common.BotSession.MessageReactionAdd(msg.ChannelID, msg.ID, v)
+				if err != nil {
+					return "Failed adding reaction " + ticketEmojiMention(v) + ", make sure the emoji is valid and the bot has permissions to add reactions", err
+				}
+			}
+
+			if conf.PanelMessageID != 0 {
+				common.BotSession.ChannelMessageDelete(conf.PanelChannelID, conf.PanelMessageID)
+			}
+
+			conf.PanelChannelID = msg.ChannelID
+			conf.PanelMessageID = msg.ID
+			_, err = conf.UpdateG(parsed.Context(), boil.Whitelist("panel_channel_id", "panel_message_id"))
+			if err != nil {
+				return nil, err
+			}
+
+			bot.EvictGSCache(parsed.GS.ID, CacheKeyConfig)
+
+			return "", nil
+		},
+	}
+
+	commands.AddRootCommands(p, cmdModmail)
+
 	container := commands.CommandSystem.Root.Sub("tickets", "ticket")
 	container.NotFound = commands.CommonContainerNotFoundHandler(container, "")
 	container.AddMidlewares(
@@ -414,9 +537,21 @@ func (p *Plugin) AddCommands() {
 				ctx := context.WithValue(data.Context(), CtxKeyConfig, conf)
 
 				if activeTicket != nil {
+					var tt *models.TicketType
+					if activeTicket.TicketTypeID != 0 {
+						tt, err = models.FindTicketTypeG(ctx, activeTicket.TicketTypeID)
+						if err != nil && err != sql.ErrNoRows {
+							return nil, err
+						}
+
+						// use the settings of the type for everything in this ticket
+						ctx = context.WithValue(ctx, CtxKeyConfig, ApplyTicketType(conf, tt))
+					}
+
 					participants, _ := models.TicketParticipants(qm.Where("ticket_guild_id = ? AND ticket_local_id = ?", activeTicket.GuildID, activeTicket.LocalID)).AllG(ctx)
 					ctx = context.WithValue(ctx, CtxKeyCurrentTicket, &Ticket{
 						Ticket:       activeTicket,
+						Type:         tt,
 						Participants: participants,
 					})
 				}
@@ -431,6 +566,57 @@ func (p *Plugin) AddCommands() {
 	container.AddCommand(cmdRenameTicket, cmdRenameTicket.GetTrigger().SetMiddlewares(RequireActiveTicketMW))
 	container.AddCommand(cmdCloseTicket, cmdCloseTicket.GetTrigger().SetMiddlewares(RequireActiveTicketMW))
 	container.AddCommand(cmdAdminsOnly, cmdAdminsOnly.GetTrigger().SetMiddlewares(RequireActiveTicketMW))
+	container.AddCommand(cmdClaim, cmdClaim.GetTrigger().SetMiddlewares(RequireActiveTicketMW))
+	container.AddCommand(cmdUnclaim, cmdUnclaim.GetTrigger().SetMiddlewares(RequireActiveTicketMW))
+	container.AddCommand(cmdAssign, cmdAssign.GetTrigger().SetMiddlewares(RequireActiveTicketMW))
+	container.AddCommand(cmdPanel, cmdPanel.GetTrigger())
+	container.AddCommand(cmdStats, cmdStats.GetTrigger())
+}
+
+// staffStatsFields returns the per staff stats lines split up into fields, as a field can only hold 1024 characters
+func staffStatsFields(staff []*TicketStaffStats) []*discordgo.MessageEmbedField {
+	var fields []*discordgo.MessageEmbedField
+	var buf strings.Builder
+
+	flush := func() {
+		name := "Per staff member"
+		if len(fields) > 0 {
+			name = "..."
+		}
+
+		fields = append(fields, &discordgo.MessageEmbedField{Name: name, Value: buf.String()})
+		buf.Reset()
+	}
+
+	for _, v := range staff {
+		line := fmt.Sprintf("<@%d> (%s): %d closed, median time to close %s, responded to %d, median first response %s\n",
+			v.UserID, v.Username, v.Closed, humanizeTicketStat(v.MedianTimeClose), v.Responded, humanizeTicketStat(v.MedianFirstResponse))
+		line = common.CutStringShort(line, 1024)
+
+		if buf.Len()+len(line) > 1024 {
+			flush()
+		}
+
+		buf.WriteString(line)
+	}
+
+	if buf.Len() > 0 {
+		flush()
+	}
+
+	return fields
+}
+
+func humanizeTicketStat(d time.Duration) string {
+	if d <= 0 {
+		return "n/a"
+	}
+
+	if d < time.Minute {
+		return "less than a minute"
+	}
+
+	return common.HumanizeDuration(common.DurationPrecisionMinutes, d)
 }
 
 func RequireActiveTicketMW(inner dcmd.RunFunc) dcmd.RunFunc {
@@ -443,6 +629,73 @@ func RequireActiveTicketMW(inner dcmd.RunFunc) dcmd.RunFunc {
 	}
 }
 
+var (
+	closingTickets     = make(map[int64]bool)
+	closingTicketsLock sync.Mutex
+
+	ErrTicketAlreadyClosing = errors.New("ticket is already being closed")
+)
+
+// closeTicket creates the transcripts, logs the closing and deletes the ticket channel,
+// conf should have the settings of the ticket type applied
+func closeTicket(ctx context.Context, gs *dstate.GuildState, conf *models.TicketConfig, ticket *models.Ticket, closedBy *discordgo.User, reason string) error {
+	// protect again'st calling close multiple times at the sime time
+	closingTicketsLock.Lock()
+	if _, ok := closingTickets[ticket.ChannelID]; ok {
+		closingTicketsLock.Unlock()
+		return ErrTicketAlreadyClosing
+	}
+	closingTickets[ticket.ChannelID] = true
+	closingTicketsLock.Unlock()
+	defer func() {
+		closingTicketsLock.Lock()
+		delete(closingTickets, ticket.ChannelID)
+		closingTicketsLock.Unlock()
+	}()
+
+	cs := gs.Channel(true, ticket.ChannelID)
+	if cs == nil {
+		return errors.New("ticket channel not found")
+	}
+
+	// send a heads up that this can take a while
+	common.BotSession.ChannelMessageSend(ticket.ChannelID, "Closing ticket, creating logs, downloading attachments and so on.\nThis may take a while if the ticket is big.")
+
+	ticket.ClosedAt.Time = time.Now()
+	ticket.ClosedAt.Valid = true
+
+	isAdminsOnly := ticketIsAdminOnly(conf, cs)
+
+	// create the logs, download the attachments
+	err := createLogs(gs, conf, ticket, isAdminsOnly)
+	if err != nil {
+		return err
+	}
+
+	TicketLog(conf, gs.ID, closedBy, &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("Ticket #%d - '%s' closed", ticket.LocalID, ticket.Title),
+		Description: fmt.Sprintf("Reason: %s", reason),
+		Color:       0xf23c3c,
+	})
+
+	// if everything went well, delete the channel
+	_, err = common.BotSession.ChannelDelete(ticket.ChannelID)
+	if err != nil {
+		return err
+	}
+
+	_, err = ticket.UpdateG(ctx, boil.Whitelist("closed_at"))
+	if err != nil {
+		return err
+	}
+
+	if ticket.IsModmail {
+		bot.SendDM(ticket.AuthorID, fmt.Sprintf("Your ticket #%d with the staff of %s was closed, reason: %s", ticket.LocalID, bot.GuildName(gs.ID), reason))
+	}
+
+	return nil
+}
+
 type CtxKey int
 
 const (
@@ -451,15 +704,17 @@ const (
 )
 
 type Ticket struct {
-	Ticket       *models.Ticket
+	Ticket *models.Ticket
+	// nil if the ticket doesn't have a type
+	Type         *models.TicketType
 	Participants []*models.TicketParticipant
 }
 
+// createLogs records the participants of the ticket, then creates the transcripts and archives the attachments if enabled.
+// The participants are always recorded as the ticket stats use them for first response times
 func createLogs(gs *dstate.GuildState, conf *models.TicketConfig, ticket *models.Ticket, adminOnly bool) error {
 
-	if !conf.TicketsUseTXTTranscripts && !conf.DownloadAttachments {
-		return nil // nothing to do here
-	}
+	useTranscripts := conf.TicketsUseTXTTranscripts || conf.TicketsUseHTMLTranscripts
 
 	channelID := ticket.ChannelID
 
@@ -508,10 +763,7 @@ func createLogs(gs *dstate.GuildState, conf *models.TicketConfig, ticket *models
 			}
 		}
 
-		// either continue fetching more or append to messages slice
-		if conf.TicketsUseTXTTranscripts {
-			msgs = append(msgs, m...)
-		}
+		msgs = append(msgs, m...)
 
 		if len(msgs) > 100000 {
 			break // hard limit at 100k
@@ -525,6 +777,15 @@ func createLogs(gs *dstate.GuildState, conf *models.TicketConfig, ticket *models
 		}
 	}
 
+	participants, err := recordParticipants(context.Background(), gs, conf, ticket, msgs)
+	if err != nil {
+		logger.WithError(err).WithField("guild", ticket.GuildID).WithField("ticket", ticket.LocalID).Error("[tickets] failed recording participants")
+	}
+
+	if !useTranscripts && !conf.DownloadAttachments {
+		return nil // nothing else to do here
+	}
+
 	if conf.TicketsUseTXTTranscripts && gs.Channel(true, transcriptChannel(conf, adminOnly)) != nil {
 		formattedTranscript := createTXTTranscript(ticket, msgs)
 
@@ -535,6 +796,19 @@ func createLogs(gs *dstate.GuildState, conf *models.TicketConfig, ticket *models
 		}
 	}
 
+	if conf.TicketsUseHTMLTranscripts && gs.Channel(true, transcriptChannel(conf, adminOnly)) != nil {
+		formattedTranscript, err := createHTMLTranscript(ticket, participants, msgs)
+		if err != nil {
+			return err
+		}
+
+		channel := transcriptChannel(conf, adminOnly)
+		_, err = common.BotSession.ChannelFileSendWithMessage(channel, fmt.Sprintf("transcript-%d-%s.html", ticket.LocalID, ticket.Title), fmt.Sprintf("transcript-%d-%s.html", ticket.LocalID, ticket.Title), formattedTranscript)
+		if err != nil {
+			return err
+		}
+	}
+
 	// compress and send the attachments
 	if conf.DownloadAttachments && gs.Channel(true, transcriptChannel(conf, adminOnly)) != nil {
 		archiveAttachments(conf, ticket, attachments, adminOnly)
@@ -663,14 +937,110 @@ func transcriptChannel(conf *models.TicketConfig, adminOnly bool) int64 {
 	return conf.TicketsTranscriptsChannel
 }
 
-func createTicketChannel(conf *models.TicketConfig, gs *dstate.GuildState, authorID int64, subject string) (int64, *discordgo.Channel, error) {
+// openTicket opens a new ticket for the member, conf should have the settings of the ticket type applied.
+// Problems the user can fix are returned as user errors
+func openTicket(ctx context.Context, gs *dstate.GuildState, conf *models.TicketConfig, tt *models.TicketType, ms *dstate.MemberState, subject string, modmail bool) (*models.Ticket, *discordgo.Channel, error) {
+	if gs.Channel(true, conf.TicketsChannelCategory) == nil {
+		return nil, nil, commands.NewUserError("No category for ticket channels set")
+	}
+
+	inCurrentTickets, err := models.Tickets(
+		qm.Where("closed_at IS NULL"),
+		qm.Where("guild_id = ?", gs.ID),
+		qm.Where("author_id = ?", ms.ID)).AllG(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	count := 0
+	for _, v := range inCurrentTickets {
+		if gs.Channel(true, v.ChannelID) != nil {
+			count++
+		}
+	}
+
+	if count >= 10 {
+		return nil, nil, commands.NewUserError("You're currently in over 10 open tickets on this server, please close some of the ones you're in.")
+	}
+
+	if len(subject) > 90 {
+		return nil, nil, commands.NewUserError("Title is too long (max 90 characters.) Please shorten it down, you can add more details in the ticket after it has been created")
+	}
+
+	author := ms.DGoUser()
+	id, channel, err := createTicketChannel(conf, tt, gs, author, subject, modmail)
+	if err != nil {
+		return nil, nil, commands.NewUserError("Failed creating the channel, make sure the bot has proper perms and the channel limit hasn't been reached.")
+	}
+
+	// create the db model for it
+	dbModel := &models.Ticket{
+		GuildID:               gs.ID,
+		LocalID:               id,
+		ChannelID:             channel.ID,
+		Title:                 subject,
+		CreatedAt:             time.Now(),
+		AuthorID:              author.ID,
+		AuthorUsernameDiscrim: author.Username + "#" + author.Discriminator,
+		LastAuthorActivityAt:  null.TimeFrom(time.Now()),
+		IsModmail:             modmail,
+	}
+
+	if tt != nil {
+		dbModel.TicketTypeID = tt.ID
+	}
+
+	err = dbModel.InsertG(ctx, boil.Infer())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if conf.InactivityWarnHours > 0 {
+		err = scheduleInactivityCheck(gs.ID, dbModel.LocalID, time.Now().Add(time.Hour*time.Duration(conf.InactivityWarnHours)))
+		if err != nil {
+			logger.WithError(err).WithField("guild", gs.ID).Error("failed scheduling ticket inactivity check")
+		}
+	}
+
+	// send the first ticket message
+	tmplCTX := templates.NewContext(gs, dstate.NewChannelState(gs, gs, channel), ms)
+	tmplCTX.Name = "ticket open message"
+	tmplCTX.Data["Reason"] = subject
+	tmplCTX.Data["TicketType"] = ""
+	if tt != nil {
+		tmplCTX.Data["TicketType"] = tt.Name
+	}
+
+	ticketOpenMsg := conf.TicketOpenMSG
+	if ticketOpenMsg == "" {
+		ticketOpenMsg = DefaultTicketMsg
+	}
+
+	err = tmplCTX.ExecuteAndSendWithErrors(ticketOpenMsg, channel.ID)
+	if err != nil {
+		logger.WithError(err).WithField("guild", gs.ID).Error("failed sending ticket open message")
+	}
+
+	// send the log message
+	description := fmt.Sprintf("Subject: %s", subject)
+	if tt != nil {
+		description = fmt.Sprintf("Type: %s\n%s", tt.Name, description)
+	}
+
+	TicketLog(conf, gs.ID, author, &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("Ticket #%d opened", id),
+		Description: description,
+		Color:       0x5df948,
+	})
+
+	return dbModel, channel, nil
+}
+
+// createTicketChannel creates the channel for a new ticket, modmail tickets don't give the author access to the channel
+// as they talk with the staff through DM's
+func createTicketChannel(conf *models.TicketConfig, tt *models.TicketType, gs *dstate.GuildState, author *discordgo.User, subject string, modmail bool) (int64, *discordgo.Channel, error) {
 	// assemble the permission overwrites for the channel were about to create
 	overwrites := []*discordgo.PermissionOverwrite{
-		&discordgo.PermissionOverwrite{
-			Type:  "member",
-			ID:    authorID,
-			Allow: InTicketPerms,
-		},
 		&discordgo.PermissionOverwrite{
 			Type: "role",
 			ID:   gs.ID,
@@ -683,6 +1053,14 @@ func createTicketChannel(conf *models.TicketConfig, gs *dstate.GuildState, autho
 		},
 	}
 
+	if !modmail {
+		overwrites = append(overwrites, &discordgo.PermissionOverwrite{
+			Type:  "member",
+			ID:    author.ID,
+			Allow: InTicketPerms,
+		})
+	}
+
 	// add all the mod and admin roles
 OUTER:
 	for _, v := range conf.ModRoles {
@@ -728,7 +1106,7 @@ OUTER2:
 		return 0, nil, err
 	}
 
-	channel, err := common.BotSession.GuildChannelCreateWithOverwrites(gs.ID, fmt.Sprintf("%d-%s", id, subject), discordgo.ChannelTypeGuildText, conf.TicketsChannelCategory, overwrites)
+	channel, err := common.BotSession.GuildChannelCreateWithOverwrites(gs.ID, TicketChannelName(tt, id, subject, author.Username), discordgo.ChannelTypeGuildText, conf.TicketsChannelCategory, overwrites)
 	if err != nil {
 		return 0, nil, err
 	}
@@ -1,11 +1,15 @@
 package tickets
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"html/template"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/jonas747/yagpdb/bot"
 	"github.com/jonas747/yagpdb/common"
 	"github.com/jonas747/yagpdb/common/cplogs"
 	"github.com/jonas747/yagpdb/tickets/models"
@@ -22,13 +26,52 @@ type FormData struct {
 	TicketsTranscriptsChannelAdminOnly int64 `valid:"channel,true"`
 	StatusChannel                      int64 `valid:"channel,true"`
 	TicketsUseTXTTranscripts           bool
+	TicketsUseHTMLTranscripts          bool
 	DownloadAttachments                bool
+	ClaimRestrictsTalking              bool
+	ModmailEnabled                     bool
+	ModmailAnonymousReplies            bool
 	ModRoles                           []int64 `valid:"role"`
 	AdminRoles                         []int64 `valid:"role"`
 	TicketOpenMSG                      string  `valid:"template,10000"`
+	InactivityWarnHours                int     `valid:"0,720"`
+	InactivityCloseHours               int     `valid:"0,720"`
 }
 
-var panelLogKey = cplogs.RegisterActionFormat(&cplogs.ActionFormat{Key: "tickets_updated_settings", FormatString: "Updated ticket settings"})
+type TicketTypeForm struct {
+	ID                 int64
+	Name               string  `valid:",1,50,trimspace"`
+	Emoji              string  `valid:",0,100,trimspace"`
+	ChannelCategory    int64   `valid:"channel,true"`
+	TranscriptsChannel int64   `valid:"channel,true"`
+	ModRoles           []int64 `valid:"role"`
+	AdminRoles         []int64 `valid:"role"`
+	OpenMSG            string  `valid:"template,10000"`
+	NamingPattern      string  `valid:",0,100,trimspace"`
+
+	InactivityWarnHours  int `valid:"0,720"`
+	InactivityCloseHours int `valid:"0,720"`
+}
+
+func (f *TicketTypeForm) ApplyTo(tt *models.TicketType) {
+	tt.Name = f.Name
+	tt.Emoji = NormalizeTicketEmoji(f.Emoji)
+	tt.ChannelCategory = f.ChannelCategory
+	tt.TranscriptsChannel = f.TranscriptsChannel
+	tt.ModRoles = f.ModRoles
+	tt.AdminRoles = f.AdminRoles
+	tt.OpenMSG = f.OpenMSG
+	tt.NamingPattern = f.NamingPattern
+	tt.InactivityWarnHours = f.InactivityWarnHours
+	tt.InactivityCloseHours = f.InactivityCloseHours
+}
+
+var (
+	panelLogKey            = cplogs.RegisterActionFormat(&cplogs.ActionFormat{Key: "tickets_updated_settings", FormatString: "Updated ticket settings"})
+	panelLogKeyAddedType   = cplogs.RegisterActionFormat(&cplogs.ActionFormat{Key: "tickets_added_type", FormatString: "Added ticket type %s"})
+	panelLogKeyUpdatedType = cplogs.RegisterActionFormat(&cplogs.ActionFormat{Key: "tickets_updated_type", FormatString: "Updated ticket type %s"})
+	panelLogKeyRemovedType = cplogs.RegisterActionFormat(&cplogs.ActionFormat{Key: "tickets_removed_type", FormatString: "Removed ticket type %s"})
+)
 
 func (p *Plugin) InitWeb() {
 	web.LoadHTMLTemplate("../../tickets/assets/tickets_control_panel.html", "templates/plugins/tickets_control_panel.html")
@@ -46,8 +89,15 @@ func (p *Plugin) InitWeb() {
 	web.CPMux.Handle(pat.Get("/tickets/settings/"), getHandler)
 
 	web.CPMux.Handle(pat.Post("/tickets/settings"), postHandler)
+
+	web.CPMux.Handle(pat.Post("/tickets/settings/types/new"), web.ControllerPostHandler(p.handleNewType, getHandler, TicketTypeForm{}))
+	web.CPMux.Handle(pat.Post("/tickets/settings/types/update"), web.ControllerPostHandler(p.handleUpdateType, getHandler, TicketTypeForm{}))
+	web.CPMux.Handle(pat.Post("/tickets/settings/types/delete"), web.ControllerPostHandler(p.handleDeleteType, getHandler, TicketTypeForm{}))
 }
 
+// number of days the stats on the control panel are for, the stats command can show other periods
+const ticketStatsPageDays = 30
+
 func (p *Plugin) handleGetSettings(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
 	ctx := r.Context()
 	activeGuild, templateData := web.GetBaseCPContextData(ctx)
@@ -62,8 +112,25 @@ func (p *Plugin) handleGetSettings(w http.ResponseWriter, r *http.Request) (web.
 		settings = &models.TicketConfig{}
 	}
 
+	types, err := GetTicketTypes(ctx, activeGuild.ID)
+	if err != nil {
+		return templateData, err
+	}
+
 	templateData["DefaultTicketMessage"] = DefaultTicketMsg
+	templateData["DefaultNamingPattern"] = DefaultTicketNamingPattern
+	templateData["MaxTicketTypes"] = MaxTicketTypes
 	templateData["PluginSettings"] = settings
+	templateData["TicketTypes"] = types
+	templateData["NewTicketType"] = &models.TicketType{}
+
+	stats, err := GetTicketStats(ctx, activeGuild.ID, time.Now().Add(-time.Hour*24*ticketStatsPageDays))
+	if err != nil {
+		return templateData, err
+	}
+
+	templateData["TicketStats"] = stats
+	templateData["TicketStatsDays"] = ticketStatsPageDays
 
 	return templateData, nil
 }
@@ -82,19 +149,136 @@ func (p *Plugin) handlePostSettings(w http.ResponseWriter, r *http.Request) (web
 		TicketsTranscriptsChannelAdminOnly: formConfig.TicketsTranscriptsChannelAdminOnly,
 		StatusChannel:                      formConfig.StatusChannel,
 		TicketsUseTXTTranscripts:           formConfig.TicketsUseTXTTranscripts,
+		TicketsUseHTMLTranscripts:          formConfig.TicketsUseHTMLTranscripts,
 		DownloadAttachments:                formConfig.DownloadAttachments,
+		ClaimRestrictsTalking:              formConfig.ClaimRestrictsTalking,
+		ModmailEnabled:                     formConfig.ModmailEnabled,
+		ModmailAnonymousReplies:            formConfig.ModmailAnonymousReplies,
 		ModRoles:                           formConfig.ModRoles,
 		AdminRoles:                         formConfig.AdminRoles,
 		TicketOpenMSG:                      formConfig.TicketOpenMSG,
+		InactivityWarnHours:                formConfig.InactivityWarnHours,
+		InactivityCloseHours:               formConfig.InactivityCloseHours,
 	}
 
-	err := model.UpsertG(ctx, true, []string{"guild_id"}, boil.Infer(), boil.Infer())
+	err := model.UpsertG(ctx, true, []string{"guild_id"}, boil.Blacklist("panel_channel_id", "panel_message_id"), boil.Infer())
 	if err == nil {
+		bot.EvictGSCache(activeGuild.ID, CacheKeyConfig)
 		go cplogs.RetryAddEntry(web.NewLogEntryFromContext(r.Context(), panelLogKey))
+		rescheduleInactivityChecks(ctx, activeGuild.ID)
+	}
+	return templateData, err
+}
+
+// rescheduleInactivityChecks makes already open tickets follow the new inactivity settings
+func rescheduleInactivityChecks(ctx context.Context, guildID int64) {
+	err := ScheduleOpenTicketsInactivityChecks(ctx, guildID)
+	if err != nil {
+		web.CtxLogger(ctx).WithError(err).Error("failed scheduling ticket inactivity checks")
+	}
+}
+
+func (p *Plugin) handleNewType(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
+	ctx := r.Context()
+	activeGuild, templateData := web.GetBaseCPContextData(ctx)
+	form := ctx.Value(common.ContextKeyParsedForm).(*TicketTypeForm)
+
+	count, err := models.TicketTypes(models.TicketTypeWhere.GuildID.EQ(activeGuild.ID)).CountG(ctx)
+	if err != nil {
+		return templateData, err
+	}
+
+	if count >= MaxTicketTypes {
+		return templateData.AddAlerts(web.ErrorAlert(fmt.Sprintf("Max %d ticket types", MaxTicketTypes))), nil
+	}
+
+	if alert := p.checkTicketTypeConflicts(r, activeGuild.ID, form); alert != nil {
+		return templateData.AddAlerts(alert), nil
+	}
+
+	model := &models.TicketType{GuildID: activeGuild.ID}
+	form.ApplyTo(model)
+
+	err = model.InsertG(ctx, boil.Infer())
+	if err == nil {
+		bot.EvictGSCache(activeGuild.ID, CacheKeyConfig)
+		go cplogs.RetryAddEntry(web.NewLogEntryFromContext(ctx, panelLogKeyAddedType, &cplogs.Param{Type: cplogs.ParamTypeString, Value: model.Name}))
 	}
 	return templateData, err
 }
 
+func (p *Plugin) handleUpdateType(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
+	ctx := r.Context()
+	activeGuild, templateData := web.GetBaseCPContextData(ctx)
+	form := ctx.Value(common.ContextKeyParsedForm).(*TicketTypeForm)
+
+	model, err := models.TicketTypes(models.TicketTypeWhere.GuildID.EQ(activeGuild.ID), models.TicketTypeWhere.ID.EQ(form.ID)).OneG(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return templateData.AddAlerts(web.ErrorAlert("Unknown ticket type")), nil
+		}
+		return templateData, err
+	}
+
+	if alert := p.checkTicketTypeConflicts(r, activeGuild.ID, form); alert != nil {
+		return templateData.AddAlerts(alert), nil
+	}
+
+	form.ApplyTo(model)
+	_, err = model.UpdateG(ctx, boil.Blacklist("id", "guild_id"))
+	if err == nil {
+		bot.EvictGSCache(activeGuild.ID, CacheKeyConfig)
+		go cplogs.RetryAddEntry(web.NewLogEntryFromContext(ctx, panelLogKeyUpdatedType, &cplogs.Param{Type: cplogs.ParamTypeString, Value: model.Name}))
+		rescheduleInactivityChecks(ctx, activeGuild.ID)
+	}
+	return templateData, err
+}
+
+func (p *Plugin) handleDeleteType(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
+	ctx := r.Context()
+	activeGuild, templateData := web.GetBaseCPContextData(ctx)
+	form := ctx.Value(common.ContextKeyParsedForm).(*TicketTypeForm)
+
+	// open tickets of this type fall back to the guild's settings
+	n, err := models.TicketTypes(models.TicketTypeWhere.GuildID.EQ(activeGuild.ID), models.TicketTypeWhere.ID.EQ(form.ID)).DeleteAll(ctx, common.PQ)
+	if err == nil && n > 0 {
+		bot.EvictGSCache(activeGuild.ID, CacheKeyConfig)
+		go cplogs.RetryAddEntry(web.NewLogEntryFromContext(ctx, panelLogKeyRemovedType, &cplogs.Param{Type: cplogs.ParamTypeString, Value: form.Name}))
+		rescheduleInactivityChecks(ctx, activeGuild.ID)
+	}
+	return templateData, err
+}
+
+// checkTicketTypeConflicts makes sure the name and emoji of the type isn't used by another type
+func (p *Plugin) checkTicketTypeConflicts(r *http.Request, guildID int64, form *TicketTypeForm) *web.Alert {
+	types, err := GetTicketTypes(r.Context(), guildID)
+	if err != nil {
+		web.CtxLogger(r.Context()).WithError(err).Error("failed retrieving ticket types")
+		return web.ErrorAlert("Failed retrieving ticket types")
+	}
+
+	if strings.ContainsAny(form.Name, " \t\n") {
+		return web.ErrorAlert("Ticket type names can't contain spaces, they're used in the open command")
+	}
+
+	emoji := NormalizeTicketEmoji(form.Emoji)
+	for _, v := range types {
+		if v.ID == form.ID {
+			continue
+		}
+
+		if strings.EqualFold(v.Name, form.Name) {
+			return web.ErrorAlert("There's already a ticket type with that name")
+		}
+
+		if emoji != "" && v.Emoji == emoji {
+			return web.ErrorAlert("There's already a ticket type with that emoji")
+		}
+	}
+
+	return nil
+}
+
 var _ web.PluginWithServerHomeWidget = (*Plugin)(nil)
 
 func (p *Plugin) LoadServerHomeWidget(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
@@ -0,0 +1,329 @@
+package tickets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/dstate/v2"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/tickets/models"
+	"github.com/volatiletech/null"
+	"github.com/volatiletech/sqlboiler/boil"
+)
+
+// recordParticipants stores everyone that sent a message in the ticket as participants
+func recordParticipants(ctx context.Context, gs *dstate.GuildState, conf *models.TicketConfig, ticket *models.Ticket, msgs []*discordgo.Message) ([]*models.TicketParticipant, error) {
+	seen := make(map[int64]bool)
+	participants := make([]*models.TicketParticipant, 0)
+
+	// oldest first, so the participants are in the order they joined the conversation
+	for i := len(msgs) - 1; i >= 0; i-- {
+		author := msgs[i].Author
+		if author == nil || author.Bot || seen[author.ID] {
+			continue
+		}
+		seen[author.ID] = true
+
+		participant := &models.TicketParticipant{
+			TicketGuildID: ticket.GuildID,
+			TicketLocalID: ticket.LocalID,
+			UserID:        author.ID,
+			Username:      author.Username,
+			Discrim:       author.Discriminator,
+			IsStaff:       isTicketStaff(gs, conf, author.ID),
+		}
+
+		if ts, err := msgs[i].Timestamp.Parse(); err == nil {
+			participant.FirstMessageAt = null.TimeFrom(ts)
+		}
+
+		err := participant.UpsertG(ctx, true, []string{"ticket_guild_id", "ticket_local_id", "user_id"}, boil.Whitelist("username", "discrim", "is_staff", "first_message_at"), boil.Infer())
+		if err != nil {
+			return participants, err
+		}
+
+		participants = append(participants, participant)
+	}
+
+	return participants, nil
+}
+
+// isTicketStaff returns true if the user has one of the mod or admin roles, users no longer in the server are not considered staff
+func isTicketStaff(gs *dstate.GuildState, conf *models.TicketConfig, userID int64) bool {
+	ms := gs.MemberCopy(true, userID)
+	if ms == nil {
+		return false
+	}
+
+	for _, r := range ms.Roles {
+		if common.ContainsInt64Slice(conf.ModRoles, r) || common.ContainsInt64Slice(conf.AdminRoles, r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type htmlTranscriptMessage struct {
+	*discordgo.Message
+
+	Timestamp string
+	Staff     bool
+	ReplyTo   *discordgo.Message
+}
+
+type htmlTranscriptParticipant struct {
+	*models.TicketParticipant
+	Messages int
+}
+
+// max total size of the images embedded into a html transcript, so it stays below discord's upload limit.
+// The rest of the attachments are linked to discord's cdn, those links stop working once the ticket channel is deleted
+const maxTranscriptInlineSize = 6 * 1024 * 1024
+
+// createHTMLTranscript renders a html transcript that can be viewed in a browser without anything else,
+// images are embedded into it as the ticket channel they were sent in is deleted after
+func createHTMLTranscript(ticket *models.Ticket, participants []*models.TicketParticipant, msgs []*discordgo.Message) (*bytes.Buffer, error) {
+	byID := make(map[int64]*discordgo.Message)
+	messageCounts := make(map[int64]int)
+	for _, m := range msgs {
+		byID[m.ID] = m
+		if m.Author != nil {
+			messageCounts[m.Author.ID]++
+		}
+	}
+
+	staff := make(map[int64]bool)
+	participantViews := make([]*htmlTranscriptParticipant, 0, len(participants))
+	for _, p := range participants {
+		staff[p.UserID] = p.IsStaff
+		participantViews = append(participantViews, &htmlTranscriptParticipant{
+			TicketParticipant: p,
+			Messages:          messageCounts[p.UserID],
+		})
+	}
+
+	// traverse reverse for correct order (they come in with new-old order, we want old-new)
+	views := make([]*htmlTranscriptMessage, 0, len(msgs))
+	for i := len(msgs) - 1; i >= 0; i-- {
+		m := msgs[i]
+		if m.Author == nil {
+			continue
+		}
+
+		ts, _ := m.Timestamp.Parse()
+		view := &htmlTranscriptMessage{
+			Message:   m,
+			Timestamp: ts.UTC().Format(TicketTXTDateFormat),
+			Staff:     staff[m.Author.ID],
+		}
+
+		if m.MessageReference != nil {
+			view.ReplyTo = byID[m.MessageReference.MessageID]
+		}
+
+		views = append(views, view)
+	}
+
+	// oldest first, so if we run out of room it's the newest images that are left out
+	inlineImages := make(map[int64]template.URL)
+	inlinedSize := 0
+	for _, v := range views {
+		for _, a := range v.Attachments {
+			if !transcriptIsImage(a.Filename) || inlinedSize+a.Size > maxTranscriptInlineSize {
+				continue
+			}
+
+			dataURI, err := transcriptImageDataURI(a)
+			if err != nil {
+				logger.WithError(err).WithField("guild", ticket.GuildID).Error("[tickets] failed downloading image for html transcript")
+				continue
+			}
+
+			// base64 makes it a bit larger than the attachment
+			inlinedSize += len(dataURI)
+			inlineImages[a.ID] = dataURI
+		}
+	}
+
+	closedAt := ""
+	if ticket.ClosedAt.Valid {
+		closedAt = ticket.ClosedAt.Time.UTC().Format(TicketTXTDateFormat)
+	}
+
+	var buf bytes.Buffer
+	err := htmlTranscriptTemplate.Execute(&buf, map[string]interface{}{
+		"Ticket":       ticket,
+		"CreatedAt":    ticket.CreatedAt.UTC().Format(TicketTXTDateFormat),
+		"ClosedAt":     closedAt,
+		"Participants": participantViews,
+		"Messages":     views,
+		"InlineImages": inlineImages,
+	})
+
+	return &buf, err
+}
+
+func transcriptIsImage(filename string) bool {
+	return transcriptImageContentType(filename) != ""
+}
+
+func transcriptImageContentType(filename string) string {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".png":
+		return "image/png"
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".gif":
+		return "image/gif"
+	case ".webp":
+		return "image/webp"
+	}
+
+	return ""
+}
+
+// transcriptImageDataURI downloads the image attachment and returns it as a data uri
+func transcriptImageDataURI(a *discordgo.MessageAttachment) (template.URL, error) {
+	resp, err := http.Get(a.URL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxTranscriptInlineSize))
+	if err != nil {
+		return "", err
+	}
+
+	return template.URL("data:" + transcriptImageContentType(a.Filename) + ";base64," + base64.StdEncoding.EncodeToString(data)), nil
+}
+
+func transcriptEmojiURL(e *discordgo.Emoji) string {
+	if e == nil || e.ID == 0 {
+		return ""
+	}
+
+	ext := "png"
+	if e.Animated {
+		ext = "gif"
+	}
+
+	return fmt.Sprintf("https://cdn.discordapp.com/emojis/%d.%s", e.ID, ext)
+}
+
+func transcriptSnippet(s string) string {
+	if len([]rune(s)) > 100 {
+		return string([]rune(s)[:100]) + "..."
+	}
+
+	return s
+}
+
+var htmlTranscriptTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"emojiURL": transcriptEmojiURL,
+	"snippet":  transcriptSnippet,
+	"hexColor": func(c int) string { return fmt.Sprintf("#%06x", c) },
+}).Parse(htmlTranscriptSource))
+
+const htmlTranscriptSource = `<!DOCTYPE html>
+<html>
+
+<head>
+    <meta charset="utf-8">
+    <title>Ticket #{{.Ticket.LocalID}} - {{.Ticket.Title}}</title>
+    <style>
+        body { font-family: sans-serif; background: #36393f; color: #dcddde; margin: 20px; }
+        a { color: #00b0f4; }
+        .header { border-bottom: 1px solid #4f545c; margin-bottom: 16px; padding-bottom: 8px; }
+        .participants li { margin: 2px 0; }
+        .staff { color: #faa61a; font-size: 0.8em; font-weight: 600; margin-left: 4px; }
+        .message { display: flex; padding: 6px 0; }
+        .avatar { width: 40px; height: 40px; border-radius: 50%; margin-right: 12px; flex-shrink: 0; }
+        .body { min-width: 0; }
+        .author { font-weight: 600; }
+        .time { color: #8e9297; font-size: 0.8em; margin-left: 6px; }
+        .content { white-space: pre-wrap; word-break: break-word; }
+        .reply { color: #b9bbbe; font-size: 0.85em; border-left: 2px solid #4f545c; padding-left: 6px; margin-bottom: 2px; }
+        .embed { background: #2f3136; border-left: 4px solid #202225; border-radius: 4px; padding: 8px 12px; margin-top: 4px; max-width: 520px; }
+        .embed-title { font-weight: 600; }
+        .embed-field { margin-top: 6px; }
+        .embed-field-name { font-weight: 600; }
+        .embed-footer { color: #b9bbbe; font-size: 0.8em; margin-top: 6px; }
+        .attachment img, .embed img { display: block; max-width: 400px; max-height: 300px; margin-top: 6px; }
+        .reactions span { display: inline-block; background: #2f3136; border-radius: 4px; padding: 2px 6px; margin: 4px 4px 0 0; }
+        .reactions img { width: 16px; height: 16px; vertical-align: middle; }
+    </style>
+</head>
+
+<body>
+    <div class="header">
+        <h2>Ticket #{{.Ticket.LocalID}} - {{.Ticket.Title}}</h2>
+        <p>Opened by {{.Ticket.AuthorUsernameDiscrim}} ({{.Ticket.AuthorID}}) at {{.CreatedAt}} UTC{{if .ClosedAt}}, closed at {{.ClosedAt}} UTC{{end}}</p>
+        <h3>Participants</h3>
+        <ul class="participants">
+            {{range .Participants}}
+            <li>{{.Username}}#{{.Discrim}} ({{.UserID}}){{if .IsStaff}}<span class="staff">STAFF</span>{{end}} - {{.Messages}} message(s)</li>
+            {{end}}
+        </ul>
+    </div>
+
+    {{range .Messages}}
+    <div class="message" id="m{{.ID}}">
+        <img class="avatar" src="{{.Author.AvatarURL "64"}}" alt="">
+        <div class="body">
+            {{if .ReplyTo}}<div class="reply">Replying to <a href="#m{{.ReplyTo.ID}}">{{.ReplyTo.Author.Username}}</a>: {{snippet .ReplyTo.Content}}</div>{{end}}
+            <div><span class="author" title="{{.Author.ID}}">{{.Author.Username}}#{{.Author.Discriminator}}</span>{{if .Staff}}<span class="staff">STAFF</span>{{end}}<span class="time">{{.Timestamp}}</span></div>
+            {{if .Content}}<div class="content">{{.Content}}</div>{{end}}
+
+            {{range .Attachments}}
+            <div class="attachment">
+                <a href="{{.URL}}">{{.Filename}}</a>
+                {{with index $.InlineImages .ID}}<img src="{{.}}" alt="">{{end}}
+            </div>
+            {{end}}
+
+            {{range .Embeds}}
+            <div class="embed" style="border-left-color: {{hexColor .Color}};">
+                {{with .Author}}<div>{{.Name}}</div>{{end}}
+                {{if .Title}}<div class="embed-title">{{if .URL}}<a href="{{.URL}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}</div>{{end}}
+                {{if .Description}}<div class="content">{{.Description}}</div>{{end}}
+                {{range .Fields}}
+                <div class="embed-field">
+                    <div class="embed-field-name">{{.Name}}</div>
+                    <div class="content">{{.Value}}</div>
+                </div>
+                {{end}}
+                {{with .Image}}<img src="{{.URL}}" alt="">{{end}}
+                {{with .Thumbnail}}<img src="{{.URL}}" alt="">{{end}}
+                {{with .Footer}}<div class="embed-footer">{{.Text}}</div>{{end}}
+            </div>
+            {{end}}
+
+            {{if .Reactions}}
+            <div class="reactions">
+                {{range .Reactions}}
+                <span>{{with emojiURL .Emoji}}<img src="{{.}}" alt="">{{else}}{{.Emoji.Name}}{{end}} {{.Count}}</span>
+                {{end}}
+            </div>
+            {{end}}
+        </div>
+    </div>
+    {{end}}
+</body>
+
+</html>
+`
@@ -0,0 +1,158 @@
+package tickets
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jonas747/yagpdb/tickets/models"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+const (
+	// MaxTicketTypes is the max number of ticket types per guild, limited by the number of reactions on the panel message
+	MaxTicketTypes = 10
+
+	DefaultTicketNamingPattern = "{id}-{subject}"
+)
+
+// GetTicketTypes returns the ticket types set up on the guild
+func GetTicketTypes(ctx context.Context, guildID int64) (models.TicketTypeSlice, error) {
+	return models.TicketTypes(models.TicketTypeWhere.GuildID.EQ(guildID), qm.OrderBy("id asc")).AllG(ctx)
+}
+
+// ApplyTicketType returns a copy of the guild's config with the settings the ticket type overrides applied,
+// so tickets of that type can be handled the same way as the ones without a type
+func ApplyTicketType(conf *models.TicketConfig, tt *models.TicketType) *models.TicketConfig {
+	cop := *conf
+	if tt == nil {
+		return &cop
+	}
+
+	if tt.ChannelCategory != 0 {
+		cop.TicketsChannelCategory = tt.ChannelCategory
+	}
+
+	if tt.TranscriptsChannel != 0 {
+		cop.TicketsTranscriptsChannel = tt.TranscriptsChannel
+	}
+
+	if len(tt.ModRoles) > 0 {
+		cop.ModRoles = tt.ModRoles
+	}
+
+	if len(tt.AdminRoles) > 0 {
+		cop.AdminRoles = tt.AdminRoles
+	}
+
+	if tt.OpenMSG != "" {
+		cop.TicketOpenMSG = tt.OpenMSG
+	}
+
+	if tt.InactivityWarnHours > 0 {
+		cop.InactivityWarnHours = tt.InactivityWarnHours
+	}
+
+	if tt.InactivityCloseHours > 0 {
+		cop.InactivityCloseHours = tt.InactivityCloseHours
+	}
+
+	return &cop
+}
+
+// TicketChannelName creates the name of a ticket channel from the naming pattern of the type,
+// the pattern can contain {id}, {subject}, {user} and {type}
+func TicketChannelName(tt *models.TicketType, localID int64, subject, username string) string {
+	pattern := DefaultTicketNamingPattern
+	typeName := ""
+	if tt != nil {
+		typeName = tt.Name
+		if tt.NamingPattern != "" {
+			pattern = tt.NamingPattern
+		}
+	}
+
+	r := strings.NewReplacer(
+		"{id}", strconv.FormatInt(localID, 10),
+		"{subject}", subject,
+		"{user}", username,
+		"{type}", typeName,
+	)
+
+	// cut by runes so a multi byte character isn't split
+	name := r.Replace(pattern)
+	if utf8.RuneCountInString(name) > 100 {
+		name = string([]rune(name)[:100])
+	}
+
+	return name
+}
+
+// matchTicketType checks if the subject starts with the name of one of the ticket types, names can be several words
+// and the longest matching one is used. Returns the type and the rest of the subject
+func matchTicketType(types models.TicketTypeSlice, subject string) (*models.TicketType, string) {
+	fields := strings.Fields(subject)
+
+	var match *models.TicketType
+	matchWords := 0
+
+OUTER:
+	for _, v := range types {
+		nameFields := strings.Fields(v.Name)
+		if len(nameFields) < 1 || len(nameFields) > len(fields) || len(nameFields) <= matchWords {
+			continue
+		}
+
+		for i, w := range nameFields {
+			if !strings.EqualFold(w, fields[i]) {
+				continue OUTER
+			}
+		}
+
+		match = v
+		matchWords = len(nameFields)
+	}
+
+	if match == nil {
+		return nil, subject
+	}
+
+	rest := strings.Join(fields[matchWords:], " ")
+	if rest == "" {
+		rest = match.Name
+	}
+
+	return match, rest
+}
+
+// findTicketType returns the ticket type with the id
+func findTicketType(types models.TicketTypeSlice, id int64) *models.TicketType {
+	for _, v := range types {
+		if v.ID == id {
+			return v
+		}
+	}
+
+	return nil
+}
+
+// NormalizeTicketEmoji turns a emoji into the form reactions use, "name:id" for custom emojis
+// (which can be given as <:name:id>) and the emoji itself for unicode emojis
+func NormalizeTicketEmoji(emoji string) string {
+	emoji = strings.TrimSpace(emoji)
+	emoji = strings.TrimPrefix(emoji, "<")
+	emoji = strings.TrimSuffix(emoji, ">")
+	emoji = strings.TrimPrefix(emoji, "a:")
+	emoji = strings.TrimPrefix(emoji, ":")
+	return emoji
+}
+
+// ticketEmojiMention returns the emoji in a form that can be used in messages
+func ticketEmojiMention(emoji string) string {
+	if strings.Contains(emoji, ":") {
+		return "<:" + emoji + ">"
+	}
+
+	return emoji
+}
@@ -0,0 +1,79 @@
+package tickets
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/jonas747/yagpdb/tickets/models"
+)
+
+func TestMatchTicketType(t *testing.T) {
+	types := models.TicketTypeSlice{
+		&models.TicketType{ID: 1, Name: "Ban"},
+		&models.TicketType{ID: 2, Name: "Ban Appeals"},
+		&models.TicketType{ID: 3, Name: "Support"},
+	}
+
+	cases := []struct {
+		Subject      string
+		ExpectedType int64
+		ExpectedRest string
+	}{
+		{"support my bot is broken", 3, "my bot is broken"},
+		{"SUPPORT   my bot", 3, "my bot"},
+		{"support", 3, "Support"},
+		{"ban appeals I was banned", 2, "I was banned"},
+		{"Ban Appeals", 2, "Ban Appeals"},
+		{"ban someone please", 1, "someone please"},
+		{"banana", 0, "banana"},
+		{"help me", 0, "help me"},
+		{"", 0, ""},
+	}
+
+	for _, v := range cases {
+		t.Run(fmt.Sprintf("Subject %q", v.Subject), func(t *testing.T) {
+			tt, rest := matchTicketType(types, v.Subject)
+
+			var id int64
+			if tt != nil {
+				id = tt.ID
+			}
+
+			if id != v.ExpectedType {
+				t.Errorf("Mismatched type, GOT %d EXPECTED %d", id, v.ExpectedType)
+			}
+			if rest != v.ExpectedRest {
+				t.Errorf("Mismatched rest, GOT %q EXPECTED %q", rest, v.ExpectedRest)
+			}
+		})
+	}
+}
+
+func TestTicketChannelName(t *testing.T) {
+	cases := []struct {
+		Type     *models.TicketType
+		Subject  string
+		Expected string
+	}{
+		{nil, "help", "5-help"},
+		{&models.TicketType{Name: "Support"}, "help", "5-help"},
+		{&models.TicketType{Name: "Support", NamingPattern: "{type}-{user}-{id}"}, "help", "Support-bob-5"},
+		{&models.TicketType{Name: "Support", NamingPattern: "{subject}"}, strings.Repeat("a", 150), strings.Repeat("a", 100)},
+		{&models.TicketType{Name: "Support", NamingPattern: "{subject}"}, strings.Repeat("é", 150), strings.Repeat("é", 100)},
+	}
+
+	for k, v := range cases {
+		t.Run(fmt.Sprintf("Case %d", k), func(t *testing.T) {
+			result := TicketChannelName(v.Type, 5, v.Subject, "bob")
+			if result != v.Expected {
+				t.Errorf("GOT %q EXPECTED %q", result, v.Expected)
+			}
+
+			if !utf8.ValidString(result) {
+				t.Errorf("Invalid utf8: %q", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package tickets
+
+import (
+	"context"
+
+	"emperror.dev/errors"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/tickets/models"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+var _ common.PluginWithUserData = (*Plugin)(nil)
+
+type userDataExport struct {
+	Opened       models.TicketSlice            `json:"tickets_opened"`
+	Participated models.TicketParticipantSlice `json:"ticket_participations"`
+}
+
+func (p *Plugin) ExportUserData(ctx context.Context, userID int64) (interface{}, error) {
+	export := &userDataExport{}
+
+	var err error
+	export.Opened, err = models.Tickets(models.TicketWhere.AuthorID.EQ(userID), qm.OrderBy("guild_id, local_id")).AllG(ctx)
+	if err != nil {
+		return nil, errors.WrapIf(err, "tickets")
+	}
+
+	export.Participated, err = models.TicketParticipants(models.TicketParticipantWhere.UserID.EQ(userID), qm.OrderBy("ticket_guild_id, ticket_local_id")).AllG(ctx)
+	if err != nil {
+		return nil, errors.WrapIf(err, "ticket_participants")
+	}
+
+	return export, nil
+}
+
+// EraseUserData removes the user from ticket participants and anonymizes the tickets they opened or claimed,
+// the ticket logs themselves are handled by the logs plugin
+func (p *Plugin) EraseUserData(ctx context.Context, userID int64) error {
+	_, err := models.TicketParticipants(models.TicketParticipantWhere.UserID.EQ(userID)).DeleteAll(ctx, common.PQ)
+	if err != nil {
+		return errors.WrapIf(err, "ticket_participants")
+	}
+
+	_, err = models.Tickets(models.TicketWhere.AuthorID.EQ(userID)).UpdateAllG(ctx, models.M{"author_id": 0, "author_username_discrim": "Deleted user"})
+	if err != nil {
+		return errors.WrapIf(err, "tickets")
+	}
+
+	_, err = models.Tickets(models.TicketWhere.ClaimedByID.EQ(userID)).UpdateAllG(ctx, models.M{"claimed_by_id": 0, "claimed_by_username": "Deleted user"})
+	return errors.WrapIf(err, "claimed tickets")
+}